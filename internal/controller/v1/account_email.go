@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SendVerificationEmail 发送邮箱验证邮件
+// @Summary 发送邮箱验证邮件
+// @Description 向当前用户的邮箱发送验证链接
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Failure 429 {object} dto.Response
+// @Router /api/v1/users/me/email/verification [post]
+func (c *UserController) SendVerificationEmail(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.userService.SendVerificationEmail(userID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrMailTooFrequent) {
+			status = http.StatusTooManyRequests
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "验证邮件已发送",
+		"data": nil,
+	})
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用验证邮件中的令牌完成邮箱验证
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.VerifyEmailRequest true "验证令牌"
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/email/verify [post]
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.userService.VerifyEmail(req.Token); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "邮箱验证成功",
+		"data": nil,
+	})
+}
+
+// ForgotPassword 忘记密码
+// @Summary 忘记密码
+// @Description 向注册邮箱发送密码重置链接，邮箱是否存在都返回成功
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.ForgotPasswordRequest true "注册邮箱"
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/password/forgot [post]
+func (c *UserController) ForgotPassword(ctx *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.userService.RequestPasswordReset(req.Email); err != nil {
+		// 发送失败只记录日志，避免泄露邮箱是否已注册
+		zap.L().Error("发送密码重置邮件失败", zap.Error(err))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "如果该邮箱已注册，重置链接将发送至邮箱",
+		"data": nil,
+	})
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用重置邮件中的令牌设置新密码，已登录的会话全部失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.ResetPasswordRequest true "重置令牌和新密码"
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/password/reset [post]
+func (c *UserController) ResetPassword(ctx *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.userService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": fieldErrors(err),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "密码已重置，请重新登录",
+		"data": nil,
+	})
+}
@@ -0,0 +1,302 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyController() *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: service.NewAPIKeyService(),
+	}
+}
+
+// CreateUserAPIKey 创建当前用户的 API Key
+// @Summary 创建 API Key
+// @Description 创建归属于当前用户的 API Key，完整密钥仅在响应中返回一次
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param data body dto.CreateAPIKeyRequest true "名称、权限范围和过期时间"
+// @Security BearerAuth
+// @Success 200 {object} dto.CreateAPIKeyResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/api-keys [post]
+func (c *APIKeyController) CreateUserAPIKey(ctx *gin.Context) {
+	if rejectAPIKey(ctx) {
+		return
+	}
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.apiKeyService.CreateUserAPIKey(userID, &req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "创建成功，请妥善保存密钥，关闭后将无法再次查看",
+		"data": resp,
+	})
+}
+
+// GetUserAPIKeys 获取当前用户的 API Key
+// @Summary API Key 列表
+// @Tags API Key
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.APIKeyResponse
+// @Router /api/v1/users/me/api-keys [get]
+func (c *APIKeyController) GetUserAPIKeys(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	keys, err := c.apiKeyService.GetUserAPIKeys(userID)
+	if err != nil {
+		zap.L().Error("获取 API Key 失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取 API Key 失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": keys,
+	})
+}
+
+// RevokeUserAPIKey 吊销当前用户的 API Key
+// @Summary 吊销 API Key
+// @Tags API Key
+// @Produce json
+// @Param id path string true "API Key ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/api-keys/{id} [delete]
+func (c *APIKeyController) RevokeUserAPIKey(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	keyID, ok := apiKeyID(ctx, "id")
+	if !ok {
+		return
+	}
+
+	respondRevokeAPIKey(ctx, c.apiKeyService.RevokeUserAPIKey(userID, keyID))
+}
+
+// CreateRoleAPIKey 创建角色的 API Key
+// @Summary 创建角色 API Key
+// @Description 创建归属于角色的 API Key，供服务间调用，权限范围不能超出角色和创建者的权限
+// @Tags API Key
+// @Accept json
+// @Produce json
+// @Param id path int true "角色ID"
+// @Param data body dto.CreateAPIKeyRequest true "名称、权限范围和过期时间"
+// @Security BearerAuth
+// @Success 200 {object} dto.CreateAPIKeyResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/roles/{id}/api-keys [post]
+func (c *APIKeyController) CreateRoleAPIKey(ctx *gin.Context) {
+	if rejectAPIKey(ctx) {
+		return
+	}
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	roleID, ok := roleIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.apiKeyService.CreateRoleAPIKey(roleID, userID, &req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "创建成功，请妥善保存密钥，关闭后将无法再次查看",
+		"data": resp,
+	})
+}
+
+// GetRoleAPIKeys 获取角色的 API Key
+// @Summary 角色 API Key 列表
+// @Tags API Key
+// @Produce json
+// @Param id path int true "角色ID"
+// @Security BearerAuth
+// @Success 200 {array} dto.APIKeyResponse
+// @Router /api/v1/roles/{id}/api-keys [get]
+func (c *APIKeyController) GetRoleAPIKeys(ctx *gin.Context) {
+	roleID, ok := roleIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	keys, err := c.apiKeyService.GetRoleAPIKeys(roleID)
+	if err != nil {
+		zap.L().Error("获取 API Key 失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取 API Key 失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": keys,
+	})
+}
+
+// RevokeRoleAPIKey 吊销角色的 API Key
+// @Summary 吊销角色 API Key
+// @Tags API Key
+// @Produce json
+// @Param id path int true "角色ID"
+// @Param keyId path string true "API Key ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/roles/{id}/api-keys/{keyId} [delete]
+func (c *APIKeyController) RevokeRoleAPIKey(ctx *gin.Context) {
+	roleID, ok := roleIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	keyID, ok := apiKeyID(ctx, "keyId")
+	if !ok {
+		return
+	}
+
+	respondRevokeAPIKey(ctx, c.apiKeyService.RevokeRoleAPIKey(roleID, keyID))
+}
+
+// rejectAPIKey 禁止使用 API Key 创建新的 API Key，避免绕过过期时间
+func rejectAPIKey(ctx *gin.Context) bool {
+	if _, exists := ctx.Get("apiKeyID"); !exists {
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"code": http.StatusForbidden,
+		"msg":  "不能使用 API Key 创建 API Key",
+		"data": nil,
+	})
+	return true
+}
+
+// roleIDParam 解析路径中的角色ID
+func roleIDParam(ctx *gin.Context) (uint, bool) {
+	roleID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的角色ID",
+			"data": nil,
+		})
+		return 0, false
+	}
+	return uint(roleID), true
+}
+
+// apiKeyID 解析路径中的 API Key ID
+func apiKeyID(ctx *gin.Context, param string) (uuid.UUID, bool) {
+	keyID, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的 API Key ID",
+			"data": nil,
+		})
+		return uuid.Nil, false
+	}
+	return keyID, true
+}
+
+// respondRevokeAPIKey 返回吊销 API Key 的结果
+func respondRevokeAPIKey(ctx *gin.Context, err error) {
+	if err != nil {
+		if errors.Is(err, model.ErrAPIKeyNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"code": http.StatusNotFound,
+				"msg":  err.Error(),
+				"data": nil,
+			})
+			return
+		}
+		zap.L().Error("吊销 API Key 失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "吊销 API Key 失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "吊销成功",
+		"data": nil,
+	})
+}
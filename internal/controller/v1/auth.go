@@ -1,10 +1,11 @@
 package v1
 
 import (
+	"errors"
 	"interviewGenius/internal/model"
 	"interviewGenius/internal/pkg/util"
+	"interviewGenius/internal/service"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -78,6 +79,7 @@ type RefreshTokenRequest struct {
 }
 
 // RefreshToken 刷新访问令牌
+// 刷新令牌每次使用后轮换，已使用的刷新令牌被重放时整个令牌家族失效
 func RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -89,47 +91,60 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 验证刷新令牌
-	token := strings.TrimPrefix(req.RefreshToken, "Bearer ")
-	claims, err := util.ParseToken(token)
+	resp, err := service.NewUserService().RefreshToken(req.RefreshToken)
 	if err != nil {
+		if errors.Is(err, model.ErrRefreshTokenReused) {
+			zap.L().Warn("检测到刷新令牌重放", zap.String("ip", c.ClientIP()))
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code": http.StatusUnauthorized,
-			"msg":  "无效的刷新令牌",
+			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 
-	// 获取用户ID
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": http.StatusInternalServerError,
-			"msg":  "无效的用户ID",
-			"data": nil,
-		})
-		return
+	c.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "刷新令牌成功",
+		"data": resp,
+	})
+}
+
+// LogoutRequest 注销请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout 注销登录，当前访问令牌立即失效
+func Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": http.StatusBadRequest,
+				"msg":  "无效的请求参数",
+				"data": nil,
+			})
+			return
+		}
 	}
 
-	// 检查用户是否存在
-	user, err := model.GetUserByID(userID)
-	if err != nil {
+	claims, exists := c.Get("claims")
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code": http.StatusUnauthorized,
-			"msg":  "用户不存在",
+			"msg":  "未认证",
 			"data": nil,
 		})
 		return
 	}
 
-	// 生成新的访问令牌
-	newToken, err := util.GenerateToken(user.ID.String(), user.Username)
-	if err != nil {
-		zap.L().Error("生成令牌失败", zap.Error(err))
+	if err := service.NewUserService().Logout(claims.(*util.Claims), req.RefreshToken); err != nil {
+		zap.L().Error("注销失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": http.StatusInternalServerError,
-			"msg":  "生成令牌失败",
+			"msg":  "注销失败",
 			"data": nil,
 		})
 		return
@@ -137,9 +152,15 @@ func RefreshToken(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
-		"msg":  "刷新令牌成功",
-		"data": gin.H{
-			"token": newToken,
-		},
+		"msg":  "注销成功",
+		"data": nil,
+	})
+}
+
+// GetJWKS 获取JWT验签公钥集合，供其他服务验证令牌
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"keys": util.JWKS(),
 	})
 }
@@ -0,0 +1,210 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CouponController struct {
+	couponService *service.CouponService
+}
+
+func NewCouponController() *CouponController {
+	return &CouponController{
+		couponService: service.NewCouponService(),
+	}
+}
+
+// CreateCoupon 创建优惠券
+// @Summary 创建优惠券
+// @Tags 优惠券
+// @Accept json
+// @Produce json
+// @Param data body dto.CouponRequest true "优惠券"
+// @Security BearerAuth
+// @Success 200 {object} model.Coupon
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/coupons [post]
+func (c *CouponController) CreateCoupon(ctx *gin.Context) {
+	var req dto.CouponRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	coupon, err := c.couponService.CreateCoupon(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "创建成功",
+		"data": coupon,
+	})
+}
+
+// GetCouponList 获取优惠券列表
+// @Summary 优惠券列表
+// @Tags 优惠券
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(10)
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/coupons [get]
+func (c *CouponController) GetCouponList(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	coupons, total, err := c.couponService.GetCouponList(page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取优惠券列表失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"list":  coupons,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+// GetCoupon 获取优惠券详情
+// @Summary 优惠券详情
+// @Tags 优惠券
+// @Produce json
+// @Param id path int true "优惠券ID"
+// @Security BearerAuth
+// @Success 200 {object} model.Coupon
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/coupons/{id} [get]
+func (c *CouponController) GetCoupon(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的优惠券ID")
+	if !ok {
+		return
+	}
+
+	coupon, err := c.couponService.GetCoupon(id)
+	if err != nil {
+		respondCouponError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": coupon,
+	})
+}
+
+// UpdateCoupon 更新优惠券
+// @Summary 更新优惠券
+// @Description 修改只影响之后创建的订单，已占用次数保持不变
+// @Tags 优惠券
+// @Accept json
+// @Produce json
+// @Param id path int true "优惠券ID"
+// @Param data body dto.CouponRequest true "优惠券"
+// @Security BearerAuth
+// @Success 200 {object} model.Coupon
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/coupons/{id} [put]
+func (c *CouponController) UpdateCoupon(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的优惠券ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CouponRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	coupon, err := c.couponService.UpdateCoupon(id, &req)
+	if err != nil {
+		respondCouponError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "更新成功",
+		"data": coupon,
+	})
+}
+
+// DeleteCoupon 删除优惠券
+// @Summary 删除优惠券
+// @Tags 优惠券
+// @Produce json
+// @Param id path int true "优惠券ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/coupons/{id} [delete]
+func (c *CouponController) DeleteCoupon(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的优惠券ID")
+	if !ok {
+		return
+	}
+
+	if err := c.couponService.DeleteCoupon(id); err != nil {
+		respondCouponError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "删除成功",
+		"data": nil,
+	})
+}
+
+// respondCouponError 优惠券不存在返回 404，其余为请求错误
+func respondCouponError(ctx *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, model.ErrCouponNotFound) {
+		status = http.StatusNotFound
+	}
+	ctx.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+		"data": nil,
+	})
+}
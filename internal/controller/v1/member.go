@@ -1,11 +1,16 @@
 package v1
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"interviewGenius/internal/dto"
 	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"io"
 	"net/http"
+	"strings"
 )
 
 // GetMemberInfo 获取用户会员信息
@@ -79,7 +84,8 @@ func GetMemberCards(c *gin.Context) {
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	CardID uint `json:"card_id" binding:"required"`
+	CardID     uint   `json:"card_id" binding:"required"`
+	CouponCode string `json:"coupon_code"` // 优惠码，可选
 }
 
 // CreateOrder 创建会员卡订单
@@ -116,7 +122,7 @@ func CreateOrder(c *gin.Context) {
 	}
 
 	// 获取会员卡信息
-	card, err := model.GetMemberCardByID(req.CardID)
+	card, err := model.GetAvailableMemberCard(req.CardID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code": http.StatusBadRequest,
@@ -127,7 +133,23 @@ func CreateOrder(c *gin.Context) {
 	}
 
 	// 创建订单
-	order, err := model.CreateOrder(userID, card.ID, card.Price)
+	order, err := model.CreateOrder(userID, card, "", req.CouponCode)
+	if errors.Is(err, model.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": http.StatusForbidden,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	if errors.Is(err, model.ErrCouponUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
 	if err != nil {
 		zap.L().Error("创建订单失败", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -142,31 +164,26 @@ func CreateOrder(c *gin.Context) {
 		"code": http.StatusOK,
 		"msg":  "创建订单成功",
 		"data": gin.H{
-			"order_id": order.ID,
-			"amount":   order.Amount,
+			"order_id":        order.ID,
+			"amount":          order.Amount,
+			"original_amount": order.OriginalAmount,
+			"discount":        order.Discount,
 		},
 	})
 }
 
-// PayOrderRequest 支付订单请求
-type PayOrderRequest struct {
-	PaymentMethod string `json:"payment_method" binding:"required"`
-}
-
-// PayOrder 支付会员卡订单
+// PayOrder 为已创建的订单发起支付
+// 跳转地址直接重定向到支付渠道的收银台，二维码内容（如微信支付 Native）以 JSON 返回；
+// 订单状态由渠道的异步通知更新
 func PayOrder(c *gin.Context) {
-	orderID := c.Param("id")
-	if orderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code": http.StatusBadRequest,
-			"msg":  "缺少订单ID",
-			"data": nil,
-		})
+	orderID, ok := uuidParam(c, "id", "无效的订单ID")
+	if !ok {
 		return
 	}
 
-	var req PayOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	// 请求体可以为空，此时使用订单已选择的渠道或默认渠道
+	var req dto.OrderPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code": http.StatusBadRequest,
 			"msg":  "无效的请求参数",
@@ -175,42 +192,34 @@ func PayOrder(c *gin.Context) {
 		return
 	}
 
-	// 获取当前用户ID
-	_, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code": http.StatusUnauthorized,
-			"msg":  "未认证",
-			"data": nil,
-		})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	orderUUID, err := uuid.Parse(orderID)
+	response, err := service.NewPaymentService().PayOrder(c.Request.Context(), userID, orderID, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code": http.StatusBadRequest,
-			"msg":  "无效的订单ID",
+		status := http.StatusBadRequest
+		if errors.Is(err, model.ErrOrderNotFound) {
+			status = http.StatusNotFound
+		}
+		zap.L().Warn("发起订单支付失败", zap.String("order_id", orderID.String()), zap.Error(err))
+		c.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
 			"data": nil,
 		})
 		return
 	}
 
-	// 这里应该有支付逻辑，为了简化示例，直接标记为已支付
-	if err := model.PayOrder(orderUUID); err != nil {
-		zap.L().Error("支付订单失败", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": http.StatusInternalServerError,
-			"msg":  "支付订单失败",
-			"data": nil,
-		})
+	if strings.HasPrefix(response.PayURL, "https://") || strings.HasPrefix(response.PayURL, "http://") {
+		c.Redirect(http.StatusSeeOther, response.PayURL)
 		return
 	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
-		"msg":  "支付订单成功",
-		"data": nil,
+		"msg":  "创建支付成功",
+		"data": response,
 	})
 }
 
@@ -257,6 +266,49 @@ func GetOrders(c *gin.Context) {
 	})
 }
 
+// GetOrderEvents 获取订单的状态变更记录
+func GetOrderEvents(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orderID, ok := uuidParam(c, "id", "无效的订单ID")
+	if !ok {
+		return
+	}
+
+	// 只能查看自己的订单
+	order, err := model.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": http.StatusNotFound,
+			"msg":  "订单不存在",
+			"data": nil,
+		})
+		return
+	}
+
+	events, err := model.GetOrderEvents(order.ID)
+	if err != nil {
+		zap.L().Error("获取订单状态记录失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取订单状态记录失败",
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取订单状态记录成功",
+		"data": gin.H{
+			"status": order.Status,
+			"events": events,
+		},
+	})
+}
+
 // CheckServiceAccess 检查用户是否可以使用服务
 func CheckServiceAccess(c *gin.Context) {
 	// 获取当前用户ID
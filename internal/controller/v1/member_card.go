@@ -0,0 +1,329 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MemberCardController struct {
+	memberCardService *service.MemberCardService
+}
+
+func NewMemberCardController() *MemberCardController {
+	return &MemberCardController{
+		memberCardService: service.NewMemberCardService(),
+	}
+}
+
+// GetMemberCardList 获取全部会员卡
+// @Summary 会员卡管理列表
+// @Description 包括已下架的会员卡，按展示顺序排列
+// @Tags 会员卡
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/member-cards [get]
+func (c *MemberCardController) GetMemberCardList(ctx *gin.Context) {
+	cards, err := c.memberCardService.GetMemberCardList()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取会员卡列表失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"cards": cards,
+		},
+	})
+}
+
+// CreateMemberCard 创建会员卡
+// @Summary 创建会员卡
+// @Tags 会员卡
+// @Accept json
+// @Produce json
+// @Param data body dto.CreateMemberCardRequest true "会员卡"
+// @Security BearerAuth
+// @Success 200 {object} model.MemberCard
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/member-cards [post]
+func (c *MemberCardController) CreateMemberCard(ctx *gin.Context) {
+	var req dto.CreateMemberCardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	card, err := c.memberCardService.CreateMemberCard(&req, operatorID(ctx))
+	if err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "创建成功",
+		"data": card,
+	})
+}
+
+// UpdateMemberCard 更新会员卡
+// @Summary 更新会员卡
+// @Description 修改名称、时长、描述、上下架和展示顺序，价格通过调价接口修改
+// @Tags 会员卡
+// @Accept json
+// @Produce json
+// @Param id path int true "会员卡ID"
+// @Param data body dto.UpdateMemberCardRequest true "会员卡"
+// @Security BearerAuth
+// @Success 200 {object} model.MemberCard
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/member-cards/{id} [put]
+func (c *MemberCardController) UpdateMemberCard(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的会员卡ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateMemberCardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	card, err := c.memberCardService.UpdateMemberCard(id, &req)
+	if err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "更新成功",
+		"data": card,
+	})
+}
+
+// SortMemberCards 调整会员卡展示顺序
+// @Summary 会员卡排序
+// @Tags 会员卡
+// @Accept json
+// @Produce json
+// @Param data body dto.SortMemberCardsRequest true "按顺序排列的会员卡ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/member-cards/sort [put]
+func (c *MemberCardController) SortMemberCards(ctx *gin.Context) {
+	var req dto.SortMemberCardsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.memberCardService.SortMemberCards(req.CardIDs); err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "排序成功",
+		"data": nil,
+	})
+}
+
+// DeleteMemberCard 归档会员卡
+// @Summary 归档会员卡
+// @Description 归档后不再展示和下单，已有订单不受影响
+// @Tags 会员卡
+// @Produce json
+// @Param id path int true "会员卡ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/member-cards/{id} [delete]
+func (c *MemberCardController) DeleteMemberCard(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的会员卡ID")
+	if !ok {
+		return
+	}
+
+	if err := c.memberCardService.DeleteMemberCard(id); err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "归档成功",
+		"data": nil,
+	})
+}
+
+// GetMemberCardPrices 获取会员卡调价记录
+// @Summary 调价记录
+// @Tags 会员卡
+// @Produce json
+// @Param id path int true "会员卡ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/member-cards/{id}/prices [get]
+func (c *MemberCardController) GetMemberCardPrices(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的会员卡ID")
+	if !ok {
+		return
+	}
+
+	prices, err := c.memberCardService.GetPrices(id)
+	if err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"prices": prices,
+		},
+	})
+}
+
+// CreateMemberCardPrice 调整会员卡价格
+// @Summary 调价
+// @Description 不指定生效时间时立即生效，否则到期后生效；已创建的订单金额不受影响
+// @Tags 会员卡
+// @Accept json
+// @Produce json
+// @Param id path int true "会员卡ID"
+// @Param data body dto.MemberCardPriceRequest true "价格和生效时间"
+// @Security BearerAuth
+// @Success 200 {object} model.MemberCardPrice
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/member-cards/{id}/prices [post]
+func (c *MemberCardController) CreateMemberCardPrice(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的会员卡ID")
+	if !ok {
+		return
+	}
+
+	var req dto.MemberCardPriceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	price, err := c.memberCardService.SchedulePrice(id, &req, operatorID(ctx))
+	if err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	msg := "调价成功"
+	if price.AppliedAt == nil {
+		msg = "调价将在生效时间后生效"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  msg,
+		"data": price,
+	})
+}
+
+// DeleteMemberCardPrice 取消尚未生效的调价
+// @Summary 取消调价
+// @Tags 会员卡
+// @Produce json
+// @Param id path int true "会员卡ID"
+// @Param priceId path int true "调价记录ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/member-cards/{id}/prices/{priceId} [delete]
+func (c *MemberCardController) DeleteMemberCardPrice(ctx *gin.Context) {
+	id, ok := uintParam(ctx, "id", "无效的会员卡ID")
+	if !ok {
+		return
+	}
+	priceID, ok := uintParam(ctx, "priceId", "无效的调价记录ID")
+	if !ok {
+		return
+	}
+
+	if err := c.memberCardService.CancelPrice(id, priceID); err != nil {
+		respondMemberCardError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "已取消调价",
+		"data": nil,
+	})
+}
+
+// uintParam 解析路径中的数字ID，格式错误时直接响应 400
+func uintParam(ctx *gin.Context, param, msg string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(param), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  msg,
+			"data": nil,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// operatorID 当前操作的管理员，角色 API Key 没有关联用户时为空
+func operatorID(ctx *gin.Context) uuid.UUID {
+	var id uuid.UUID
+	if userID, exists := ctx.Get("userID"); exists {
+		id, _ = uuid.Parse(userID.(string))
+	}
+	return id
+}
+
+// respondMemberCardError 会员卡或调价不存在返回 404，其余为请求错误
+func respondMemberCardError(ctx *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, model.ErrMemberCardNotFound) || errors.Is(err, model.ErrMemberCardPriceNotFound) {
+		status = http.StatusNotFound
+	}
+	ctx.JSON(status, gin.H{
+		"code": status,
+		"msg":  err.Error(),
+		"data": nil,
+	})
+}
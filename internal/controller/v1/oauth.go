@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"interviewGenius/internal/pkg/oauth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetOAuthProviders 获取已启用的第三方登录方式
+// @Summary 第三方登录方式
+// @Tags 第三方登录
+// @Produce json
+// @Success 200 {object} dto.Response
+// @Router /api/v1/oauth/providers [get]
+func (c *UserController) GetOAuthProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"providers": oauth.Names(),
+		},
+	})
+}
+
+// OAuthLogin 跳转到第三方授权页面
+// @Summary 第三方登录
+// @Tags 第三方登录
+// @Param provider path string true "提供方名称"
+// @Success 302
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/oauth/{provider}/login [get]
+func (c *UserController) OAuthLogin(ctx *gin.Context) {
+	authURL, err := c.userService.OAuthLoginURL(ctx.Param("provider"), nil)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback 第三方授权回调
+// @Summary 第三方登录回调
+// @Description 登录流程返回令牌（或两步验证挑战），绑定流程返回绑定结果
+// @Tags 第三方登录
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} dto.TokenResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/oauth/{provider}/callback [get]
+func (c *UserController) OAuthCallback(ctx *gin.Context) {
+	if errCode := ctx.Query("error"); errCode != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "第三方授权被拒绝: " + errCode,
+			"data": nil,
+		})
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, linked, err := c.userService.OAuthCallback(ctx.Request.Context(), ctx.Param("provider"), state, code, clientInfo(ctx))
+	if err != nil {
+		zap.L().Info("第三方登录失败", zap.String("provider", ctx.Param("provider")), zap.Error(err))
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	msg := "登录成功"
+	switch {
+	case linked:
+		msg = "绑定成功"
+	case resp.TwoFactorRequired:
+		msg = "需要两步验证"
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  msg,
+		"data": resp,
+	})
+}
+
+// LinkIdentity 绑定第三方账号
+// @Summary 绑定第三方账号
+// @Description 返回授权地址，前端跳转授权后在回调中完成绑定
+// @Tags 第三方登录
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/identities/{provider} [post]
+func (c *UserController) LinkIdentity(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	authURL, err := c.userService.OAuthLoginURL(ctx.Param("provider"), &userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"url": authURL,
+		},
+	})
+}
+
+// GetUserIdentities 获取已绑定的第三方账号
+// @Summary 已绑定的第三方账号
+// @Tags 第三方登录
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/users/me/identities [get]
+func (c *UserController) GetUserIdentities(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	identities, err := c.userService.GetUserIdentities(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取绑定信息失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": identities,
+	})
+}
+
+// UnlinkIdentity 解除第三方账号绑定
+// @Summary 解除第三方账号绑定
+// @Tags 第三方登录
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/identities/{provider} [delete]
+func (c *UserController) UnlinkIdentity(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.userService.UnlinkIdentity(userID, ctx.Param("provider")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "解除成功",
+		"data": nil,
+	})
+}
@@ -1,11 +1,17 @@
 package v1
 
 import (
+	"errors"
 	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
 	"interviewGenius/internal/pkg/payment"
 	"interviewGenius/internal/service"
+	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type PaymentController struct {
@@ -13,89 +19,193 @@ type PaymentController struct {
 }
 
 func NewPaymentController() *PaymentController {
-	config := &payment.AlipayConfig{
-		AppID:        "9021000134664821",
-		PrivateKey:   "MIIEogIBAAKCAQEAwOjAbX3n3//o/j2WpkWULdfitzQqjPdhSbfXUNvL48FK+uVeYn13VA4OR1pZGM9alUaq+a0QJlJcz2Vt8PAcvT57JBp3dGPJc8WWAYQ/eKqwOvsF+bfa/yjrjlg4nbLjP/ZjlaM3zs39Ybnt0/vdFPDyVpNHk6H//n6Q0lKR8yL67M27RHgYkm3cU/khRvj738XJzY91vH0V2+nTMtkUorPf7WO4IRwhHGBFzhafQ/q9eenyS5tsJJ8m/eQOJ2k0dMfCjw2IK8ShT2qfBU3VD4MBKpmhdJu5IBXxdC/B+y3BimTQYC8rhwAN+MzdB/t+tOyQKbivwFZ+JkicK4Gw8wIDAQABAoIBAFGvfSQgB1rDu35EyBD6L4fF/buD/Gyap/iWPzd/CvQTOlPJYlEkPa47EXLHYCjwTLQfK3D0Bn2jrKcplQdMNW8xEOW1y1Vel8RNK3rS7CmFZYBkISCf6LzZL/2jf73PLQk9pOeNKKmKcju6hmmYIgKnEIb2cH2kQIkcQOi+jAy+y5wIxnb4+zqgbqqXzEKEPZQtV7uDIwsOKL4Nz6uokJGu5ekGe8lwiE7e7e2JRQZQAZ/CGC8XwFDaSqQVDtMFEQBS1fWR9wvQ4XlmH/IaSCy9grwoVHY+oqv5tG+tGdOJt8sKY8jJ7shWV+bcgKSnf6gOf5U7h6VvhqIvYJg5TvECgYEA7Ljb5pzqBDOmAFWZqDoJJd8z3UWHnW5JsYWtT9XIG1cfR8iFfccByFNOVUKHYLR6WAWPlYjUmRfm9aEcAaND6G79W41tfvSLZHXju5bKFbJmQqRDEOQtCELKZ2Cx0SHTBhgrk6eoMRrCIpIMraAnf3fu5VhtH943LMhiADyZ12kCgYEA0J59FcZ9vYyV3fCa6QKZsWmWQFKJZOsyhHcXII+D28hTCWhv0SIwGfgrkXgX3CAlx+CG061dxH8X5YMMkCLhIlAzOz+6Uj3y/yarThsz2SDulnRzUnwNv0u2rGhdgF+NmryoVY11ds8MbQAACQT0U9DwM8XWOPd3lxdZRszk9fsCgYBAH99pvA3kb31DT+zc1kPOH4V0JjaTXeHWleiZ3MZlKZeOoXIP3U3NT0vD6s6zUpBlsbPwhO1aP1BQL4FfrDNkDlTRbSFBJ8tuvkSfdzxs3jO3T7nfJIBSYY1krZvdk/UPDJMZX2w/SQlXxgprKhwo+nsbY3XEETUPC4UInWHrKQKBgFksxi8+r5UMuSsrpCwiDmyFw9Iu9cgLuYZiGaKzdhvGn6gP2mw8/u665HTELv7LRxsPYNKu8rwBz8cto3shTbcLLTsQXKa3EF38u5Ehk6Imr5XkpT8HBCFXTfiYjA9JyQ/xwMsBMsrcamVVcK5qTb5eO68FzDKBpb8SHfljsCNtAoGAJCdJ3lnEWm46B43kxE+3mW9D/iaJuJaFPHw/795I5XkXIyIR7RReKN/B7KofHL2RUlVoQWIaW/NU50BrSuCerWKarjp6gKNRr6C/jWz4cEFs4XYh1sCahP6QKtFgNsKOnQaLlAONkQC0HGtkGuZLVmUKSSY9VnY+1XfKBiPwyfY=",
-		AliPublicKey: "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAyX32DX+ijKSxqBCMffXjlYIKDbXPwUAs9E6g974kO3s6evhQSoMkfZYMDbqSZ9oAJQvIchE7W9sdJl3Q0prNBGhTffVlVyUDRpiKTX2WClSss8gybSRNUwY4jHZljcDFVPMlwGF/ushkuHhlg/UKuNHIeT6Xmy3nPOt7MIPeBGewDGCQlF0DYLfpha3U7puGgGMzByZ0ENVYyruCJrUMoe13Le1di5eaueElYq8G0ACVkEE8w2l+FetxN3EkaxSY1k3LffdP+0gPIm5oSPmCS3IdjCRvkn8NiwkdhyXli9ff04U7HJhQEHz01W+igIEHNpIrejpNdMFLYmsnLYpp6wIDAQAB",
-		NotifyURL:    "https://your-domain.com/api/v1/payment/notify",
-		ReturnURL:    "https://your-domain.com/api/v1/payment/return",
-		IsProduction: false, // 测试环境
-	}
-
-	paymentService, err := service.NewPaymentService(config)
-	if err != nil {
-		panic(err)
-	}
-
 	return &PaymentController{
-		paymentService: paymentService,
+		paymentService: service.NewPaymentService(),
 	}
 }
 
+// GetPaymentProviders 获取可用的支付方式
+// @Summary 支付方式
+// @Tags 支付
+// @Produce json
+// @Success 200 {object} dto.Response
+// @Router /api/v1/payment/providers [get]
+func (c *PaymentController) GetPaymentProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"providers": payment.Names(),
+		},
+	})
+}
+
 // CreatePayment 创建支付订单
+// @Summary 创建支付
+// @Description 按所选支付方式下单，返回跳转支付的地址；未指定支付方式时使用默认渠道
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param request body dto.PaymentRequest true "支付请求"
+// @Security BearerAuth
+// @Success 200 {object} dto.PaymentResponse
+// @Failure 400 {object} dto.Response
+// @Failure 403 {object} dto.Response
+// @Router /api/v1/payment/create [post]
 func (c *PaymentController) CreatePayment(ctx *gin.Context) {
 	var req dto.PaymentRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(400, gin.H{"error": "无效的请求数据"})
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
 		return
 	}
 
-	// 获取当前用户ID
-	userID := ctx.GetString("user_id")
-	if userID == "" {
-		ctx.JSON(401, gin.H{"error": "未授权"})
+	userID, ok := currentUserID(ctx)
+	if !ok {
 		return
 	}
 
-	response, err := c.paymentService.CreatePayment(userID, req.CardID)
+	response, err := c.paymentService.CreatePayment(ctx.Request.Context(), userID, &req)
+	if errors.Is(err, model.ErrEmailNotVerified) {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code": http.StatusForbidden,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
 	if err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		zap.L().Warn("创建支付失败", zap.String("provider", req.Provider), zap.Error(err))
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
 		return
 	}
 
-	ctx.JSON(200, response)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "创建支付成功",
+		"data": response,
+	})
 }
 
-// HandlePaymentNotify 处理支付通知
-func (c *PaymentController) HandlePaymentNotify(ctx *gin.Context) {
-	var req dto.PaymentNotifyRequest
-	if err := ctx.ShouldBind(&req); err != nil {
-		ctx.JSON(400, gin.H{"error": "无效的请求数据"})
+// ConfirmPayment 确认订单已线下收款
+// @Summary 确认线下收款
+// @Description 管理员凭收款凭证号将未支付订单标记为已支付，订单已选择渠道时先关闭渠道交易
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param id path string true "订单ID"
+// @Param data body dto.ConfirmPaymentRequest true "收款凭证"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/orders/{id}/confirm [post]
+func (c *PaymentController) ConfirmPayment(ctx *gin.Context) {
+	orderID, ok := uuidParam(ctx, "id", "无效的订单ID")
+	if !ok {
+		return
+	}
+
+	var req dto.ConfirmPaymentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
 		return
 	}
 
-	// 将请求参数转换为map
-	params := make(map[string]string)
-	for k, v := range ctx.Request.URL.Query() {
-		if len(v) > 0 {
-			params[k] = v[0]
+	if err := c.paymentService.ConfirmOfflinePayment(ctx.Request.Context(), orderID, operatorID(ctx), &req); err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, model.ErrOrderNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, model.ErrOrderAlreadyPaid):
+			status = http.StatusConflict
 		}
+		zap.L().Warn("确认线下收款失败", zap.String("order_id", orderID.String()), zap.Error(err))
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
 	}
 
-	if err := c.paymentService.HandlePaymentNotify(params); err != nil {
-		ctx.JSON(500, gin.H{"error": err.Error()})
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "确认收款成功",
+		"data": nil,
+	})
+}
+
+// HandlePaymentNotify 处理支付渠道的异步通知
+// 渠道名称取自路径参数，/payment/notify 兼容原有的支付宝通知地址
+// 应答格式由渠道决定，处理失败时渠道会按策略重试
+func (c *PaymentController) HandlePaymentNotify(ctx *gin.Context) {
+	name := ctx.Param("provider")
+	if name == "" {
+		name = "alipay"
+	}
+	provider, ok := payment.Get(name)
+	if !ok {
+		ctx.String(http.StatusNotFound, "fail")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 64<<10))
+	if err != nil {
+		provider.AckNotify(ctx.Writer, err)
 		return
 	}
 
-	ctx.String(200, "success")
+	err = c.paymentService.HandlePaymentNotify(provider, ctx.Request.Header, body, ctx.ClientIP())
+	if err != nil {
+		zap.L().Warn("处理支付通知失败", zap.String("provider", name), zap.Error(err))
+	}
+	provider.AckNotify(ctx.Writer, err)
 }
 
-// HandlePaymentReturn 处理支付返回
+// HandlePaymentReturn 处理支付完成后的同步跳转
+// 只校验签名并跳转到结果页，订单是否支付成功以异步通知为准
 func (c *PaymentController) HandlePaymentReturn(ctx *gin.Context) {
-	// 将请求参数转换为map
-	params := make(map[string]string)
-	for k, v := range ctx.Request.URL.Query() {
-		if len(v) > 0 {
-			params[k] = v[0]
-		}
+	name := ctx.Param("provider")
+	if name == "" {
+		name = "alipay"
 	}
 
-	// 处理支付返回
-	if err := c.paymentService.HandlePaymentReturn(params); err != nil {
-		ctx.JSON(400, gin.H{"error": err.Error()})
+	orderID, err := c.paymentService.HandlePaymentReturn(name, ctx.Request.URL.Query())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
 		return
 	}
 
-	// 重定向到支付成功页面
-	ctx.Redirect(302, "/payment/success")
+	// 重定向到支付结果页面，由前端查询订单状态
+	ctx.Redirect(http.StatusFound, "/payment/success?order_id="+url.QueryEscape(orderID))
+}
+
+// SandboxCheckout 沙箱收银台，仅在启用沙箱渠道时可用
+func (c *PaymentController) SandboxCheckout(ctx *gin.Context) {
+	provider, _ := payment.Get("sandbox")
+	handler, ok := provider.(http.Handler)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code": http.StatusNotFound,
+			"msg":  "沙箱支付未启用",
+			"data": nil,
+		})
+		return
+	}
+	handler.ServeHTTP(ctx.Writer, ctx.Request)
 }
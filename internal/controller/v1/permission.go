@@ -85,6 +85,7 @@ func GetPermissionList(c *gin.Context) {
 			"method":       permission.Method,
 			"path_pattern": permission.PathPattern,
 			"description":  permission.Description,
+			"orphaned":     permission.Orphaned,
 		})
 	}
 
@@ -98,6 +99,26 @@ func GetPermissionList(c *gin.Context) {
 	})
 }
 
+// GetPermissionReport 获取权限与路由差异报告
+func GetPermissionReport(c *gin.Context) {
+	report, err := model.GetPermissionDriftReport()
+	if err != nil {
+		zap.L().Error("获取权限差异报告失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取权限差异报告失败",
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": report,
+	})
+}
+
 // GetPermissionDetail 获取权限详情
 func GetPermissionDetail(c *gin.Context) {
 	// 获取路径参数
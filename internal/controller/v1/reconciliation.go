@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReconcileController struct {
+	reconcileService *service.ReconcileService
+}
+
+func NewReconcileController() *ReconcileController {
+	return &ReconcileController{
+		reconcileService: service.NewReconcileService(),
+	}
+}
+
+// CreateReconciliation 手动核对渠道账单
+// @Summary 账单核对
+// @Description 核对渠道某一天的账单；上传 file 时使用上传的账单，否则读取账单目录或由渠道提供
+// @Tags 对账
+// @Accept multipart/form-data
+// @Produce json
+// @Param provider formData string true "支付渠道"
+// @Param date formData string true "账单日期 2006-01-02"
+// @Param file formData file false "CSV 账单"
+// @Security BearerAuth
+// @Success 200 {object} model.ReconciliationReport
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/reconciliations [post]
+func (c *ReconcileController) CreateReconciliation(ctx *gin.Context) {
+	provider := ctx.PostForm("provider")
+	date, err := time.ParseInLocation("2006-01-02", ctx.PostForm("date"), time.Local)
+	if provider == "" || err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	var (
+		bill   io.Reader
+		source string
+	)
+	if header, err := ctx.FormFile("file"); err == nil {
+		f, err := header.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code": http.StatusBadRequest,
+				"msg":  "读取账单文件失败",
+				"data": nil,
+			})
+			return
+		}
+		defer f.Close()
+		bill, source = f, "upload:"+header.Filename
+	}
+
+	report, err := c.reconcileService.Reconcile(ctx.Request.Context(), provider, date, bill, source)
+	if err != nil && report == nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrBillNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	// 账单解析失败时同样返回已保存的失败报告
+	msg := "对账完成"
+	if err != nil {
+		msg = "对账失败: " + err.Error()
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  msg,
+		"data": report,
+	})
+}
+
+// GetReconciliations 获取对账报告列表
+// @Summary 对账报告列表
+// @Tags 对账
+// @Produce json
+// @Param provider query string false "支付渠道"
+// @Param page query int false "页码" default(1)
+// @Param limit query int false "每页数量" default(10)
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/reconciliations [get]
+func (c *ReconcileController) GetReconciliations(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	reports, total, err := c.reconcileService.GetReports(ctx.Query("provider"), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取对账报告失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"list":  reports,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		},
+	})
+}
+
+// GetReconciliation 获取对账报告及差异明细
+// @Summary 对账报告详情
+// @Tags 对账
+// @Produce json
+// @Param id path int true "报告ID"
+// @Security BearerAuth
+// @Success 200 {object} model.ReconciliationReport
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/reconciliations/{id} [get]
+func (c *ReconcileController) GetReconciliation(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的报告ID",
+			"data": nil,
+		})
+		return
+	}
+
+	report, err := c.reconcileService.GetReport(uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrReconciliationNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": report,
+	})
+}
@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RefundController struct {
+	refundService *service.RefundService
+}
+
+func NewRefundController() *RefundController {
+	return &RefundController{
+		refundService: service.NewRefundService(),
+	}
+}
+
+// CreateRefund 订单退款
+// @Summary 订单退款
+// @Description 全额或部分退款，退款成功后按比例回退该订单尚未使用的会员时长
+// @Tags 退款
+// @Accept json
+// @Produce json
+// @Param id path string true "订单ID"
+// @Param data body dto.CreateRefundRequest true "退款金额和原因"
+// @Security BearerAuth
+// @Success 200 {object} model.Refund
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/orders/{id}/refunds [post]
+func (c *RefundController) CreateRefund(ctx *gin.Context) {
+	orderID, ok := uuidParam(ctx, "id", "无效的订单ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateRefundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	refund, err := c.refundService.CreateRefund(ctx.Request.Context(), orderID, &req, operatorID(ctx))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, model.ErrRefundInProgress) {
+			status = http.StatusConflict
+		}
+		zap.L().Warn("订单退款失败", zap.String("order_id", orderID.String()), zap.Error(err))
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	msg := "退款成功"
+	if refund.Status != model.RefundStatusSuccess {
+		msg = "退款处理中"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  msg,
+		"data": refund,
+	})
+}
+
+// GetOrderRefunds 获取订单的退款记录
+// @Summary 订单退款记录
+// @Tags 退款
+// @Produce json
+// @Param id path string true "订单ID"
+// @Security BearerAuth
+// @Success 200 {array} model.Refund
+// @Router /api/v1/orders/{id}/refunds [get]
+func (c *RefundController) GetOrderRefunds(ctx *gin.Context) {
+	orderID, ok := uuidParam(ctx, "id", "无效的订单ID")
+	if !ok {
+		return
+	}
+
+	refunds, err := c.refundService.GetOrderRefunds(orderID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取退款记录失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": refunds,
+	})
+}
+
+// SyncRefund 查询处理中的退款结果
+// @Summary 同步退款结果
+// @Description 向支付渠道查询处理中的退款，成功后回退会员时长
+// @Tags 退款
+// @Produce json
+// @Param id path string true "订单ID"
+// @Param refundId path string true "退款ID"
+// @Security BearerAuth
+// @Success 200 {object} model.Refund
+// @Failure 400 {object} dto.Response
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/orders/{id}/refunds/{refundId}/sync [post]
+func (c *RefundController) SyncRefund(ctx *gin.Context) {
+	orderID, ok := uuidParam(ctx, "id", "无效的订单ID")
+	if !ok {
+		return
+	}
+	refundID, ok := uuidParam(ctx, "refundId", "无效的退款ID")
+	if !ok {
+		return
+	}
+
+	refund, err := c.refundService.SyncRefund(ctx.Request.Context(), orderID, refundID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, model.ErrRefundNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "同步成功",
+		"data": refund,
+	})
+}
+
+// uuidParam 解析路径中的 UUID 参数
+func uuidParam(ctx *gin.Context, param, msg string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param(param))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  msg,
+			"data": nil,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// GetSessions 获取当前用户的登录会话
+// @Summary 登录设备列表
+// @Description 列出当前用户所有有效的登录会话，发起请求的会话 current 为 true
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.SessionResponse
+// @Router /api/v1/users/me/sessions [get]
+func (c *UserController) GetSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.userService.GetSessions(userID, ctx.GetString("sessionID"))
+	if err != nil {
+		zap.L().Error("获取登录会话失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取登录会话失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": sessions,
+	})
+}
+
+// RevokeSession 注销指定的登录会话
+// @Summary 注销登录设备
+// @Description 吊销指定会话，该设备的访问令牌和刷新令牌随即失效
+// @Tags 用户管理
+// @Produce json
+// @Param id path string true "会话ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/sessions/{id} [delete]
+func (c *UserController) RevokeSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的会话ID",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.userService.RevokeSession(userID, sessionID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "注销成功",
+		"data": nil,
+	})
+}
+
+// RevokeAllSessions 退出全部设备
+// @Summary 退出全部设备
+// @Description 吊销当前用户的全部会话，包括发起请求的会话
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Router /api/v1/users/me/sessions [delete]
+func (c *UserController) RevokeAllSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.userService.RevokeAllSessions(userID); err != nil {
+		zap.L().Error("退出全部设备失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "退出全部设备失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "已退出全部设备",
+		"data": nil,
+	})
+}
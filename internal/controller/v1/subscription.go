@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/payment"
+	"interviewGenius/internal/service"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SubscriptionController struct {
+	subscriptionService *service.SubscriptionService
+}
+
+func NewSubscriptionController() *SubscriptionController {
+	return &SubscriptionController{
+		subscriptionService: service.NewSubscriptionService(),
+	}
+}
+
+// CreateSubscription 开通自动续费
+// @Summary 开通自动续费
+// @Description 创建自动续费订阅并返回签约页面地址，签约成功后首期立即扣款，之后在会员到期前自动扣款
+// @Tags 会员
+// @Accept json
+// @Produce json
+// @Param request body dto.SubscriptionRequest true "开通请求"
+// @Security BearerAuth
+// @Success 200 {object} dto.SubscriptionResponse
+// @Failure 400 {object} dto.Response
+// @Failure 403 {object} dto.Response
+// @Router /api/v1/member/subscriptions [post]
+func (c *SubscriptionController) CreateSubscription(ctx *gin.Context) {
+	var req dto.SubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := c.subscriptionService.Create(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		zap.L().Warn("开通自动续费失败", zap.String("provider", req.Provider), zap.Error(err))
+		status := http.StatusBadRequest
+		if errors.Is(err, model.ErrSubscriptionExists) {
+			status = http.StatusConflict
+		}
+		if errors.Is(err, model.ErrEmailNotVerified) {
+			status = http.StatusForbidden
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "请完成签约",
+		"data": response,
+	})
+}
+
+// GetSubscriptions 获取当前用户的自动续费订阅
+// @Summary 自动续费列表
+// @Tags 会员
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Subscription
+// @Router /api/v1/member/subscriptions [get]
+func (c *SubscriptionController) GetSubscriptions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	subs, err := c.subscriptionService.GetUserSubscriptions(userID)
+	if err != nil {
+		zap.L().Error("获取自动续费列表失败", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "获取自动续费列表失败",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取自动续费列表成功",
+		"data": subs,
+	})
+}
+
+// CancelSubscription 取消自动续费
+// @Summary 取消自动续费
+// @Description 已签约的订阅在当前周期结束后不再扣款；尚未签约或续费失败的订阅立即结束
+// @Tags 会员
+// @Produce json
+// @Param id path string true "订阅ID"
+// @Security BearerAuth
+// @Success 200 {object} model.Subscription
+// @Failure 404 {object} dto.Response
+// @Router /api/v1/member/subscriptions/{id}/cancel [post]
+func (c *SubscriptionController) CancelSubscription(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+	id, ok := uuidParam(ctx, "id", "无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	sub, err := c.subscriptionService.Cancel(ctx.Request.Context(), userID, id)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, model.ErrSubscriptionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrSubscriptionEnded):
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, gin.H{
+			"code": status,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "已取消自动续费",
+		"data": sub,
+	})
+}
+
+// HandleAgreementNotify 处理支付渠道的签约、解约通知
+// 应答格式与支付通知一致，处理失败时渠道会按策略重试
+func (c *SubscriptionController) HandleAgreementNotify(ctx *gin.Context) {
+	name := ctx.Param("provider")
+	provider, ok := payment.Get(name)
+	if !ok {
+		ctx.String(http.StatusNotFound, "fail")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 64<<10))
+	if err != nil {
+		provider.AckNotify(ctx.Writer, err)
+		return
+	}
+
+	err = c.subscriptionService.HandleAgreementNotify(ctx.Request.Context(), provider, ctx.Request.Header, body)
+	if err != nil {
+		zap.L().Warn("处理签约通知失败", zap.String("provider", name), zap.Error(err))
+	}
+	provider.AckNotify(ctx.Writer, err)
+}
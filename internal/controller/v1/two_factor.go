@@ -0,0 +1,264 @@
+package v1
+
+import (
+	"interviewGenius/internal/dto"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor 两步验证登录
+// @Summary 两步验证登录
+// @Description 使用登录返回的挑战令牌和验证码（或恢复码）换取访问令牌
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.TwoFactorLoginRequest true "挑战令牌和验证码"
+// @Success 200 {object} dto.TokenResponse
+// @Failure 400 {object} dto.Response
+// @Failure 429 {object} dto.Response
+// @Router /api/v1/users/login/2fa [post]
+func (c *UserController) LoginTwoFactor(ctx *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.userService.LoginTwoFactor(&req, clientInfo(ctx))
+	if err != nil {
+		if respondBlocked(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "登录成功",
+		"data": resp,
+	})
+}
+
+// SetupTwoFactorWithChallenge 登录过程中设置两步验证
+// @Summary 登录时设置两步验证
+// @Description 策略要求启用两步验证的用户使用挑战令牌生成密钥
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.TwoFactorChallengeRequest true "挑战令牌"
+// @Success 200 {object} dto.TwoFactorSetupResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/login/2fa/setup [post]
+func (c *UserController) SetupTwoFactorWithChallenge(ctx *gin.Context) {
+	var req dto.TwoFactorChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.userService.SetupTwoFactorWithChallenge(req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "请使用认证器应用扫描并输入验证码完成登录",
+		"data": resp,
+	})
+}
+
+// EnrollTwoFactor 开始设置两步验证
+// @Summary 设置两步验证
+// @Description 生成TOTP密钥和 otpauth URI，需调用确认接口后才会启用
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.TwoFactorSetupResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/2fa/enroll [post]
+func (c *UserController) EnrollTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := c.userService.EnrollTwoFactor(userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "获取成功",
+		"data": resp,
+	})
+}
+
+// ConfirmTwoFactor 确认并启用两步验证
+// @Summary 确认两步验证
+// @Description 使用认证器应用生成的验证码确认设置，返回一次性恢复码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.TwoFactorCodeRequest true "验证码"
+// @Security BearerAuth
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/2fa/confirm [post]
+func (c *UserController) ConfirmTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.userService.ConfirmTwoFactor(userID, req.Code)
+	if err != nil {
+		if respondBlocked(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "两步验证已启用",
+		"data": resp,
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 旧恢复码全部作废
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.TwoFactorCodeRequest true "验证码"
+// @Security BearerAuth
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/2fa/recovery-codes [post]
+func (c *UserController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	resp, err := c.userService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		if respondBlocked(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "生成成功",
+		"data": resp,
+	})
+}
+
+// DisableTwoFactor 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 需要提供当前验证码，策略强制启用的账户不能关闭
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body dto.TwoFactorCodeRequest true "验证码"
+// @Security BearerAuth
+// @Success 200 {object} dto.Response
+// @Failure 400 {object} dto.Response
+// @Router /api/v1/users/me/2fa [delete]
+func (c *UserController) DisableTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的请求参数",
+			"data": nil,
+		})
+		return
+	}
+
+	if err := c.userService.DisableTwoFactor(userID, req.Code); err != nil {
+		if respondBlocked(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "两步验证已关闭",
+		"data": nil,
+	})
+}
@@ -1,9 +1,13 @@
 package v1
 
 import (
+	"errors"
 	"interviewGenius/internal/dto"
 	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/loginguard"
+	"interviewGenius/internal/pkg/password"
 	"interviewGenius/internal/service"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -43,12 +47,12 @@ func (c *UserController) Register(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.userService.Register(&req)
+	resp, err := c.userService.Register(&req, clientInfo(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code": http.StatusBadRequest,
 			"msg":  err.Error(),
-			"data": nil,
+			"data": fieldErrors(err),
 		})
 		return
 	}
@@ -69,6 +73,7 @@ func (c *UserController) Register(ctx *gin.Context) {
 // @Param data body dto.LoginRequest true "用户登录信息"
 // @Success 200 {object} dto.TokenResponse
 // @Failure 400 {object} dto.Response
+// @Failure 429 {object} dto.Response
 // @Router /api/v1/users/login [post]
 func (c *UserController) Login(ctx *gin.Context) {
 	var req dto.LoginRequest
@@ -81,8 +86,12 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := c.userService.Login(&req)
+	resp, err := c.userService.Login(&req, clientInfo(ctx))
 	if err != nil {
+		if respondBlocked(ctx, err) {
+			return
+		}
+
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code": http.StatusBadRequest,
 			"msg":  err.Error(),
@@ -91,13 +100,79 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
+	msg := "登录成功"
+	if resp.TwoFactorRequired {
+		msg = "需要两步验证"
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
-		"msg":  "登录成功",
+		"msg":  msg,
 		"data": resp,
 	})
 }
 
+// fieldErrors 密码不符合策略时返回逐项的字段错误
+func fieldErrors(err error) interface{} {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		return gin.H{"errors": policyErr.Errors}
+	}
+	return nil
+}
+
+// respondBlocked 登录被退避或锁定时返回 429 及 Retry-After
+func respondBlocked(ctx *gin.Context, err error) bool {
+	var blocked *loginguard.BlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"code": http.StatusTooManyRequests,
+		"msg":  err.Error(),
+		"data": gin.H{
+			"retry_after": retryAfter,
+			"locked":      blocked.Locked,
+		},
+	})
+	return true
+}
+
+// clientInfo 提取发起请求的客户端信息，用于记录登录会话
+func clientInfo(ctx *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
+
+// currentUserID 获取当前登录用户ID，失败时写入错误响应
+func currentUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	userIDStr, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"code": http.StatusUnauthorized,
+			"msg":  "未认证",
+			"data": nil,
+		})
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "无效的用户ID",
+			"data": nil,
+		})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
 // GetUserInfo 获取用户信息
 // @Summary 获取用户信息
 // @Description 获取用户的详细信息
@@ -186,7 +261,7 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code": http.StatusBadRequest,
 			"msg":  err.Error(),
-			"data": nil,
+			"data": fieldErrors(err),
 		})
 		return
 	}
@@ -228,6 +303,47 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 	})
 }
 
+// UnlockUser 解除用户的登录锁定
+func (c *UserController) UnlockUser(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  "无效的用户ID",
+			"data": nil,
+		})
+		return
+	}
+
+	var req dto.UnlockUserRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code": http.StatusBadRequest,
+				"msg":  "无效的请求参数",
+				"data": nil,
+			})
+			return
+		}
+	}
+
+	if err := c.userService.UnlockUser(id, req.ClientIP); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code": http.StatusBadRequest,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code": http.StatusOK,
+		"msg":  "解锁成功",
+		"data": nil,
+	})
+}
+
 // AddUserRoles 为用户添加角色
 func (c *UserController) AddUserRoles(ctx *gin.Context) {
 	idStr := ctx.Param("id")
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest 创建 API Key 请求
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=64"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,uuid"` // 权限ID
+	ExpiresAt *time.Time `json:"expires_at"`                                // 为空表示永不过期
+}
+
+// APIKeyResponse API Key 响应
+type APIKeyResponse struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	Scopes     []PermissionInfo `json:"scopes"`
+	ExpiresAt  *time.Time       `json:"expires_at"`
+	LastUsedAt *time.Time       `json:"last_used_at"`
+	LastUsedIP string           `json:"last_used_ip"`
+	UsageCount int64            `json:"usage_count"`
+	Revoked    bool             `json:"revoked"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// CreateAPIKeyResponse 创建 API Key 响应，完整密钥仅在创建时返回一次
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
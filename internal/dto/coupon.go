@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// CouponRequest 创建或更新优惠券请求
+type CouponRequest struct {
+	Code              string     `json:"code" binding:"required,alphanum,min=3,max=32"` // 优惠码，不区分大小写
+	Name              string     `json:"name" binding:"required,max=64"`
+	Type              string     `json:"type" binding:"required,oneof=percent fixed"` // percent 按比例减免，fixed 固定金额减免
+	Value             int        `json:"value" binding:"required,min=1"`              // 减免百分比（1-100）或减免金额（单位：分）
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	TotalLimit        int        `json:"total_limit" binding:"min=0"`    // 总使用次数上限，0 表示不限
+	PerUserLimit      int        `json:"per_user_limit" binding:"min=0"` // 每个用户的使用次数上限，0 表示不限
+	FirstPurchaseOnly bool       `json:"first_purchase_only"`
+	IsActive          *bool      `json:"is_active"` // 为空时新建的优惠券启用，更新时保持不变
+	CardIDs           []uint     `json:"card_ids"`  // 适用的会员卡，为空时适用全部会员卡
+}
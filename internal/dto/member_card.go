@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// CreateMemberCardRequest 创建会员卡请求
+type CreateMemberCardRequest struct {
+	Name         string `json:"name" binding:"required,max=20"`
+	DurationDays int    `json:"duration_days" binding:"required,min=1"`
+	Price        int    `json:"price" binding:"required,min=1"` // 价格（单位：分）
+	Description  string `json:"description" binding:"required,max=255"`
+	SortOrder    int    `json:"sort_order"`
+	IsActive     *bool  `json:"is_active"` // 为空时上架
+}
+
+// UpdateMemberCardRequest 更新会员卡请求，价格通过调价接口修改
+type UpdateMemberCardRequest struct {
+	Name         string `json:"name" binding:"required,max=20"`
+	DurationDays int    `json:"duration_days" binding:"required,min=1"`
+	Description  string `json:"description" binding:"required,max=255"`
+	SortOrder    int    `json:"sort_order"`
+	IsActive     bool   `json:"is_active"`
+}
+
+// MemberCardPriceRequest 会员卡调价请求
+type MemberCardPriceRequest struct {
+	Price       int        `json:"price" binding:"required,min=1"` // 价格（单位：分）
+	EffectiveAt *time.Time `json:"effective_at"`                   // 生效时间，为空时立即生效
+}
+
+// SortMemberCardsRequest 会员卡排序请求
+type SortMemberCardsRequest struct {
+	CardIDs []uint `json:"card_ids" binding:"required,min=1"` // 按展示顺序排列的会员卡ID
+}
@@ -2,13 +2,29 @@ package dto
 
 // PaymentRequest 支付请求
 type PaymentRequest struct {
-	CardID uint `json:"card_id" binding:"required"`
+	CardID     uint   `json:"card_id" binding:"required"`
+	Provider   string `json:"provider"`    // 支付渠道，为空时使用默认渠道
+	CouponCode string `json:"coupon_code"` // 优惠码，可选
 }
 
 // PaymentResponse 支付响应
 type PaymentResponse struct {
-	OrderID string `json:"order_id"`
-	PayURL  string `json:"pay_url"`
+	OrderID  string `json:"order_id"`
+	Provider string `json:"provider"`
+	Amount   int    `json:"amount"`   // 实付金额（单位：分）
+	Discount int    `json:"discount"` // 优惠金额（单位：分）
+	PayURL   string `json:"pay_url"`
+}
+
+// OrderPaymentRequest 为已创建的订单发起支付
+type OrderPaymentRequest struct {
+	Provider string `json:"provider"` // 支付渠道，订单已选择渠道时必须一致，均为空时使用默认渠道
+}
+
+// ConfirmPaymentRequest 管理员确认线下收款
+type ConfirmPaymentRequest struct {
+	ReferenceNo string `json:"reference_no" binding:"required,max=64"` // 收款凭证号，如银行流水号
+	Remark      string `json:"remark" binding:"max=255"`
 }
 
 // PaymentNotifyRequest 支付通知请求
@@ -27,3 +43,9 @@ type PaymentNotifyRequest struct {
 	SellerID       string `form:"seller_id"`
 	AppID          string `form:"app_id"`
 }
+
+// CreateRefundRequest 创建退款请求
+type CreateRefundRequest struct {
+	Amount int    `json:"amount" binding:"min=0"`            // 退款金额（单位：分），为 0 时全额退款
+	Reason string `json:"reason" binding:"required,max=255"` // 退款原因
+}
@@ -0,0 +1,15 @@
+package dto
+
+// SubscriptionRequest 开通自动续费
+type SubscriptionRequest struct {
+	CardID   uint   `json:"card_id" binding:"required"`
+	Provider string `json:"provider"` // 支付渠道，为空时使用默认渠道，需支持签约代扣
+}
+
+// SubscriptionResponse 开通自动续费响应，用户打开签约页面完成签约后首期立即扣款
+type SubscriptionResponse struct {
+	SubscriptionID string `json:"subscription_id"`
+	Provider       string `json:"provider"`
+	Amount         int    `json:"amount"` // 每期扣款金额（单位：分），按扣款时的会员卡价格
+	SignURL        string `json:"sign_url"`
+}
@@ -1,9 +1,11 @@
 package dto
 
+import "time"
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=30"`
-	Password string `json:"password" binding:"required,min=6,max=30"`
+	Password string `json:"password" binding:"required,max=128"` // 其余要求由密码策略校验
 	Email    string `json:"email" binding:"required,email"`
 }
 
@@ -21,6 +23,27 @@ type UpdateUserRequest struct {
 	NewPassword string `json:"new_password"`
 }
 
+// VerifyEmailRequest 邮箱验证请求
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,max=128"`
+}
+
+// UnlockUserRequest 解除登录锁定请求
+type UnlockUserRequest struct {
+	ClientIP string `json:"client_ip" binding:"omitempty,ip"`
+}
+
 // UserRoleRequest 用户角色请求
 type UserRoleRequest struct {
 	RoleIDs []uint `json:"role_ids" binding:"required"`
@@ -41,8 +64,55 @@ type RoleInfo struct {
 }
 
 // TokenResponse 令牌响应
+// 需要两步验证时只返回挑战令牌，使用 /users/login/2fa 换取访问令牌
 type TokenResponse struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Token    string `json:"token"`
+	ID                string   `json:"id"`
+	Username          string   `json:"username"`
+	Token             string   `json:"token,omitempty"`
+	RefreshToken      string   `json:"refresh_token,omitempty"`
+	ExpiresIn         int64    `json:"expires_in,omitempty"` // 访问令牌有效期（秒）
+	TwoFactorRequired bool     `json:"two_factor_required,omitempty"`
+	SetupRequired     bool     `json:"setup_required,omitempty"` // 策略要求启用两步验证但尚未设置
+	ChallengeToken    string   `json:"challenge_token,omitempty"`
+	RecoveryCodes     []string `json:"recovery_codes,omitempty"` // 首次启用时返回，仅展示一次
+}
+
+// TwoFactorLoginRequest 两步验证登录请求，验证码与恢复码二选一
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorChallengeRequest 使用挑战令牌设置两步验证请求
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// TwoFactorSetupResponse 两步验证设置响应
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse 恢复码响应
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// SessionResponse 登录会话响应
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为发起请求的会话
 }
@@ -1,15 +1,23 @@
 package middleware
 
 import (
+	"errors"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/denylist"
+	"interviewGenius/internal/pkg/setting"
 	"interviewGenius/internal/pkg/util"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // JWT 认证中间件
+// 同时接受 "Authorization: ApiKey <key>" 形式的 API Key 认证
 func JWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var token string
@@ -20,6 +28,10 @@ func JWT() gin.HandlerFunc {
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token = parts[1]
 			}
+			if len(parts) == 2 && parts[0] == "ApiKey" {
+				authenticateAPIKey(c, strings.TrimSpace(parts[1]))
+				return
+			}
 		}
 
 		// 如果没有token
@@ -44,18 +56,194 @@ func JWT() gin.HandlerFunc {
 			return
 		}
 
+		// 检查令牌是否已被吊销
+		revoked, err := isTokenRevoked(claims)
+		if err != nil {
+			zap.L().Error("检查令牌黑名单失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": http.StatusInternalServerError,
+				"msg":  "检查认证令牌失败",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code": http.StatusUnauthorized,
+				"msg":  "认证令牌已失效",
+			})
+			c.Abort()
+			return
+		}
+
+		// 角色变更后权限版本递增，旧令牌需要刷新
+		if stale, err := isPermVersionStale(claims); err != nil || stale {
+			if err != nil {
+				zap.L().Warn("检查权限版本失败", zap.String("user_id", claims.UserID), zap.Error(err))
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code": http.StatusUnauthorized,
+				"msg":  "权限已变更，请刷新令牌",
+			})
+			c.Abort()
+			return
+		}
+
+		// 会话被注销后其访问令牌随即失效
+		if claims.SessionID != "" {
+			active, err := checkSession(claims.SessionID, c.ClientIP())
+			if err != nil {
+				zap.L().Error("检查登录会话失败", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code": http.StatusInternalServerError,
+					"msg":  "检查认证令牌失败",
+				})
+				c.Abort()
+				return
+			}
+			if !active {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code": http.StatusUnauthorized,
+					"msg":  "会话已失效，请重新登录",
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("roles", claims.Roles)
+		c.Set("permVersion", claims.PermVersion)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
 }
 
+// authenticateAPIKey 校验 API Key 并记录使用情况
+// 用户的 Key 以该用户身份访问；角色的 Key 不对应具体用户，上下文中没有 userID
+func authenticateAPIKey(c *gin.Context, raw string) {
+	key, err := model.GetAPIKeyByHash(util.HashToken(raw))
+	if err != nil {
+		if !errors.Is(err, model.ErrAPIKeyInvalid) {
+			zap.L().Error("查询 API Key 失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": http.StatusInternalServerError,
+				"msg":  "检查认证令牌失败",
+			})
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code": http.StatusUnauthorized,
+			"msg":  "无效的 API Key",
+		})
+		c.Abort()
+		return
+	}
+
+	if !key.Active() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code": http.StatusUnauthorized,
+			"msg":  "API Key 已过期或已吊销",
+		})
+		c.Abort()
+		return
+	}
+
+	if err := model.RecordAPIKeyUsage(key.ID, c.ClientIP()); err != nil {
+		zap.L().Warn("记录 API Key 使用情况失败", zap.String("api_key_id", key.ID.String()), zap.Error(err))
+	}
+
+	if key.UserID != nil {
+		c.Set("userID", key.UserID.String())
+	}
+	c.Set("apiKeyID", key.ID.String())
+	c.Set("apiKey", key)
+
+	c.Next()
+}
+
+// isTokenRevoked 检查令牌本身或其所属用户是否已被吊销
+func isTokenRevoked(claims *util.Claims) (bool, error) {
+	store := denylist.Default()
+
+	if claims.ID != "" {
+		revoked, err := store.IsRevoked(claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedAt, err := store.UserRevokedAt(claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return !revokedAt.IsZero() && (claims.IssuedAt == nil || !claims.IssuedAt.After(revokedAt)), nil
+}
+
+// checkSession 检查令牌所属会话是否有效，并按间隔更新最近活跃时间
+func checkSession(sid, clientIP string) (bool, error) {
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return false, nil
+	}
+
+	session, err := model.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if session.RevokedAt != nil {
+		return false, nil
+	}
+
+	if time.Since(session.LastSeenAt) >= sessionTouchInterval() {
+		if err := model.TouchSession(sessionID, clientIP); err != nil {
+			zap.L().Warn("更新会话活跃时间失败", zap.String("session_id", sid), zap.Error(err))
+		}
+	}
+	return true, nil
+}
+
+// sessionTouchInterval 更新会话活跃时间的最小间隔，未配置时默认60秒
+func sessionTouchInterval() time.Duration {
+	if setting.AppSetting.SessionTouchInterval <= 0 {
+		return time.Minute
+	}
+	return time.Duration(setting.AppSetting.SessionTouchInterval) * time.Second
+}
+
+// isPermVersionStale 检查令牌中的权限版本是否落后于用户当前版本
+func isPermVersionStale(claims *util.Claims) (bool, error) {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return true, err
+	}
+
+	version, err := model.GetUserPermVersion(userID)
+	if err != nil {
+		return true, err
+	}
+	return claims.PermVersion != version, nil
+}
+
 // CheckPermission 权限检查中间件
-func CheckPermission(method, path string) gin.HandlerFunc {
+// 根据当前用户的角色，以请求方法和 Gin 路由模板匹配权限表中的路径模式
+func CheckPermission() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		_, exists := c.Get("userID")
+		// API Key 按其权限范围和归属者的权限检查
+		if key, exists := c.Get("apiKey"); exists {
+			checkAPIKeyPermission(c, key.(*model.APIKey))
+			return
+		}
+
+		userIDStr, exists := c.Get("userID")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code": http.StatusUnauthorized,
@@ -65,9 +253,33 @@ func CheckPermission(method, path string) gin.HandlerFunc {
 			return
 		}
 
-		// 这里应该有权限检查逻辑
-		// 暂不实现详细的权限检查
-		hasPermission := true
+		userID, err := uuid.Parse(userIDStr.(string))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code": http.StatusUnauthorized,
+				"msg":  "无效的用户ID",
+			})
+			c.Abort()
+			return
+		}
+
+		// 未匹配到路由时交由 Gin 返回 404
+		path := c.FullPath()
+		if path == "" {
+			c.Next()
+			return
+		}
+
+		hasPermission, err := model.CheckUserPermission(userID, c.Request.Method, path)
+		if err != nil {
+			zap.L().Error("检查权限失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code": http.StatusInternalServerError,
+				"msg":  "检查权限失败",
+			})
+			c.Abort()
+			return
+		}
 
 		if !hasPermission {
 			c.JSON(http.StatusForbidden, gin.H{
@@ -82,11 +294,42 @@ func CheckPermission(method, path string) gin.HandlerFunc {
 	}
 }
 
+// checkAPIKeyPermission 检查 API Key 是否可以访问当前路由
+func checkAPIKeyPermission(c *gin.Context, key *model.APIKey) {
+	path := c.FullPath()
+	if path == "" {
+		c.Next()
+		return
+	}
+
+	hasPermission, err := model.CheckAPIKeyPermission(key, c.Request.Method, path)
+	if err != nil {
+		zap.L().Error("检查 API Key 权限失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": http.StatusInternalServerError,
+			"msg":  "检查权限失败",
+		})
+		c.Abort()
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code": http.StatusForbidden,
+			"msg":  "API Key 没有权限",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
 // AdminAuth 管理员权限验证中间件
 func AdminAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
+		roles, exists := c.Get("roles")
+		if !exists || !hasRole(roles.([]string), "admin") {
 			c.JSON(http.StatusForbidden, gin.H{
 				"code": http.StatusForbidden,
 				"msg":  "需要管理员权限",
@@ -98,3 +341,13 @@ func AdminAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// hasRole 判断角色列表是否包含指定角色
+func hasRole(roles []string, roleName string) bool {
+	for _, role := range roles {
+		if role == roleName {
+			return true
+		}
+	}
+	return false
+}
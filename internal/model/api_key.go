@@ -0,0 +1,158 @@
+package model
+
+import (
+	"errors"
+	"interviewGenius/internal/pkg/rbac"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAPIKeyInvalid  = errors.New("无效的 API Key")
+	ErrAPIKeyNotFound = errors.New("API Key 不存在")
+)
+
+// APIKey 供脚本和服务调用的 API Key
+// 归属于用户或角色，只能访问 Scopes 中列出的权限，且不超出归属者自身的权限
+type APIKey struct {
+	ID         uuid.UUID     `json:"id" gorm:"type:char(36);primaryKey"`
+	Name       string        `json:"name" gorm:"size:64;not null"`
+	Prefix     string        `json:"prefix" gorm:"size:16;not null;index"` // 明文前缀，用于识别
+	KeyHash    string        `json:"-" gorm:"size:64;not null;unique"`
+	UserID     *uuid.UUID    `json:"user_id" gorm:"type:char(36);index"` // 归属用户
+	RoleID     *uint         `json:"role_id" gorm:"index"`               // 归属角色
+	CreatedBy  uuid.UUID     `json:"created_by" gorm:"type:char(36);not null"`
+	ExpiresAt  *time.Time    `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	LastUsedIP string        `json:"last_used_ip" gorm:"size:45"`
+	UsageCount int64         `json:"usage_count" gorm:"default:0"`
+	RevokedAt  *time.Time    `json:"revoked_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Scopes     []*Permission `json:"scopes" gorm:"many2many:api_key_permission;"`
+}
+
+// BeforeCreate 创建前生成UUID
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// Active 判断 API Key 是否未吊销且未过期
+func (k *APIKey) Active() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
+
+// CreateAPIKey 创建 API Key 并关联 Scopes 中的权限
+func CreateAPIKey(key *APIKey) error {
+	return DB.Omit("Scopes.*").Create(key).Error
+}
+
+// GetAPIKeyByHash 根据摘要获取 API Key 及其权限范围
+func GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	var key APIKey
+	if err := DB.Preload("Scopes").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetUserAPIKeys 获取用户的 API Key
+func GetUserAPIKeys(userID uuid.UUID) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := DB.Preload("Scopes").Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// GetRoleAPIKeys 获取角色的 API Key
+func GetRoleAPIKeys(roleID uint) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := DB.Preload("Scopes").Where("role_id = ?", roleID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeUserAPIKey 吊销用户的指定 API Key
+func RevokeUserAPIKey(userID, id uuid.UUID) error {
+	return revokeAPIKeys(DB.Where("id = ? AND user_id = ?", id, userID), true)
+}
+
+// RevokeRoleAPIKey 吊销角色的指定 API Key
+func RevokeRoleAPIKey(roleID uint, id uuid.UUID) error {
+	return revokeAPIKeys(DB.Where("id = ? AND role_id = ?", id, roleID), true)
+}
+
+// RevokeUserAPIKeys 吊销用户的全部 API Key
+func RevokeUserAPIKeys(userID uuid.UUID) error {
+	return revokeAPIKeys(DB.Where("user_id = ?", userID), false)
+}
+
+// revokeAPIKeys 吊销满足条件的 API Key，single 为 true 时未找到返回 ErrAPIKeyNotFound
+func revokeAPIKeys(tx *gorm.DB, single bool) error {
+	result := tx.Model(&APIKey{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if single && result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RecordAPIKeyUsage 记录 API Key 的使用时间、来源IP和次数
+func RecordAPIKeyUsage(id uuid.UUID, ip string) error {
+	return DB.Model(&APIKey{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+		"usage_count":  gorm.Expr("usage_count + ?", 1),
+	}).Error
+}
+
+// CheckAPIKeyPermission 检查 API Key 是否可以访问指定路由
+// 请求需同时落在 Key 的权限范围和归属者当前的权限之内
+func CheckAPIKeyPermission(key *APIKey, method, path string) (bool, error) {
+	scopes := make([]rbac.Policy, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, rbac.Policy{
+			Method:      scope.Method,
+			PathPattern: scope.PathPattern,
+		})
+	}
+	if !rbac.Match(scopes, method, path) {
+		return false, nil
+	}
+
+	switch {
+	case key.UserID != nil:
+		return CheckUserPermission(*key.UserID, method, path)
+	case key.RoleID != nil:
+		return CheckRolePermission(*key.RoleID, method, path)
+	}
+	return false, nil
+}
+
+// CheckRolePermission 检查角色是否有特定权限，超级角色直接放行
+func CheckRolePermission(roleID uint, method, path string) (bool, error) {
+	var role Role
+	if err := DB.First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if role.IsSuper {
+		return true, nil
+	}
+
+	roleIDs := []uint{roleID}
+	return rbac.Enforce(roleIDs, method, path, func() ([]rbac.Policy, error) {
+		return GetRolesPolicies(roleIDs)
+	})
+}
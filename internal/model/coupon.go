@@ -0,0 +1,289 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CouponType 优惠券类型
+type CouponType string
+
+const (
+	CouponTypePercent CouponType = "percent" // 按比例减免，Value 为减免的百分比
+	CouponTypeFixed   CouponType = "fixed"   // 固定金额减免，Value 为减免金额（单位：分）
+)
+
+// CouponRedemptionStatus 优惠券使用状态
+type CouponRedemptionStatus string
+
+const (
+	CouponRedemptionReserved CouponRedemptionStatus = "reserved" // 订单未支付，占用一次使用次数
+	CouponRedemptionUsed     CouponRedemptionStatus = "used"     // 订单已支付
+	CouponRedemptionReleased CouponRedemptionStatus = "released" // 订单取消或关闭，使用次数已归还
+)
+
+var (
+	ErrCouponNotFound = errors.New("优惠券不存在")
+	// ErrCouponUnavailable 下单时优惠码不可用，具体原因包装在错误信息中
+	ErrCouponUnavailable = errors.New("优惠码不可用")
+)
+
+// Coupon 优惠券，用户下单时填写优惠码
+type Coupon struct {
+	Model
+	Code              string        `json:"code" gorm:"size:32;not null;uniqueIndex"` // 优惠码，统一保存为大写
+	Name              string        `json:"name" gorm:"size:64;not null"`
+	Type              CouponType    `json:"type" gorm:"size:20;not null"`
+	Value             int           `json:"value" gorm:"not null"`
+	StartsAt          *time.Time    `json:"starts_at"`                             // 生效时间，为空表示立即生效
+	EndsAt            *time.Time    `json:"ends_at"`                               // 失效时间，为空表示长期有效
+	TotalLimit        int           `json:"total_limit" gorm:"not null;default:0"` // 总使用次数上限，0 表示不限
+	PerUserLimit      int           `json:"per_user_limit" gorm:"not null;default:0"`
+	UsedCount         int           `json:"used_count" gorm:"not null;default:0"` // 已占用次数，包括未支付的订单
+	FirstPurchaseOnly bool          `json:"first_purchase_only"`                  // 仅限没有支付过订单的用户使用
+	IsActive          bool          `json:"is_active" gorm:"not null"`
+	Cards             []*MemberCard `json:"cards" gorm:"many2many:coupon_card;"` // 适用的会员卡，为空时适用全部会员卡
+}
+
+// CouponRedemption 优惠券使用记录，每个订单最多使用一张优惠券
+type CouponRedemption struct {
+	ID        uint                   `json:"id" gorm:"primarykey"`
+	CouponID  uint                   `json:"coupon_id" gorm:"not null;index"`
+	UserID    uuid.UUID              `json:"user_id" gorm:"type:char(36);not null;index"`
+	OrderID   uuid.UUID              `json:"order_id" gorm:"type:char(36);not null;uniqueIndex"`
+	Discount  int                    `json:"discount" gorm:"not null"` // 减免金额（单位：分）
+	Status    CouponRedemptionStatus `json:"status" gorm:"size:20;not null"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// NormalizeCouponCode 优惠码不区分大小写
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// DiscountFor 计算订单金额可减免的金额
+// 实付金额至少保留 1 分，支付渠道不接受 0 元订单
+func (c *Coupon) DiscountFor(amount int) int {
+	discount := c.Value
+	if c.Type == CouponTypePercent {
+		discount = amount * c.Value / 100
+	}
+	if discount > amount-1 {
+		discount = amount - 1
+	}
+	if discount < 0 {
+		discount = 0
+	}
+	return discount
+}
+
+// appliesTo 判断优惠券是否适用于会员卡
+func (c *Coupon) appliesTo(cardID uint) bool {
+	if len(c.Cards) == 0 {
+		return true
+	}
+	for _, card := range c.Cards {
+		if card.ID == cardID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCoupon 创建优惠券并关联适用的会员卡
+func CreateCoupon(coupon *Coupon) error {
+	coupon.Code = NormalizeCouponCode(coupon.Code)
+	return DB.Omit("Cards.*").Create(coupon).Error
+}
+
+// GetCouponByID 根据ID获取未删除的优惠券
+func GetCouponByID(id uint) (*Coupon, error) {
+	var coupon Coupon
+	if err := DB.Preload("Cards").Where("deleted_at IS NULL").First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// GetCouponList 分页获取未删除的优惠券
+func GetCouponList(page, limit int) ([]*Coupon, int64, error) {
+	var (
+		coupons []*Coupon
+		total   int64
+	)
+	if err := DB.Model(&Coupon{}).Where("deleted_at IS NULL").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := DB.Preload("Cards").Where("deleted_at IS NULL").Order("id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&coupons).Error; err != nil {
+		return nil, 0, err
+	}
+	return coupons, total, nil
+}
+
+// CouponCodeExists 判断优惠码是否已被其他优惠券使用，已删除的优惠券同样占用优惠码
+func CouponCodeExists(code string, excludeID uint) (bool, error) {
+	var count int64
+	if err := DB.Model(&Coupon{}).Where("code = ? AND id <> ?", NormalizeCouponCode(code), excludeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpdateCoupon 更新优惠券及适用的会员卡，已占用次数不受影响
+func UpdateCoupon(coupon *Coupon) error {
+	coupon.Code = NormalizeCouponCode(coupon.Code)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Coupon{}).Where("id = ?", coupon.ID).Updates(map[string]interface{}{
+			"code":                coupon.Code,
+			"name":                coupon.Name,
+			"type":                coupon.Type,
+			"value":               coupon.Value,
+			"starts_at":           coupon.StartsAt,
+			"ends_at":             coupon.EndsAt,
+			"total_limit":         coupon.TotalLimit,
+			"per_user_limit":      coupon.PerUserLimit,
+			"first_purchase_only": coupon.FirstPurchaseOnly,
+			"is_active":           coupon.IsActive,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(coupon).Association("Cards").Replace(coupon.Cards)
+	})
+}
+
+// DeleteCoupon 删除优惠券，保留记录以便查询历史订单的使用情况
+func DeleteCoupon(id uint) error {
+	result := DB.Model(&Coupon{}).Where("id = ? AND deleted_at IS NULL", id).Updates(map[string]interface{}{
+		"deleted_at": time.Now(),
+		"is_active":  false,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCouponNotFound
+	}
+	return nil
+}
+
+// redeemCoupon 在创建订单的事务中校验优惠码，减免订单金额并占用一次使用次数
+// 优惠券行加锁，保证并发下单时不会超过使用次数上限
+func redeemCoupon(tx *gorm.DB, order *Order, code string) error {
+	var coupon Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Cards").
+		Where("code = ? AND deleted_at IS NULL", NormalizeCouponCode(code)).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: 优惠码不存在", ErrCouponUnavailable)
+		}
+		return err
+	}
+
+	now := time.Now()
+	switch {
+	case !coupon.IsActive:
+		return fmt.Errorf("%w: 优惠码已停用", ErrCouponUnavailable)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return fmt.Errorf("%w: 优惠码尚未生效", ErrCouponUnavailable)
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return fmt.Errorf("%w: 优惠码已过期", ErrCouponUnavailable)
+	case coupon.TotalLimit > 0 && coupon.UsedCount >= coupon.TotalLimit:
+		return fmt.Errorf("%w: 优惠码已被用完", ErrCouponUnavailable)
+	case !coupon.appliesTo(order.CardID):
+		return fmt.Errorf("%w: 优惠码不适用于该会员卡", ErrCouponUnavailable)
+	}
+
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := tx.Model(&CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ? AND status IN ?", coupon.ID, order.UserID,
+				[]CouponRedemptionStatus{CouponRedemptionReserved, CouponRedemptionUsed}).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if int(used) >= coupon.PerUserLimit {
+			return fmt.Errorf("%w: 已达到该优惠码的使用次数上限", ErrCouponUnavailable)
+		}
+	}
+
+	if coupon.FirstPurchaseOnly {
+		// 锁定用户行，同一用户并发使用不同的首购优惠码时串行校验
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			First(&user, "id = ?", order.UserID).Error; err != nil {
+			return err
+		}
+
+		var paid int64
+		if err := tx.Model(&Order{}).Where("user_id = ? AND payment_time IS NOT NULL", order.UserID).
+			Count(&paid).Error; err != nil {
+			return err
+		}
+
+		// 未支付订单占用的首购优惠也算作首次购买，避免同时创建多个首购订单后分别支付
+		var pending int64
+		if err := tx.Model(&CouponRedemption{}).
+			Where("user_id = ? AND status IN ? AND coupon_id IN (?)", order.UserID,
+				[]CouponRedemptionStatus{CouponRedemptionReserved, CouponRedemptionUsed},
+				tx.Model(&Coupon{}).Select("id").Where("first_purchase_only = ?", true)).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if paid > 0 || pending > 0 {
+			return fmt.Errorf("%w: 优惠码仅限首次购买使用", ErrCouponUnavailable)
+		}
+	}
+
+	discount := coupon.DiscountFor(order.Amount)
+	order.Amount -= discount
+	order.Discount = discount
+	order.CouponID = &coupon.ID
+	if err := tx.Create(order).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&Coupon{}).Where("id = ?", coupon.ID).
+		Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Create(&CouponRedemption{
+		CouponID: coupon.ID,
+		UserID:   order.UserID,
+		OrderID:  order.ID,
+		Discount: discount,
+		Status:   CouponRedemptionReserved,
+	}).Error
+}
+
+// useCoupon 订单支付后将占用的优惠券记为已使用
+func useCoupon(tx *gorm.DB, orderID uuid.UUID) error {
+	return tx.Model(&CouponRedemption{}).
+		Where("order_id = ? AND status = ?", orderID, CouponRedemptionReserved).
+		Update("status", CouponRedemptionUsed).Error
+}
+
+// releaseCoupon 订单取消或关闭后归还占用的使用次数
+func releaseCoupon(tx *gorm.DB, order *Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+	result := tx.Model(&CouponRedemption{}).
+		Where("order_id = ? AND status = ?", order.ID, CouponRedemptionReserved).
+		Update("status", CouponRedemptionReleased)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&Coupon{}).Where("id = ? AND used_count > 0", *order.CouponID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
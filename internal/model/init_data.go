@@ -4,46 +4,149 @@ import (
 	"go.uber.org/zap"
 )
 
-// API路由权限定义
+// API路由权限定义，与 router.InitRouter 中注册的路由保持一致
 var apiRoutes = []Permission{
 	// 用户管理
-	{Method: "POST", PathPattern: "/api/v1/user", Description: "创建用户"},
-	{Method: "PUT", PathPattern: "/api/v1/user/:id", Description: "更新用户"},
-	{Method: "DELETE", PathPattern: "/api/v1/user/:id", Description: "删除用户"},
-	{Method: "GET", PathPattern: "/api/v1/user/:id", Description: "获取用户详情"},
 	{Method: "GET", PathPattern: "/api/v1/users", Description: "获取用户列表"},
+	{Method: "GET", PathPattern: "/api/v1/users/:id", Description: "获取用户详情"},
+	{Method: "PUT", PathPattern: "/api/v1/users/:id", Description: "更新用户"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/:id", Description: "删除用户"},
+	{Method: "POST", PathPattern: "/api/v1/users/:id/unlock", Description: "解除用户登录锁定"},
+	{Method: "POST", PathPattern: "/api/v1/users/:id/roles", Description: "分配用户角色"},
+	{Method: "GET", PathPattern: "/api/v1/users/:id/roles", Description: "获取用户角色"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/:id/roles/:roleId", Description: "移除用户角色"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/email/verification", Description: "发送邮箱验证邮件"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/identities", Description: "获取已绑定的第三方账号"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/identities/:provider", Description: "绑定第三方账号"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/identities/:provider", Description: "解除第三方账号绑定"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/enroll", Description: "设置两步验证"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/confirm", Description: "确认两步验证"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/recovery-codes", Description: "重新生成恢复码"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/2fa", Description: "关闭两步验证"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/sessions", Description: "获取登录设备列表"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/sessions", Description: "退出全部设备"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/sessions/:id", Description: "注销登录设备"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/api-keys", Description: "获取 API Key 列表"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/api-keys", Description: "创建 API Key"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/api-keys/:id", Description: "吊销 API Key"},
 
 	// 角色管理
-	{Method: "POST", PathPattern: "/api/v1/role", Description: "创建角色"},
-	{Method: "PUT", PathPattern: "/api/v1/role/:id", Description: "更新角色"},
-	{Method: "DELETE", PathPattern: "/api/v1/role/:id", Description: "删除角色"},
-	{Method: "GET", PathPattern: "/api/v1/role/:id", Description: "获取角色详情"},
+	{Method: "POST", PathPattern: "/api/v1/roles", Description: "创建角色"},
 	{Method: "GET", PathPattern: "/api/v1/roles", Description: "获取角色列表"},
-	{Method: "POST", PathPattern: "/api/v1/role/:id/permissions", Description: "分配角色权限"},
+	{Method: "GET", PathPattern: "/api/v1/roles/:id", Description: "获取角色详情"},
+	{Method: "PUT", PathPattern: "/api/v1/roles/:id", Description: "更新角色"},
+	{Method: "DELETE", PathPattern: "/api/v1/roles/:id", Description: "删除角色"},
+	{Method: "POST", PathPattern: "/api/v1/roles/:id/permissions", Description: "分配角色权限"},
+	{Method: "GET", PathPattern: "/api/v1/roles/:id/permissions", Description: "获取角色权限"},
+	{Method: "DELETE", PathPattern: "/api/v1/roles/:id/permissions/:permissionId", Description: "移除角色权限"},
+	{Method: "GET", PathPattern: "/api/v1/roles/:id/api-keys", Description: "获取角色 API Key 列表"},
+	{Method: "POST", PathPattern: "/api/v1/roles/:id/api-keys", Description: "创建角色 API Key"},
+	{Method: "DELETE", PathPattern: "/api/v1/roles/:id/api-keys/:keyId", Description: "吊销角色 API Key"},
 
 	// 权限管理
+	{Method: "POST", PathPattern: "/api/v1/permissions", Description: "创建权限"},
 	{Method: "GET", PathPattern: "/api/v1/permissions", Description: "获取权限列表"},
+	{Method: "GET", PathPattern: "/api/v1/permissions/report", Description: "获取权限与路由差异报告"},
+	{Method: "GET", PathPattern: "/api/v1/permissions/:id", Description: "获取权限详情"},
+	{Method: "PUT", PathPattern: "/api/v1/permissions/:id", Description: "更新权限"},
+	{Method: "DELETE", PathPattern: "/api/v1/permissions/:id", Description: "删除权限"},
 
 	// 权限验证
-	{Method: "POST", PathPattern: "/api/v1/auth/login", Description: "用户登录"},
-	{Method: "POST", PathPattern: "/api/v1/auth/logout", Description: "用户登出"},
-	{Method: "GET", PathPattern: "/api/v1/auth/info", Description: "获取当前用户信息"},
+	{Method: "POST", PathPattern: "/api/v1/auth/verify", Description: "验证权限"},
+	{Method: "POST", PathPattern: "/api/v1/auth/refresh", Description: "刷新令牌"},
+	{Method: "POST", PathPattern: "/api/v1/auth/logout", Description: "注销登录"},
+
+	// 会员
+	{Method: "GET", PathPattern: "/api/v1/member/info", Description: "获取会员信息"},
+	{Method: "POST", PathPattern: "/api/v1/member/order", Description: "创建会员卡订单"},
+	{Method: "POST", PathPattern: "/api/v1/member/order/:id/pay", Description: "发起订单支付"},
+	{Method: "GET", PathPattern: "/api/v1/member/orders", Description: "获取订单列表"},
+	{Method: "GET", PathPattern: "/api/v1/member/orders/:id/events", Description: "获取订单状态记录"},
+	{Method: "GET", PathPattern: "/api/v1/member/check", Description: "检查服务使用权限"},
+	{Method: "POST", PathPattern: "/api/v1/member/subscriptions", Description: "开通自动续费"},
+	{Method: "GET", PathPattern: "/api/v1/member/subscriptions", Description: "获取自动续费列表"},
+	{Method: "POST", PathPattern: "/api/v1/member/subscriptions/:id/cancel", Description: "取消自动续费"},
+	{Method: "POST", PathPattern: "/api/v1/payment/create", Description: "创建支付"},
+
+	// 会员卡管理
+	{Method: "GET", PathPattern: "/api/v1/member-cards", Description: "获取会员卡管理列表"},
+	{Method: "POST", PathPattern: "/api/v1/member-cards", Description: "创建会员卡"},
+	{Method: "PUT", PathPattern: "/api/v1/member-cards/sort", Description: "调整会员卡顺序"},
+	{Method: "PUT", PathPattern: "/api/v1/member-cards/:id", Description: "更新会员卡"},
+	{Method: "DELETE", PathPattern: "/api/v1/member-cards/:id", Description: "归档会员卡"},
+	{Method: "GET", PathPattern: "/api/v1/member-cards/:id/prices", Description: "获取会员卡调价记录"},
+	{Method: "POST", PathPattern: "/api/v1/member-cards/:id/prices", Description: "调整会员卡价格"},
+	{Method: "DELETE", PathPattern: "/api/v1/member-cards/:id/prices/:priceId", Description: "取消会员卡调价"},
+
+	// 订单管理
+	{Method: "POST", PathPattern: "/api/v1/orders/:id/confirm", Description: "确认线下收款"},
+	{Method: "GET", PathPattern: "/api/v1/orders/:id/refunds", Description: "获取订单退款记录"},
+	{Method: "POST", PathPattern: "/api/v1/orders/:id/refunds", Description: "订单退款"},
+	{Method: "POST", PathPattern: "/api/v1/orders/:id/refunds/:refundId/sync", Description: "同步退款结果"},
+
+	// 优惠券管理
+	{Method: "POST", PathPattern: "/api/v1/coupons", Description: "创建优惠券"},
+	{Method: "GET", PathPattern: "/api/v1/coupons", Description: "获取优惠券列表"},
+	{Method: "GET", PathPattern: "/api/v1/coupons/:id", Description: "获取优惠券详情"},
+	{Method: "PUT", PathPattern: "/api/v1/coupons/:id", Description: "更新优惠券"},
+	{Method: "DELETE", PathPattern: "/api/v1/coupons/:id", Description: "删除优惠券"},
+
+	// 账单核对
+	{Method: "GET", PathPattern: "/api/v1/reconciliations", Description: "获取对账报告列表"},
+	{Method: "POST", PathPattern: "/api/v1/reconciliations", Description: "核对渠道账单"},
+	{Method: "GET", PathPattern: "/api/v1/reconciliations/:id", Description: "获取对账报告详情"},
 }
 
+// DefaultRoleName 新注册用户默认分配的角色
+const DefaultRoleName = "regular_user"
+
 // 普通用户可访问的路由
-var regularUserRoutes = []string{
-	"/api/v1/auth/login",
-	"/api/v1/auth/logout",
-	"/api/v1/auth/info",
+var regularUserRoutes = []Permission{
+	{Method: "POST", PathPattern: "/api/v1/users/me/email/verification"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/identities"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/identities/:provider"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/identities/:provider"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/enroll"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/confirm"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/2fa/recovery-codes"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/2fa"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/sessions"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/sessions"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/sessions/:id"},
+	{Method: "GET", PathPattern: "/api/v1/users/me/api-keys"},
+	{Method: "POST", PathPattern: "/api/v1/users/me/api-keys"},
+	{Method: "DELETE", PathPattern: "/api/v1/users/me/api-keys/:id"},
+	{Method: "POST", PathPattern: "/api/v1/auth/verify"},
+	{Method: "POST", PathPattern: "/api/v1/auth/logout"},
+	{Method: "GET", PathPattern: "/api/v1/member/info"},
+	{Method: "POST", PathPattern: "/api/v1/member/order"},
+	{Method: "POST", PathPattern: "/api/v1/member/order/:id/pay"},
+	{Method: "GET", PathPattern: "/api/v1/member/orders"},
+	{Method: "GET", PathPattern: "/api/v1/member/orders/:id/events"},
+	{Method: "GET", PathPattern: "/api/v1/member/check"},
+	{Method: "POST", PathPattern: "/api/v1/member/subscriptions"},
+	{Method: "GET", PathPattern: "/api/v1/member/subscriptions"},
+	{Method: "POST", PathPattern: "/api/v1/member/subscriptions/:id/cancel"},
+	{Method: "POST", PathPattern: "/api/v1/payment/create"},
+}
+
+// isRegularUserRoute 判断路由是否默认授予普通用户
+func isRegularUserRoute(method, path string) bool {
+	for _, route := range regularUserRoutes {
+		if route.Method == method && route.PathPattern == path {
+			return true
+		}
+	}
+	return false
 }
 
 // 会员卡类型定义
 var memberCardTypes = []MemberCard{
-	{Name: "日卡", DurationDays: 1, Price: 998, Description: "24小时内无限次使用服务"},
-	{Name: "周卡", DurationDays: 7, Price: 2998, Description: "7天内无限次使用服务"},
-	{Name: "双周卡", DurationDays: 14, Price: 4998, Description: "14天内无限次使用服务"},
-	{Name: "月卡", DurationDays: 30, Price: 8998, Description: "30天内无限次使用服务"},
-	{Name: "双月卡", DurationDays: 60, Price: 15998, Description: "60天内无限次使用服务"},
+	{Name: "日卡", DurationDays: 1, Price: 998, Description: "24小时内无限次使用服务", IsActive: true, SortOrder: 1},
+	{Name: "周卡", DurationDays: 7, Price: 2998, Description: "7天内无限次使用服务", IsActive: true, SortOrder: 2},
+	{Name: "双周卡", DurationDays: 14, Price: 4998, Description: "14天内无限次使用服务", IsActive: true, SortOrder: 3},
+	{Name: "月卡", DurationDays: 30, Price: 8998, Description: "30天内无限次使用服务", IsActive: true, SortOrder: 4},
+	{Name: "双月卡", DurationDays: 60, Price: 15998, Description: "60天内无限次使用服务", IsActive: true, SortOrder: 5},
 }
 
 // InitData 初始化系统数据
@@ -119,7 +222,7 @@ func InitData() error {
 
 		// 创建普通用户角色
 		regularRole := Role{
-			RoleName:    "regular_user",
+			RoleName:    DefaultRoleName,
 			Name:        "普通用户",
 			Description: "普通用户，拥有基本权限",
 		}
@@ -130,11 +233,11 @@ func InitData() error {
 		}
 
 		// 为普通用户角色分配基本权限
-		for _, routePath := range regularUserRoutes {
+		for _, route := range regularUserRoutes {
 			var permission Permission
-			if err := tx.Where("path_pattern = ?", routePath).First(&permission).Error; err != nil {
+			if err := tx.Where("method = ? AND path_pattern = ?", route.Method, route.PathPattern).First(&permission).Error; err != nil {
 				tx.Rollback()
-				zap.L().Error("查询基本权限失败", zap.String("path", routePath), zap.Error(err))
+				zap.L().Error("查询基本权限失败", zap.String("path", route.PathPattern), zap.Error(err))
 				return err
 			}
 
@@ -184,5 +287,11 @@ func InitData() error {
 		zap.L().Info("会员卡数据初始化成功", zap.Int("card_types_count", len(memberCardTypes)))
 	}
 
+	// 早期创建的会员卡没有调价记录
+	if err := backfillMemberCardPrices(); err != nil {
+		zap.L().Error("补充会员卡价格记录失败", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// JobLock 定时任务的租约锁，多实例部署时同一任务同时只在一个实例上运行
+type JobLock struct {
+	Name        string    `json:"name" gorm:"size:64;primaryKey"`
+	Owner       string    `json:"owner" gorm:"size:128;not null"`
+	LockedUntil time.Time `json:"locked_until" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AcquireJobLock 获取或续期任务锁，锁已过期或本来就由 owner 持有时成功
+func AcquireJobLock(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	result := DB.Model(&JobLock{}).
+		Where("name = ? AND (locked_until < ? OR owner = ?)", name, now, owner).
+		Updates(map[string]interface{}{
+			"owner":        owner,
+			"locked_until": now.Add(ttl),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 首次运行时创建锁记录，并发创建时只有一个实例成功
+	result = DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&JobLock{
+		Name:        name,
+		Owner:       owner,
+		LockedUntil: now.Add(ttl),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseJobLock 释放 owner 持有的任务锁
+func ReleaseJobLock(name, owner string) error {
+	return DB.Model(&JobLock{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Update("locked_until", time.Now()).Error
+}
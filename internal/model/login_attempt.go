@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"interviewGenius/internal/pkg/loginguard"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttempt 登录失败记录，键为 user:<用户名> 或 ip:<客户端IP>
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"size:191;primaryKey"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	BlockedUntil  *time.Time `json:"blocked_until"`
+}
+
+// LoginAttemptStore 基于数据库的登录失败记录存储，适用于多实例部署
+type LoginAttemptStore struct{}
+
+// NewLoginAttemptStore 创建数据库登录失败记录存储
+func NewLoginAttemptStore() *LoginAttemptStore {
+	return &LoginAttemptStore{}
+}
+
+// Get 获取记录
+func (s *LoginAttemptStore) Get(key string) (loginguard.Attempt, error) {
+	var attempt LoginAttempt
+	if err := DB.Where("`key` = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return loginguard.Attempt{}, nil
+		}
+		return loginguard.Attempt{}, err
+	}
+	return attempt.toAttempt(), nil
+}
+
+// Incr 在行锁内累加失败次数
+func (s *LoginAttemptStore) Incr(key string, now time.Time, window time.Duration) (loginguard.Attempt, error) {
+	var result loginguard.Attempt
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&LoginAttempt{Key: key}).Error; err != nil {
+			return err
+		}
+
+		var attempt LoginAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+
+		expired := attempt.LastFailureAt == nil || (window > 0 && now.Sub(*attempt.LastFailureAt) > window)
+		blocked := attempt.BlockedUntil != nil && attempt.BlockedUntil.After(now)
+		if expired && !blocked {
+			attempt.Failures = 0
+			attempt.BlockedUntil = nil
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = &now
+
+		if err := tx.Model(&LoginAttempt{}).Where("`key` = ?", key).Updates(map[string]interface{}{
+			"failures":        attempt.Failures,
+			"last_failure_at": attempt.LastFailureAt,
+			"blocked_until":   attempt.BlockedUntil,
+		}).Error; err != nil {
+			return err
+		}
+
+		result = attempt.toAttempt()
+		return nil
+	})
+
+	return result, err
+}
+
+// Block 设置解除限制的时间
+func (s *LoginAttemptStore) Block(key string, until time.Time) error {
+	return DB.Model(&LoginAttempt{}).Where("`key` = ?", key).Update("blocked_until", until).Error
+}
+
+// Reset 清除记录
+func (s *LoginAttemptStore) Reset(key string) error {
+	return DB.Where("`key` = ?", key).Delete(&LoginAttempt{}).Error
+}
+
+// toAttempt 转换为 loginguard.Attempt
+func (a *LoginAttempt) toAttempt() loginguard.Attempt {
+	attempt := loginguard.Attempt{Failures: a.Failures}
+	if a.LastFailureAt != nil {
+		attempt.LastFailureAt = *a.LastFailureAt
+	}
+	if a.BlockedUntil != nil {
+		attempt.BlockedUntil = *a.BlockedUntil
+	}
+	return attempt
+}
@@ -1,28 +1,152 @@
 package model
 
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrMemberCardNotFound = errors.New("会员卡不存在")
+
 // MemberCard 会员卡商品模型
 type MemberCard struct {
 	Model
 	Name         string `json:"name" gorm:"size:20;not null"`         // 日卡/周卡等
 	DurationDays int    `json:"duration_days" gorm:"not null"`        // 有效天数（日卡=1，周卡=7...）
-	Price        int    `json:"price" gorm:"not null"`                // 价格（单位：分）
+	Price        int    `json:"price" gorm:"not null"`                // 当前价格（单位：分），调价记录见 MemberCardPrice
 	Description  string `json:"description" gorm:"size:255;not null"` // 会员卡描述
+	IsActive     bool   `json:"is_active" gorm:"not null;default:true"`
+	SortOrder    int    `json:"sort_order" gorm:"not null;default:0"` // 展示顺序，越小越靠前
 }
 
-// GetAllMemberCards 获取所有会员卡类型
+// GetAllMemberCards 获取在售的会员卡，按展示顺序排列
 func GetAllMemberCards() ([]*MemberCard, error) {
+	if err := applyDuePrices(); err != nil {
+		return nil, err
+	}
+	var cards []*MemberCard
+	if err := DB.Where("is_active = ? AND deleted_at IS NULL", true).Order("sort_order, id").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// GetMemberCardList 获取未删除的全部会员卡，包括已下架的会员卡
+func GetMemberCardList() ([]*MemberCard, error) {
+	if err := applyDuePrices(); err != nil {
+		return nil, err
+	}
+	var cards []*MemberCard
+	if err := DB.Where("deleted_at IS NULL").Order("sort_order, id").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// GetMemberCardsByIDs 批量获取未删除的会员卡
+func GetMemberCardsByIDs(ids []uint) ([]*MemberCard, error) {
 	var cards []*MemberCard
-	if err := DB.Find(&cards).Error; err != nil {
+	if len(ids) == 0 {
+		return cards, nil
+	}
+	if err := DB.Where("id IN ? AND deleted_at IS NULL", ids).Find(&cards).Error; err != nil {
 		return nil, err
 	}
 	return cards, nil
 }
 
-// GetMemberCardByID 根据ID获取会员卡
+// GetMemberCardByID 根据ID获取未删除的会员卡
 func GetMemberCardByID(id uint) (*MemberCard, error) {
+	if err := applyDuePrices(); err != nil {
+		return nil, err
+	}
 	var card MemberCard
-	if err := DB.First(&card, id).Error; err != nil {
+	if err := DB.Where("deleted_at IS NULL").First(&card, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberCardNotFound
+		}
 		return nil, err
 	}
 	return &card, nil
 }
+
+// GetAvailableMemberCard 获取可以下单的会员卡，已下架或已删除时返回 ErrMemberCardNotFound
+func GetAvailableMemberCard(id uint) (*MemberCard, error) {
+	card, err := GetMemberCardByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !card.IsActive {
+		return nil, ErrMemberCardNotFound
+	}
+	return card, nil
+}
+
+// CreateMemberCard 创建会员卡并记录初始价格
+func CreateMemberCard(card *MemberCard, price *MemberCardPrice) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		isActive := card.IsActive
+		if err := tx.Create(card).Error; err != nil {
+			return err
+		}
+		// is_active 有默认值，创建时不会写入 false
+		if !isActive {
+			if err := tx.Model(card).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		price.CardID = card.ID
+		price.Price = card.Price
+		price.EffectiveAt = now
+		price.AppliedAt = &now
+		return tx.Create(price).Error
+	})
+}
+
+// UpdateMemberCard 更新会员卡信息，价格通过 ScheduleMemberCardPrice 修改
+func UpdateMemberCard(card *MemberCard) error {
+	result := DB.Model(&MemberCard{}).Where("id = ? AND deleted_at IS NULL", card.ID).Updates(map[string]interface{}{
+		"name":          card.Name,
+		"duration_days": card.DurationDays,
+		"description":   card.Description,
+		"is_active":     card.IsActive,
+		"sort_order":    card.SortOrder,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberCardNotFound
+	}
+	return nil
+}
+
+// SortMemberCards 按 ids 的顺序设置会员卡的展示顺序
+func SortMemberCards(ids []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			if err := tx.Model(&MemberCard{}).Where("id = ?", id).Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMemberCard 归档会员卡，已有订单仍可查到对应的会员卡
+func DeleteMemberCard(id uint) error {
+	result := DB.Model(&MemberCard{}).Where("id = ? AND deleted_at IS NULL", id).Updates(map[string]interface{}{
+		"deleted_at": time.Now(),
+		"is_active":  false,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberCardNotFound
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrMemberCardPriceNotFound = errors.New("待生效的调价不存在")
+
+// MemberCardPrice 会员卡调价记录，用于解释历史订单的金额和定时调价
+type MemberCardPrice struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	CardID      uint       `json:"card_id" gorm:"not null;index"`
+	Price       int        `json:"price" gorm:"not null"`                                 // 价格（单位：分）
+	EffectiveAt time.Time  `json:"effective_at" gorm:"not null;index:idx_card_price_due"` // 生效时间
+	AppliedAt   *time.Time `json:"applied_at" gorm:"index:idx_card_price_due"`            // 实际生效时间，为空表示尚未生效
+	OperatorID  uuid.UUID  `json:"operator_id" gorm:"type:char(36)"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ScheduleMemberCardPrice 调整会员卡价格，effectiveAt 不晚于当前时间时立即生效
+func ScheduleMemberCardPrice(price *MemberCardPrice) error {
+	if _, err := GetMemberCardByID(price.CardID); err != nil {
+		return err
+	}
+	if price.EffectiveAt.After(time.Now()) {
+		return DB.Create(price).Error
+	}
+
+	now := time.Now()
+	price.EffectiveAt = now
+	price.AppliedAt = &now
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(price).Error; err != nil {
+			return err
+		}
+		return tx.Model(&MemberCard{}).Where("id = ?", price.CardID).Update("price", price.Price).Error
+	})
+}
+
+// GetMemberCardPrices 获取会员卡的调价记录，包括尚未生效的调价
+func GetMemberCardPrices(cardID uint) ([]*MemberCardPrice, error) {
+	if err := applyDuePrices(); err != nil {
+		return nil, err
+	}
+	var prices []*MemberCardPrice
+	if err := DB.Where("card_id = ?", cardID).Order("effective_at DESC, id DESC").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// CancelMemberCardPrice 取消尚未生效的调价
+func CancelMemberCardPrice(cardID, priceID uint) error {
+	result := DB.Where("id = ? AND card_id = ? AND applied_at IS NULL", priceID, cardID).Delete(&MemberCardPrice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberCardPriceNotFound
+	}
+	return nil
+}
+
+// applyDuePrices 将已到生效时间的调价写入会员卡价格，按生效时间顺序执行，最后到期的价格为准
+// 在读取会员卡时调用，多个实例重复调用不会重复生效
+func applyDuePrices() error {
+	now := time.Now()
+	var due []*MemberCardPrice
+	if err := DB.Where("applied_at IS NULL AND effective_at <= ?", now).Order("effective_at, id").Find(&due).Error; err != nil {
+		return err
+	}
+
+	for _, price := range due {
+		if err := DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&MemberCardPrice{}).Where("id = ? AND applied_at IS NULL", price.ID).Update("applied_at", now)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return tx.Model(&MemberCard{}).Where("id = ?", price.CardID).Update("price", price.Price).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillMemberCardPrices 为没有调价记录的会员卡补充初始价格记录
+func backfillMemberCardPrices() error {
+	var cards []*MemberCard
+	if err := DB.Where("id NOT IN (?)", DB.Model(&MemberCardPrice{}).Select("card_id")).Find(&cards).Error; err != nil {
+		return err
+	}
+	for _, card := range cards {
+		createdAt := card.CreatedAt
+		if err := DB.Create(&MemberCardPrice{
+			CardID:      card.ID,
+			Price:       card.Price,
+			EffectiveAt: createdAt,
+			AppliedAt:   &createdAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -56,7 +56,7 @@ func Init() error {
 	}
 
 	// 迁移数据库表
-	if err = DB.AutoMigrate(&User{}, &Role{}, &Permission{}, &RolePermission{}, &MemberCard{}, &Order{}); err != nil {
+	if err = DB.AutoMigrate(&User{}, &Role{}, &Permission{}, &RolePermission{}, &MemberCard{}, &MemberCardPrice{}, &Order{}, &OrderEvent{}, &RefreshToken{}, &RevokedToken{}, &UserTokenRevocation{}, &LoginAttempt{}, &UserTOTP{}, &RecoveryCode{}, &UserActionToken{}, &UserIdentity{}, &OAuthState{}, &Session{}, &APIKey{}, &PaymentNotification{}, &Refund{}, &Coupon{}, &CouponRedemption{}, &Subscription{}, &JobLock{}, &ReconciliationReport{}, &ReconciliationItem{}); err != nil {
 		zap.L().Error("迁移数据库表失败", zap.Error(err))
 		return err
 	}
@@ -1,8 +1,11 @@
 package model
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"interviewGenius/internal/pkg/setting"
 	"time"
 )
 
@@ -11,23 +14,61 @@ type OrderStatus string
 
 const (
 	OrderStatusCreated   OrderStatus = "created"   // 已创建
-	OrderStatusPaid      OrderStatus = "paid"      // 已支付
-	OrderStatusCancelled OrderStatus = "cancelled" // 已取消
+	OrderStatusPaid      OrderStatus = "paid"      // 已支付，部分退款后仍为已支付
+	OrderStatusCancelled OrderStatus = "cancelled" // 已取消，包括超时未支付
 	OrderStatusFailed    OrderStatus = "failed"    // 支付失败
+	OrderStatusClosed    OrderStatus = "closed"    // 支付渠道已关闭交易
+	OrderStatusRefunding OrderStatus = "refunding" // 退款处理中
+	OrderStatusRefunded  OrderStatus = "refunded"  // 已全额退款
+)
+
+// orderTransitions 订单状态允许的变更，未列出的状态为终态
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated: {OrderStatusPaid, OrderStatusCancelled, OrderStatusFailed, OrderStatusClosed},
+	// 已支付直接变为已退款用于早期没有经过退款中状态的退款
+	OrderStatusPaid:      {OrderStatusRefunding, OrderStatusRefunded},
+	OrderStatusRefunding: {OrderStatusPaid, OrderStatusRefunded},
+}
+
+// CanTransitionTo 判断订单能否从当前状态变为 to
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrOrderNotFound      = errors.New("订单不存在")
+	ErrOrderAlreadyPaid   = errors.New("订单已支付")
+	ErrOrderStatusInvalid = errors.New("订单状态不允许支付")
+	ErrEmailNotVerified   = errors.New("请先验证邮箱")
 )
 
 // Order 订单模型
 type Order struct {
-	ID           uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
-	UserID       uuid.UUID   `json:"user_id" gorm:"type:char(36);not null;index"`
-	CardID       uint        `json:"card_id" gorm:"not null"`
-	Amount       int         `json:"amount" gorm:"not null"`               // 订单金额（单位：分）
-	Status       OrderStatus `json:"status" gorm:"size:20;not null;index"` // 订单状态
-	PurchaseTime *time.Time  `json:"purchase_time"`                        // 购买时间
-	PaymentTime  *time.Time  `json:"payment_time"`                         // 支付时间
-	CreatedAt    time.Time   `json:"created_at"`
-	UpdatedAt    time.Time   `json:"updated_at"`
-	Card         *MemberCard `json:"card,omitempty" gorm:"foreignKey:CardID"`
+	ID             uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID         uuid.UUID   `json:"user_id" gorm:"type:char(36);not null;index"`
+	CardID         uint        `json:"card_id" gorm:"not null"`
+	Amount         int         `json:"amount" gorm:"not null"`                     // 实付金额（单位：分）
+	OriginalAmount int         `json:"original_amount" gorm:"not null;default:0"`  // 会员卡原价（单位：分）
+	Discount       int         `json:"discount" gorm:"not null;default:0"`         // 优惠金额（单位：分）
+	CouponID       *uint       `json:"coupon_id" gorm:"index"`                     // 使用的优惠券
+	SubscriptionID *uuid.UUID  `json:"subscription_id" gorm:"type:char(36);index"` // 自动续费订单所属的订阅
+	Status         OrderStatus `json:"status" gorm:"size:20;not null;index"`       // 订单状态
+	Version        int         `json:"version" gorm:"not null;default:0"`          // 乐观锁版本号，每次状态变更加一
+	Provider       string      `json:"provider" gorm:"size:20"`                    // 支付渠道
+	TradeNo        string      `json:"trade_no" gorm:"size:64;index"`              // 支付渠道的交易号
+	RefundedAmount int         `json:"refunded_amount" gorm:"not null;default:0"`  // 已退款金额（单位：分）
+	PeriodStart    *time.Time  `json:"period_start"`                               // 本订单提供的会员时段开始时间
+	PeriodEnd      *time.Time  `json:"period_end"`                                 // 本订单提供的会员时段结束时间，退款后相应缩短
+	PurchaseTime   *time.Time  `json:"purchase_time"`                              // 购买时间
+	PaymentTime    *time.Time  `json:"payment_time"`                               // 支付时间
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	Card           *MemberCard `json:"card,omitempty" gorm:"foreignKey:CardID"`
 }
 
 // BeforeCreate 创建前生成UUID
@@ -46,22 +87,54 @@ func (o *Order) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// CreateOrder 创建订单
-func CreateOrder(userID uuid.UUID, cardID uint, amount int) (*Order, error) {
+// CreateOrder 按会员卡价格创建订单，provider 为支付渠道名称，尚未选择渠道时为空
+// 填写优惠码时在同一事务中校验并占用优惠券，优惠码不可用时返回 ErrCouponUnavailable
+// 配置要求验证邮箱而用户尚未验证时返回 ErrEmailNotVerified
+func CreateOrder(userID uuid.UUID, card *MemberCard, provider, couponCode string) (*Order, error) {
+	if err := checkUserCanOrder(userID); err != nil {
+		return nil, err
+	}
+
 	order := &Order{
-		UserID: userID,
-		CardID: cardID,
-		Amount: amount,
-		Status: OrderStatusCreated,
+		UserID:         userID,
+		CardID:         card.ID,
+		Amount:         card.Price,
+		OriginalAmount: card.Price,
+		Provider:       provider,
+		Status:         OrderStatusCreated,
 	}
 
-	if err := DB.Create(order).Error; err != nil {
+	var err error
+	if couponCode == "" {
+		err = DB.Create(order).Error
+	} else {
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			return redeemCoupon(tx, order, couponCode)
+		})
+	}
+	if err != nil {
 		return nil, err
 	}
 
 	return order, nil
 }
 
+// checkUserCanOrder 按配置检查用户是否已验证邮箱，所有创建订单的入口都经过这里
+func checkUserCanOrder(userID uuid.UUID) error {
+	if !setting.SecuritySetting.Email.RequireVerifiedForOrder {
+		return nil
+	}
+
+	var user User
+	if err := DB.Select("id", "email_verified_at").First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
 // GetOrderByID 根据ID获取订单
 func GetOrderByID(id uuid.UUID) (*Order, error) {
 	var order Order
@@ -80,52 +153,128 @@ func GetUserOrders(userID uuid.UUID) ([]*Order, error) {
 	return orders, nil
 }
 
-// PayOrder 支付订单
-func PayOrder(id uuid.UUID) error {
-	now := time.Now()
-	return DB.Transaction(func(tx *gorm.DB) error {
-		// 更新订单状态
-		if err := tx.Model(&Order{}).Where("id = ?", id).Updates(map[string]interface{}{
-			"status":       OrderStatusPaid,
-			"payment_time": now,
-		}).Error; err != nil {
-			return err
-		}
+// PayOrder 支付订单并延长会员有效期
+// 只有已创建的订单可以支付，已支付过的订单返回 ErrOrderAlreadyPaid 且不会重复延长会员
+func PayOrder(id uuid.UUID, tradeNo string, info OrderEventInfo) error {
+	return payOrder(id, map[string]interface{}{"trade_no": tradeNo}, info)
+}
 
-		// 获取订单信息
-		var order Order
-		if err := tx.Preload("Card").First(&order, "id = ?", id).Error; err != nil {
-			return err
-		}
+// ConfirmOfflinePayment 管理员确认线下收款，referenceNo 为银行流水号等收款凭证号
+// 订单改为没有支付渠道，之后的退款同样线下处理
+func ConfirmOfflinePayment(id uuid.UUID, referenceNo string, info OrderEventInfo) error {
+	return payOrder(id, map[string]interface{}{
+		"trade_no": referenceNo,
+		"provider": "",
+	}, info)
+}
 
-		// 获取用户信息
-		var user User
-		if err := tx.First(&user, "id = ?", order.UserID).Error; err != nil {
-			return err
-		}
+// SetOrderProvider 为尚未选择支付渠道的未支付订单记录渠道
+func SetOrderProvider(id uuid.UUID, provider string) (bool, error) {
+	result := DB.Model(&Order{}).
+		Where("id = ? AND status = ? AND provider = ?", id, OrderStatusCreated, "").
+		Update("provider", provider)
+	return result.RowsAffected > 0, result.Error
+}
 
-		// 计算新的会员到期时间
-		var expiryTime time.Time
-		if user.MemberExpiry == nil || user.MemberExpiry.Before(now) {
-			// 如果用户不是会员或会员已过期，从当前时间开始计算
-			expiryTime = now.AddDate(0, 0, order.Card.DurationDays)
-		} else {
-			// 如果用户是会员且未过期，从当前到期时间开始叠加
-			expiryTime = user.MemberExpiry.AddDate(0, 0, order.Card.DurationDays)
-		}
+// payOrder 将订单改为已支付并延长会员有效期，updates 为同时更新的交易信息
+func payOrder(id uuid.UUID, updates map[string]interface{}, info OrderEventInfo) error {
+	return retryOnConflict(func() error {
+		return DB.Transaction(func(tx *gorm.DB) error {
+			var order Order
+			if err := tx.Preload("Card").First(&order, "id = ?", id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrOrderNotFound
+				}
+				return err
+			}
+			if order.PaymentTime != nil {
+				return ErrOrderAlreadyPaid
+			}
+			if !order.Status.CanTransitionTo(OrderStatusPaid) {
+				return ErrOrderStatusInvalid
+			}
+
+			// 锁定用户，避免并发支付时会员时长叠加错误
+			var user User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", order.UserID).Error; err != nil {
+				return err
+			}
 
-		// 更新用户的会员到期时间
-		if err := tx.Model(&User{}).Where("id = ?", order.UserID).Updates(map[string]interface{}{
-			"member_expiry": expiryTime,
-		}).Error; err != nil {
+			// 计算新的会员到期时间
+			now := time.Now()
+			periodStart := now
+			if user.MemberExpiry != nil && user.MemberExpiry.After(now) {
+				// 如果用户是会员且未过期，从当前到期时间开始叠加
+				periodStart = *user.MemberExpiry
+			}
+			expiryTime := periodStart.AddDate(0, 0, order.Card.DurationDays)
+
+			// 记录本订单提供的会员时段，退款时据此回退
+			values := map[string]interface{}{
+				"payment_time": now,
+				"period_start": periodStart,
+				"period_end":   expiryTime,
+			}
+			for column, value := range updates {
+				values[column] = value
+			}
+			if err := transitionOrder(tx, &order, OrderStatusPaid, info, values); err != nil {
+				return err
+			}
+			if err := useCoupon(tx, order.ID); err != nil {
+				return err
+			}
+
+			// 更新用户的会员到期时间
+			return tx.Model(&User{}).Where("id = ?", order.UserID).Updates(map[string]interface{}{
+				"member_expiry": expiryTime,
+			}).Error
+		})
+	})
+}
+
+// GetUnpaidOrders 获取在指定时间之前创建、仍未支付的订单，按创建时间排序
+func GetUnpaidOrders(createdBefore time.Time, limit int) ([]*Order, error) {
+	var orders []*Order
+	if err := DB.Where("status = ? AND created_at < ?", OrderStatusCreated, createdBefore).
+		Order("created_at").Limit(limit).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// CloseUnpaidOrder 将未支付的订单改为 status 指定的终态（已取消、支付失败或渠道已关闭）并归还占用的优惠券，
+// 订单已不是未支付状态时返回 false
+func CloseUnpaidOrder(id uuid.UUID, status OrderStatus, info OrderEventInfo) (bool, error) {
+	closed := false
+	err := retryOnConflict(func() error {
+		var order Order
+		if err := DB.First(&order, "id = ?", id).Error; err != nil {
 			return err
 		}
-
-		return nil
+		if order.Status != OrderStatusCreated {
+			return nil
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := transitionOrder(tx, &order, status, info, nil); err != nil {
+				return err
+			}
+			return releaseCoupon(tx, &order)
+		})
+		closed = err == nil
+		return err
 	})
+	return closed, err
 }
 
-// CancelOrder 取消订单
-func CancelOrder(id uuid.UUID) error {
-	return DB.Model(&Order{}).Where("id = ?", id).Update("status", OrderStatusCancelled).Error
+// CancelOrder 取消未支付的订单
+func CancelOrder(id uuid.UUID, info OrderEventInfo) error {
+	closed, err := CloseUnpaidOrder(id, OrderStatusCancelled, info)
+	if err != nil {
+		return err
+	}
+	if !closed {
+		return ErrOrderTransitionInvalid
+	}
+	return nil
 }
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderTransitionInvalid = errors.New("订单状态不允许该操作")
+	ErrOrderVersionConflict   = errors.New("订单已被其他操作修改")
+)
+
+// orderConflictRetries 乐观锁冲突时的最大尝试次数
+const orderConflictRetries = 3
+
+// ActorSystem 系统任务触发的状态变更
+const ActorSystem = "system"
+
+// UserActor 用户操作
+func UserActor(id uuid.UUID) string {
+	return "user:" + id.String()
+}
+
+// AdminActor 管理员操作
+func AdminActor(id uuid.UUID) string {
+	return "admin:" + id.String()
+}
+
+// ProviderActor 支付渠道的通知或查询结果
+func ProviderActor(name string) string {
+	return "provider:" + name
+}
+
+// OrderEventInfo 状态变更的来源信息
+type OrderEventInfo struct {
+	Actor   string // 操作方，见 ActorSystem、UserActor 等
+	Reason  string
+	Payload string // 渠道原始报文
+}
+
+// OrderEvent 订单状态变更记录
+type OrderEvent struct {
+	ID         uint        `json:"id" gorm:"primarykey"`
+	OrderID    uuid.UUID   `json:"order_id" gorm:"type:char(36);not null;index"`
+	FromStatus OrderStatus `json:"from_status" gorm:"size:20;not null"`
+	ToStatus   OrderStatus `json:"to_status" gorm:"size:20;not null"`
+	Actor      string      `json:"actor" gorm:"size:64;not null"`
+	Reason     string      `json:"reason" gorm:"size:255"`
+	Payload    string      `json:"-" gorm:"type:text"` // 仅用于排查，不对外返回
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// GetOrderEvents 按时间顺序获取订单的状态变更记录
+func GetOrderEvents(orderID uuid.UUID) ([]*OrderEvent, error) {
+	var events []*OrderEvent
+	if err := DB.Where("order_id = ?", orderID).Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// transitionOrder 将订单变为 to 状态并记录变更，updates 为同时更新的其他字段
+// 按读取订单时的版本号条件更新，订单已被其他操作修改时返回 ErrOrderVersionConflict
+func transitionOrder(tx *gorm.DB, order *Order, to OrderStatus, info OrderEventInfo, updates map[string]interface{}) error {
+	if !order.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrOrderTransitionInvalid, order.Status, to)
+	}
+
+	values := map[string]interface{}{
+		"status":  to,
+		"version": gorm.Expr("version + 1"),
+	}
+	for column, value := range updates {
+		values[column] = value
+	}
+	result := tx.Model(&Order{}).Where("id = ? AND version = ?", order.ID, order.Version).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderVersionConflict
+	}
+
+	event := &OrderEvent{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		Actor:      info.Actor,
+		Reason:     truncateRunes(info.Reason, 255),
+		Payload:    info.Payload,
+	}
+	order.Status = to
+	order.Version++
+	return tx.Create(event).Error
+}
+
+// retryOnConflict 乐观锁冲突时重新执行 fn，fn 每次需要重新读取订单
+func retryOnConflict(fn func() error) error {
+	var err error
+	for i := 0; i < orderConflictRetries; i++ {
+		if err = fn(); !errors.Is(err, ErrOrderVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+// truncateRunes 按字符截断字符串，避免截断多字节字符
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// 支付通知处理结果
+const (
+	NotifyResultPending   = "pending"   // 待处理
+	NotifyResultProcessed = "processed" // 已处理，订单已支付
+	NotifyResultDuplicate = "duplicate" // 订单此前已支付
+	NotifyResultIgnored   = "ignored"   // 非成功状态的交易，无需处理
+	NotifyResultRejected  = "rejected"  // 签名、商户号、金额等校验未通过
+	NotifyResultConflict  = "conflict"  // 订单状态不允许支付，需人工处理
+)
+
+// PaymentNotification 支付渠道的异步通知原文，用于审计和排查
+type PaymentNotification struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Provider    string    `json:"provider" gorm:"size:20;not null"`
+	NotifyID    string    `json:"notify_id" gorm:"size:64;index"`
+	OutTradeNo  string    `json:"out_trade_no" gorm:"size:64;index"`
+	TradeNo     string    `json:"trade_no" gorm:"size:64"`
+	TradeStatus string    `json:"trade_status" gorm:"size:32"`
+	TotalAmount string    `json:"total_amount" gorm:"size:20"`
+	Verified    bool      `json:"verified"` // 签名是否校验通过
+	Result      string    `json:"result" gorm:"size:20;not null;index"`
+	Error       string    `json:"error" gorm:"size:255"`
+	RawBody     string    `json:"raw_body" gorm:"type:text"`
+	ClientIP    string    `json:"client_ip" gorm:"size:45"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreatePaymentNotification 保存收到的通知原文
+func CreatePaymentNotification(notification *PaymentNotification) error {
+	if notification.Result == "" {
+		notification.Result = NotifyResultPending
+	}
+	return DB.Create(notification).Error
+}
+
+// UpdatePaymentNotification 更新通知的校验和处理结果
+func UpdatePaymentNotification(notification *PaymentNotification) error {
+	errMsg := truncateRunes(notification.Error, 255)
+	return DB.Model(&PaymentNotification{}).Where("id = ?", notification.ID).Updates(map[string]interface{}{
+		"notify_id":    notification.NotifyID,
+		"out_trade_no": notification.OutTradeNo,
+		"trade_no":     notification.TradeNo,
+		"trade_status": notification.TradeStatus,
+		"total_amount": notification.TotalAmount,
+		"verified":     notification.Verified,
+		"result":       notification.Result,
+		"error":        errMsg,
+	}).Error
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"interviewGenius/internal/pkg/rbac"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -11,6 +13,7 @@ type Permission struct {
 	Method      string    `json:"method" gorm:"size:8;not null"`
 	PathPattern string    `json:"path_pattern" gorm:"size:128;not null"`
 	Description string    `json:"description" gorm:"size:128"`
+	Orphaned    bool      `json:"orphaned" gorm:"default:false"` // 未匹配任何已注册路由
 	Roles       []*Role   `json:"roles,omitempty" gorm:"many2many:role_permission;"`
 }
 
@@ -36,6 +39,15 @@ func GetPermissionByID(id uuid.UUID) (*Permission, error) {
 	return &permission, nil
 }
 
+// GetPermissionsByIDs 根据ID批量获取权限
+func GetPermissionsByIDs(ids []string) ([]*Permission, error) {
+	var permissions []*Permission
+	if err := DB.Where("id IN ?", ids).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
 // GetPermissionList 获取权限列表
 func GetPermissionList() ([]*Permission, error) {
 	var permissions []*Permission
@@ -47,21 +59,33 @@ func GetPermissionList() ([]*Permission, error) {
 
 // UpdatePermission 更新权限
 func UpdatePermission(permission *Permission) error {
-	return DB.Model(&Permission{}).Where("id = ?", permission.ID).Updates(map[string]interface{}{
+	if err := DB.Model(&Permission{}).Where("id = ?", permission.ID).Updates(map[string]interface{}{
 		"method":       permission.Method,
 		"path_pattern": permission.PathPattern,
 		"description":  permission.Description,
-	}).Error
+	}).Error; err != nil {
+		return err
+	}
+
+	rbac.Invalidate()
+	return nil
 }
 
 // DeletePermission 删除权限
 func DeletePermission(id uuid.UUID) error {
+	defer rbac.Invalidate()
+
 	return DB.Transaction(func(tx *gorm.DB) error {
 		// 删除权限与角色的关联
 		if err := tx.Exec("DELETE FROM role_permission WHERE permission_id = ?", id).Error; err != nil {
 			return err
 		}
 
+		// 从 API Key 的权限范围中移除
+		if err := tx.Exec("DELETE FROM api_key_permission WHERE permission_id = ?", id).Error; err != nil {
+			return err
+		}
+
 		// 删除权限
 		if err := tx.Where("id = ?", id).Delete(&Permission{}).Error; err != nil {
 			return err
@@ -72,31 +96,44 @@ func DeletePermission(id uuid.UUID) error {
 }
 
 // CheckUserPermission 检查用户是否有特定权限
+// path 为 Gin 路由模板（如 /api/v1/users/:id），拥有超级角色的用户直接放行
 func CheckUserPermission(userID uuid.UUID, method, path string) (bool, error) {
-	var count int64
-
-	// 复杂SQL查询，检查用户是否有匹配的权限
-	// 1. 获取用户的所有角色
-	// 2. 检查这些角色是否有匹配的权限
-	query := `
-		SELECT COUNT(*) 
-		FROM permissions p
-		JOIN role_permission rp ON p.id = rp.permission_id
-		JOIN user_role ur ON rp.role_id = ur.role_id
-		JOIN roles r ON ur.role_id = r.id
-		WHERE ur.user_id = ?
-		AND (
-			r.is_super = true 
-			OR (
-				p.method = ? 
-				AND ? LIKE REPLACE(REPLACE(p.path_pattern, '*', '%'), '?', '_')
-			)
-		)
-	`
-
-	if err := DB.Raw(query, userID, method, path).Count(&count).Error; err != nil {
+	roles, err := GetUserRoles(userID)
+	if err != nil {
 		return false, err
 	}
 
-	return count > 0, nil
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		if role.IsSuper {
+			return true, nil
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	if len(roleIDs) == 0 {
+		return false, nil
+	}
+
+	return rbac.Enforce(roleIDs, method, path, func() ([]rbac.Policy, error) {
+		return GetRolesPolicies(roleIDs)
+	})
+}
+
+// GetRolesPolicies 获取角色集合拥有的全部权限策略
+func GetRolesPolicies(roleIDs []uint) ([]rbac.Policy, error) {
+	var permissions []*Permission
+	subQuery := DB.Model(&RolePermission{}).Select("permission_id").Where("role_id IN ?", roleIDs)
+	if err := DB.Where("id IN (?)", subQuery).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	policies := make([]rbac.Policy, 0, len(permissions))
+	for _, permission := range permissions {
+		policies = append(policies, rbac.Policy{
+			Method:      permission.Method,
+			PathPattern: permission.PathPattern,
+		})
+	}
+	return policies, nil
 }
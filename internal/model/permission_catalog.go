@@ -0,0 +1,173 @@
+package model
+
+import (
+	"interviewGenius/internal/pkg/rbac"
+	"sync"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RouteInfo 已注册的路由
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// PermissionSyncResult 路由同步结果
+type PermissionSyncResult struct {
+	Created  int `json:"created"`
+	Orphaned int `json:"orphaned"`
+	Restored int `json:"restored"`
+}
+
+// PermissionDriftReport 权限与路由差异报告
+type PermissionDriftReport struct {
+	RouteCount          int           `json:"route_count"`
+	PermissionCount     int           `json:"permission_count"`
+	UncoveredRoutes     []RouteInfo   `json:"uncovered_routes"`     // 没有任何权限覆盖的路由
+	OrphanedPermissions []*Permission `json:"orphaned_permissions"` // 未匹配任何路由的权限
+}
+
+var (
+	catalogMu    sync.RWMutex
+	routeCatalog []RouteInfo
+)
+
+// SyncRoutePermissions 根据已注册路由同步权限表
+// 为每个方法 + 路径创建缺失的权限，未匹配任何路由的权限标记为孤立而不删除
+func SyncRoutePermissions(routes []RouteInfo) (*PermissionSyncResult, error) {
+	result := &PermissionSyncResult{}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var permissions []*Permission
+		if err := tx.Find(&permissions).Error; err != nil {
+			return err
+		}
+
+		// 创建缺失的路由权限
+		existing := make(map[RouteInfo]bool, len(permissions))
+		for _, permission := range permissions {
+			existing[RouteInfo{Method: permission.Method, Path: permission.PathPattern}] = true
+		}
+
+		var regularRole *Role
+		for _, route := range routes {
+			if existing[route] {
+				continue
+			}
+
+			permission := &Permission{
+				Method:      route.Method,
+				PathPattern: route.Path,
+				Description: "路由同步",
+			}
+			if err := tx.Create(permission).Error; err != nil {
+				return err
+			}
+			permissions = append(permissions, permission)
+			existing[route] = true
+			result.Created++
+
+			// 新路由属于普通用户默认权限时同步授予
+			if !isRegularUserRoute(route.Method, route.Path) {
+				continue
+			}
+			if regularRole == nil {
+				var role Role
+				if err := tx.Where("role_name = ?", DefaultRoleName).First(&role).Error; err != nil {
+					continue
+				}
+				regularRole = &role
+			}
+			if err := tx.Create(&RolePermission{RoleID: regularRole.ID, PermissionID: permission.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 更新孤立标记
+		for _, permission := range permissions {
+			orphaned := !permissionMatchesAny(permission, routes)
+			if orphaned == permission.Orphaned {
+				continue
+			}
+			if err := tx.Model(&Permission{}).Where("id = ?", permission.ID).Update("orphaned", orphaned).Error; err != nil {
+				return err
+			}
+			if orphaned {
+				result.Orphaned++
+			} else {
+				result.Restored++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	catalogMu.Lock()
+	routeCatalog = routes
+	catalogMu.Unlock()
+
+	rbac.Invalidate()
+
+	zap.L().Info("路由权限同步完成",
+		zap.Int("routes", len(routes)),
+		zap.Int("created", result.Created),
+		zap.Int("orphaned", result.Orphaned),
+		zap.Int("restored", result.Restored))
+
+	return result, nil
+}
+
+// GetPermissionDriftReport 获取权限与当前路由的差异报告
+func GetPermissionDriftReport() (*PermissionDriftReport, error) {
+	permissions, err := GetPermissionList()
+	if err != nil {
+		return nil, err
+	}
+
+	catalogMu.RLock()
+	routes := routeCatalog
+	catalogMu.RUnlock()
+
+	report := &PermissionDriftReport{
+		RouteCount:          len(routes),
+		PermissionCount:     len(permissions),
+		UncoveredRoutes:     make([]RouteInfo, 0),
+		OrphanedPermissions: make([]*Permission, 0),
+	}
+
+	for _, route := range routes {
+		covered := false
+		for _, permission := range permissions {
+			if rbac.MethodMatch(route.Method, permission.Method) && rbac.KeyMatch(route.Path, permission.PathPattern) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			report.UncoveredRoutes = append(report.UncoveredRoutes, route)
+		}
+	}
+
+	for _, permission := range permissions {
+		if !permissionMatchesAny(permission, routes) {
+			report.OrphanedPermissions = append(report.OrphanedPermissions, permission)
+		}
+	}
+
+	return report, nil
+}
+
+// permissionMatchesAny 判断权限是否匹配任一路由
+func permissionMatchesAny(permission *Permission, routes []RouteInfo) bool {
+	for _, route := range routes {
+		if rbac.MethodMatch(route.Method, permission.Method) && rbac.KeyMatch(route.Path, permission.PathPattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"interviewGenius/internal/pkg/rbac"
+	"testing"
+)
+
+func TestCheckUserPermissionAfterRoleChange(t *testing.T) {
+	setupTestDB(t, &User{}, &Role{}, &Permission{}, &RolePermission{})
+	rbac.Invalidate()
+	if err := DB.SetupJoinTable(&User{}, "Roles", &UserRole{}); err != nil {
+		t.Fatalf("设置User-Role关联表失败: %v", err)
+	}
+
+	role := &Role{RoleName: "editor", Name: "编辑"}
+	if err := DB.Create(role).Error; err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+	permission := &Permission{Method: "GET", PathPattern: "/api/v1/users/:id"}
+	if err := DB.Create(permission).Error; err != nil {
+		t.Fatalf("创建权限失败: %v", err)
+	}
+	user := &User{Username: "editor", Email: "editor@example.com"}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if err := AddRolesToUser(user.ID, []uint{role.ID}); err != nil {
+		t.Fatalf("分配角色失败: %v", err)
+	}
+
+	check := func(want bool) {
+		t.Helper()
+		allowed, err := CheckUserPermission(user.ID, "GET", "/api/v1/users/:id")
+		if err != nil {
+			t.Fatalf("检查权限失败: %v", err)
+		}
+		if allowed != want {
+			t.Fatalf("检查权限 = %v, 期望 %v", allowed, want)
+		}
+	}
+
+	// 未授权的结果被缓存后，为角色添加权限立即生效
+	check(false)
+	if err := AddPermissionsToRole(role.ID, []string{permission.ID.String()}); err != nil {
+		t.Fatalf("添加权限失败: %v", err)
+	}
+	check(true)
+
+	// 修改权限的路径后，缓存的允许结果失效
+	permission.PathPattern = "/api/v1/roles/:id"
+	if err := UpdatePermission(permission); err != nil {
+		t.Fatalf("更新权限失败: %v", err)
+	}
+	check(false)
+
+	// 从角色移除权限后同样失效
+	permission.PathPattern = "/api/v1/users/:id"
+	if err := UpdatePermission(permission); err != nil {
+		t.Fatalf("更新权限失败: %v", err)
+	}
+	check(true)
+	if err := RemovePermissionFromRole(role.ID, permission.ID); err != nil {
+		t.Fatalf("移除权限失败: %v", err)
+	}
+	check(false)
+}
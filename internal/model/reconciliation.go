@@ -0,0 +1,138 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 对账差异类型
+const (
+	DiscrepancyMissingLocal   = "missing_local"   // 渠道有交易，本地没有对应订单
+	DiscrepancyMissingRemote  = "missing_remote"  // 本地已支付，渠道账单中没有交易
+	DiscrepancyAmountMismatch = "amount_mismatch" // 支付或退款金额不一致
+	DiscrepancyStatusMismatch = "status_mismatch" // 渠道已收款，本地订单未支付
+)
+
+// 对账报告状态
+const (
+	ReconcileStatusBalanced   = "balanced"   // 无差异
+	ReconcileStatusUnbalanced = "unbalanced" // 存在差异
+	ReconcileStatusFailed     = "failed"     // 获取或解析账单失败
+)
+
+var ErrReconciliationNotFound = errors.New("对账报告不存在")
+
+// ReconciliationReport 单个渠道某一天的对账报告
+type ReconciliationReport struct {
+	ID            uint                  `json:"id" gorm:"primarykey"`
+	Provider      string                `json:"provider" gorm:"size:20;not null;index:idx_reconcile_provider_date"`
+	BillDate      string                `json:"bill_date" gorm:"size:10;not null;index:idx_reconcile_provider_date"` // 账单日期 2006-01-02
+	Source        string                `json:"source" gorm:"size:255"`                                              // 账单来源：文件路径、上传文件名或渠道
+	Status        string                `json:"status" gorm:"size:20;not null"`
+	RemoteCount   int                   `json:"remote_count"`  // 账单记录数
+	LocalCount    int                   `json:"local_count"`   // 当天本地已支付订单数
+	MatchedCount  int                   `json:"matched_count"` // 核对一致的订单数
+	Discrepancies int                   `json:"discrepancies"` // 差异数
+	Error         string                `json:"error" gorm:"size:255"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Items         []*ReconciliationItem `json:"items,omitempty" gorm:"foreignKey:ReportID"`
+}
+
+// ReconciliationItem 对账差异明细
+type ReconciliationItem struct {
+	ID           uint   `json:"id" gorm:"primarykey"`
+	ReportID     uint   `json:"report_id" gorm:"not null;index"`
+	OutTradeNo   string `json:"out_trade_no" gorm:"size:64;not null"`
+	TradeNo      string `json:"trade_no" gorm:"size:64"`
+	Type         string `json:"type" gorm:"size:20;not null"`
+	LocalAmount  int    `json:"local_amount"`  // 本地金额（单位：分）
+	RemoteAmount int    `json:"remote_amount"` // 渠道金额（单位：分）
+	LocalStatus  string `json:"local_status" gorm:"size:20"`
+	Detail       string `json:"detail" gorm:"size:255"`
+}
+
+// CreateReconciliationReport 保存对账报告及差异明细
+func CreateReconciliationReport(report *ReconciliationReport) error {
+	report.Error = truncateRunes(report.Error, 255)
+	return DB.Create(report).Error
+}
+
+// HasReconciliationReport 判断渠道某天是否已有对账报告
+func HasReconciliationReport(provider, billDate string) (bool, error) {
+	var count int64
+	if err := DB.Model(&ReconciliationReport{}).
+		Where("provider = ? AND bill_date = ?", provider, billDate).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetReconciliationReports 分页获取对账报告，provider 为空时不过滤
+func GetReconciliationReports(provider string, page, limit int) ([]*ReconciliationReport, int64, error) {
+	var (
+		reports []*ReconciliationReport
+		total   int64
+	)
+
+	filter := func(db *gorm.DB) *gorm.DB {
+		if provider != "" {
+			return db.Where("provider = ?", provider)
+		}
+		return db
+	}
+	if err := DB.Model(&ReconciliationReport{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := DB.Scopes(filter).Order("bill_date DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&reports).Error; err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
+
+// GetReconciliationReport 获取对账报告及差异明细
+func GetReconciliationReport(id uint) (*ReconciliationReport, error) {
+	var report ReconciliationReport
+	if err := DB.Preload("Items").First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetOrdersPaidBetween 获取渠道在时间段内支付的订单
+func GetOrdersPaidBetween(provider string, start, end time.Time) ([]*Order, error) {
+	var orders []*Order
+	if err := DB.Where("provider = ? AND payment_time >= ? AND payment_time < ?", provider, start, end).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetOrdersByIDs 批量获取订单
+func GetOrdersByIDs(ids []uuid.UUID) ([]*Order, error) {
+	var orders []*Order
+	if len(ids) == 0 {
+		return orders, nil
+	}
+	if err := DB.Where("id IN ?", ids).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetRefundsCompletedBetween 获取时间段内完成的退款
+func GetRefundsCompletedBetween(start, end time.Time) ([]*Refund, error) {
+	var refunds []*Refund
+	if err := DB.Where("status = ? AND completed_at >= ? AND completed_at < ?", RefundStatusSuccess, start, end).
+		Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("无效的刷新令牌")
+	ErrRefreshTokenExpired = errors.New("刷新令牌已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，请重新登录")
+)
+
+// RefreshToken 刷新令牌模型，同一次登录轮换出的令牌属于同一家族
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	FamilyID  uuid.UUID  `json:"family_id" gorm:"type:char(36);not null;index"`
+	TokenHash string     `json:"-" gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`    // 轮换时间
+	RevokedAt *time.Time `json:"revoked_at"` // 吊销时间
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate 创建前生成UUID
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	if t.FamilyID == uuid.Nil {
+		t.FamilyID = uuid.New()
+	}
+	return nil
+}
+
+// CreateRefreshToken 创建刷新令牌，familyID 为空时开启新的令牌家族
+func CreateRefreshToken(userID, familyID uuid.UUID, tokenHash string, expiresAt time.Time) (*RefreshToken, error) {
+	token := &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+	if err := DB.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken 轮换刷新令牌
+// 旧令牌被标记为已使用并签发同家族的新令牌；已使用或已吊销的令牌被重放时吊销整个家族
+func RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (*RefreshToken, error) {
+	var (
+		newToken *RefreshToken
+		reused   bool
+	)
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var old RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", oldHash).First(&old).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+
+		// 重放检测：吊销整个令牌家族后正常提交事务
+		if old.UsedAt != nil || old.RevokedAt != nil {
+			reused = true
+			if err := tx.Model(&RefreshToken{}).
+				Where("family_id = ? AND revoked_at IS NULL", old.FamilyID).
+				Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+			return revokeSessions(tx, "id = ?", old.FamilyID)
+		}
+
+		if old.ExpiresAt.Before(now) {
+			return ErrRefreshTokenExpired
+		}
+
+		if err := tx.Model(&old).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		newToken = &RefreshToken{
+			UserID:    old.UserID,
+			FamilyID:  old.FamilyID,
+			TokenHash: newHash,
+			ExpiresAt: expiresAt,
+		}
+		if err := tx.Create(newToken).Error; err != nil {
+			return err
+		}
+		return extendSession(tx, old.FamilyID, expiresAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return newToken, nil
+}
+
+// RevokeRefreshTokenFamily 吊销整个令牌家族及对应的会话
+func RevokeRefreshTokenFamily(familyID uuid.UUID) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, "id = ?", familyID)
+	})
+}
+
+// RevokeRefreshTokenFamilyByHash 吊销用户指定刷新令牌所在的家族
+func RevokeRefreshTokenFamilyByHash(userID uuid.UUID, tokenHash string) error {
+	var token RefreshToken
+	if err := DB.Where("user_id = ? AND token_hash = ?", userID, tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		return err
+	}
+	return RevokeRefreshTokenFamily(token.FamilyID)
+}
+
+// RevokeUserRefreshTokens 吊销用户的全部刷新令牌和会话
+func RevokeUserRefreshTokens(userID uuid.UUID) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return revokeSessions(tx, "user_id = ?", userID)
+	})
+}
@@ -0,0 +1,347 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundStatus 退款状态
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"    // 已创建，尚未提交到支付渠道
+	RefundStatusProcessing RefundStatus = "processing" // 支付渠道处理中
+	RefundStatusSuccess    RefundStatus = "success"    // 退款成功，会员时长已回退
+	RefundStatusFailed     RefundStatus = "failed"     // 退款失败
+)
+
+var (
+	ErrRefundNotFound      = errors.New("退款记录不存在")
+	ErrOrderNotRefundable  = errors.New("订单状态不允许退款")
+	ErrRefundAmountInvalid = errors.New("退款金额无效或超过可退金额")
+	ErrRefundInProgress    = errors.New("订单有正在处理的退款")
+)
+
+// 会员时段首尾衔接的容差，用于识别叠加购买的订单
+const periodTolerance = time.Second
+
+// Refund 订单退款，一个订单可以多次部分退款
+type Refund struct {
+	ID              uuid.UUID    `json:"id" gorm:"type:char(36);primaryKey"` // 同时作为商户退款单号
+	OrderID         uuid.UUID    `json:"order_id" gorm:"type:char(36);not null;index"`
+	UserID          uuid.UUID    `json:"user_id" gorm:"type:char(36);not null;index"`
+	Amount          int          `json:"amount" gorm:"not null"` // 退款金额（单位：分）
+	Reason          string       `json:"reason" gorm:"size:255"`
+	Status          RefundStatus `json:"status" gorm:"size:20;not null;index"`
+	RefundNo        string       `json:"refund_no" gorm:"size:64"`         // 支付渠道的退款单号
+	RollbackSeconds int64        `json:"rollback_seconds"`                 // 回退的会员时长（秒）
+	Error           string       `json:"error" gorm:"size:255"`            // 失败原因
+	OperatorID      uuid.UUID    `json:"operator_id" gorm:"type:char(36)"` // 发起退款的管理员
+	CompletedAt     *time.Time   `json:"completed_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// BeforeCreate 创建前生成UUID
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Status == "" {
+		r.Status = RefundStatusPending
+	}
+	return nil
+}
+
+// CreateRefund 为订单创建退款记录并将订单改为退款中
+// amount 为 0 时退还全部可退金额；同一订单同时只能有一笔处理中的退款
+func CreateRefund(orderID uuid.UUID, amount int, reason string, operatorID uuid.UUID) (*Refund, *Order, error) {
+	var (
+		refund *Refund
+		order  Order
+	)
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("订单不存在")
+			}
+			return err
+		}
+		if order.Status == OrderStatusRefunding {
+			return ErrRefundInProgress
+		}
+		if order.Status != OrderStatusPaid {
+			return ErrOrderNotRefundable
+		}
+
+		remaining := order.Amount - order.RefundedAmount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return ErrRefundAmountInvalid
+		}
+
+		var inProgress int64
+		if err := tx.Model(&Refund{}).
+			Where("order_id = ? AND status IN ?", orderID, []RefundStatus{RefundStatusPending, RefundStatusProcessing}).
+			Count(&inProgress).Error; err != nil {
+			return err
+		}
+		if inProgress > 0 {
+			return ErrRefundInProgress
+		}
+
+		refund = &Refund{
+			OrderID:    order.ID,
+			UserID:     order.UserID,
+			Amount:     amount,
+			Reason:     reason,
+			OperatorID: operatorID,
+		}
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		return transitionOrder(tx, &order, OrderStatusRefunding, OrderEventInfo{
+			Actor:  AdminActor(operatorID),
+			Reason: "申请退款 " + refund.ID.String() + ": " + reason,
+		}, nil)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return refund, &order, nil
+}
+
+// GetRefund 根据ID获取退款记录
+func GetRefund(id uuid.UUID) (*Refund, error) {
+	var refund Refund
+	if err := DB.First(&refund, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// GetOrderRefunds 获取订单的退款记录
+func GetOrderRefunds(orderID uuid.UUID) ([]*Refund, error) {
+	var refunds []*Refund
+	if err := DB.Where("order_id = ?", orderID).Order("created_at DESC").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// UpdateRefundStatus 更新尚未完成的退款状态，用于处理中和失败
+// 退款失败时订单从退款中恢复为已支付
+func UpdateRefundStatus(id uuid.UUID, status RefundStatus, refundNo, errMsg string) error {
+	errMsg = truncateRunes(errMsg, 255)
+	updates := map[string]interface{}{
+		"status": status,
+		"error":  errMsg,
+	}
+	if refundNo != "" {
+		updates["refund_no"] = refundNo
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Refund{}).
+			Where("id = ? AND status IN ?", id, []RefundStatus{RefundStatusPending, RefundStatusProcessing}).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 || status != RefundStatusFailed {
+			return result.Error
+		}
+
+		var refund Refund
+		if err := tx.First(&refund, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var order Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", refund.OrderID).Error; err != nil {
+			return err
+		}
+		if order.Status != OrderStatusRefunding {
+			return nil
+		}
+		return transitionOrder(tx, &order, OrderStatusPaid, OrderEventInfo{
+			Actor:  refundActor(&order),
+			Reason: "退款失败 " + refund.ID.String() + ": " + errMsg,
+		}, nil)
+	})
+}
+
+// CompleteRefund 退款成功后更新订单并回退会员时长，重复调用不会重复回退
+// 回退时长为本订单尚未使用的会员时长按退款金额占剩余可退金额的比例折算；
+// 叠加在本订单之后的订单时段和用户到期时间一并前移
+func CompleteRefund(id uuid.UUID, refundNo string) (*Refund, error) {
+	var refund Refund
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefundNotFound
+			}
+			return err
+		}
+		if refund.Status == RefundStatusSuccess {
+			return nil
+		}
+		if refund.Status == RefundStatusFailed {
+			return errors.New("退款已失败")
+		}
+
+		var order Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Card").First(&order, "id = ?", refund.OrderID).Error; err != nil {
+			return err
+		}
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", order.UserID).Error; err != nil {
+			return err
+		}
+
+		remaining := order.Amount - order.RefundedAmount
+		if refund.Amount > remaining {
+			return ErrRefundAmountInvalid
+		}
+
+		rollback, err := rollbackMembership(tx, &order, &user, refund.Amount, remaining)
+		if err != nil {
+			return err
+		}
+
+		// 全额退款后订单变为已退款，部分退款恢复为已支付
+		refundedAmount := order.RefundedAmount + refund.Amount
+		target := OrderStatusPaid
+		if refundedAmount == order.Amount {
+			target = OrderStatusRefunded
+		}
+		orderUpdates := map[string]interface{}{
+			"refunded_amount": refundedAmount,
+		}
+		if order.Status == target {
+			err = tx.Model(&Order{}).Where("id = ?", order.ID).Updates(orderUpdates).Error
+		} else {
+			err = transitionOrder(tx, &order, target, OrderEventInfo{
+				Actor:  refundActor(&order),
+				Reason: "退款成功 " + refund.ID.String(),
+			}, orderUpdates)
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		refund.Status = RefundStatusSuccess
+		refund.RollbackSeconds = int64(rollback / time.Second)
+		refund.CompletedAt = &now
+		refund.Error = ""
+		if refundNo != "" {
+			refund.RefundNo = refundNo
+		}
+		return tx.Model(&Refund{}).Where("id = ?", refund.ID).Updates(map[string]interface{}{
+			"status":           refund.Status,
+			"refund_no":        refund.RefundNo,
+			"rollback_seconds": refund.RollbackSeconds,
+			"completed_at":     now,
+			"error":            "",
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// refundActor 有支付渠道的退款结果来自渠道，线下订单的退款由系统直接完成
+func refundActor(order *Order) string {
+	if order.Provider == "" {
+		return ActorSystem
+	}
+	return ProviderActor(order.Provider)
+}
+
+// rollbackMembership 按退款比例回退订单未使用的会员时长，返回回退的时长
+func rollbackMembership(tx *gorm.DB, order *Order, user *User, amount, remaining int) (time.Duration, error) {
+	now := time.Now()
+
+	// 本订单尚未使用的时长
+	var unused time.Duration
+	legacy := order.PeriodStart == nil || order.PeriodEnd == nil
+	if legacy {
+		// 早期订单没有记录会员时段，按会员卡时长和用户剩余时长估算
+		if user.MemberExpiry != nil && user.MemberExpiry.After(now) {
+			unused = user.MemberExpiry.Sub(now)
+		}
+		if order.Card != nil {
+			if full := time.Duration(order.Card.DurationDays) * 24 * time.Hour; unused > full {
+				unused = full
+			}
+		}
+	} else {
+		start := *order.PeriodStart
+		if now.After(start) {
+			start = now
+		}
+		if order.PeriodEnd.After(start) {
+			unused = order.PeriodEnd.Sub(start)
+		}
+	}
+
+	// 按秒折算，避免大金额时溢出
+	rollback := time.Duration(int64(unused/time.Second)*int64(amount)/int64(remaining)) * time.Second
+	if rollback <= 0 {
+		return 0, nil
+	}
+
+	if legacy {
+		expiry := user.MemberExpiry.Add(-rollback)
+		return rollback, tx.Model(&User{}).Where("id = ?", user.ID).Update("member_expiry", expiry).Error
+	}
+
+	// 缩短本订单的会员时段
+	originalEnd := *order.PeriodEnd
+	if err := tx.Model(&Order{}).Where("id = ?", order.ID).Update("period_end", originalEnd.Add(-rollback)).Error; err != nil {
+		return 0, err
+	}
+
+	// 前移首尾相接叠加在本订单之后的订单，遇到间断（会员曾过期后重新购买）时停止
+	var later []*Order
+	if err := tx.Where("user_id = ? AND id <> ? AND period_start >= ?", order.UserID, order.ID, originalEnd.Add(-periodTolerance)).
+		Order("period_start").Find(&later).Error; err != nil {
+		return 0, err
+	}
+	chainEnd := originalEnd
+	for _, o := range later {
+		if o.PeriodEnd == nil || absDuration(o.PeriodStart.Sub(chainEnd)) > periodTolerance {
+			break
+		}
+		chainEnd = *o.PeriodEnd
+		if err := tx.Model(&Order{}).Where("id = ?", o.ID).Updates(map[string]interface{}{
+			"period_start": o.PeriodStart.Add(-rollback),
+			"period_end":   o.PeriodEnd.Add(-rollback),
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	// 用户到期时间位于这条叠加链的末尾时一并前移
+	if user.MemberExpiry != nil && absDuration(user.MemberExpiry.Sub(chainEnd)) <= periodTolerance {
+		if err := tx.Model(&User{}).Where("id = ?", user.ID).Update("member_expiry", user.MemberExpiry.Add(-rollback)).Error; err != nil {
+			return 0, err
+		}
+	}
+	return rollback, nil
+}
+
+// absDuration 时长的绝对值
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// periodDays 测试会员卡的时长
+const periodDays = 30
+
+// paidChain 为用户依次支付 n 个会员卡订单，订单的会员时段首尾相接
+func paidChain(t *testing.T, user *User, card *MemberCard, n int) []*Order {
+	t.Helper()
+
+	orders := make([]*Order, 0, n)
+	for i := 0; i < n; i++ {
+		order := &Order{
+			UserID:         user.ID,
+			CardID:         card.ID,
+			Amount:         card.Price,
+			OriginalAmount: card.Price,
+			Status:         OrderStatusCreated,
+		}
+		if err := DB.Create(order).Error; err != nil {
+			t.Fatalf("创建订单失败: %v", err)
+		}
+		if err := PayOrder(order.ID, "trade-"+order.ID.String(), OrderEventInfo{Actor: ActorSystem}); err != nil {
+			t.Fatalf("支付订单失败: %v", err)
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// assertNear 断言两个时间相差不超过2秒
+func assertNear(t *testing.T, name string, got, want time.Time) {
+	t.Helper()
+	if absDuration(got.Sub(want)) > 2*time.Second {
+		t.Errorf("%s = %s, 期望 %s", name, got.Format(time.RFC3339), want.Format(time.RFC3339))
+	}
+}
+
+func TestCompleteRefundRollsBackMembership(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name         string
+		chain        int // 叠加购买的订单数
+		refundIndex  int // 退款的订单
+		amount       int // 退款金额，0 表示全额
+		wantRollback time.Duration
+		wantStatus   OrderStatus
+	}{
+		{name: "全额退款", chain: 1, refundIndex: 0, amount: 0, wantRollback: 30 * day, wantStatus: OrderStatusRefunded},
+		{name: "部分退款", chain: 1, refundIndex: 0, amount: 1000, wantRollback: 10 * day, wantStatus: OrderStatusPaid},
+		{name: "叠加链中较早的订单全额退款", chain: 3, refundIndex: 0, amount: 0, wantRollback: 30 * day, wantStatus: OrderStatusRefunded},
+		{name: "叠加链中较早的订单部分退款", chain: 2, refundIndex: 0, amount: 1500, wantRollback: 15 * day, wantStatus: OrderStatusPaid},
+		{name: "叠加链中最后的订单全额退款", chain: 2, refundIndex: 1, amount: 0, wantRollback: 30 * day, wantStatus: OrderStatusRefunded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t, &User{}, &MemberCard{}, &Order{}, &OrderEvent{}, &Refund{}, &CouponRedemption{})
+
+			card := &MemberCard{Name: "月卡", DurationDays: periodDays, Price: 3000, Description: "30天会员", IsActive: true}
+			if err := DB.Create(card).Error; err != nil {
+				t.Fatalf("创建会员卡失败: %v", err)
+			}
+			user := &User{Username: "member", Email: "member@example.com"}
+			if err := DB.Create(user).Error; err != nil {
+				t.Fatalf("创建用户失败: %v", err)
+			}
+
+			orders := paidChain(t, user, card, tt.chain)
+			before := make([]Order, len(orders))
+			for i, o := range orders {
+				if err := DB.First(&before[i], "id = ?", o.ID).Error; err != nil {
+					t.Fatalf("读取订单失败: %v", err)
+				}
+			}
+			var original User
+			if err := DB.First(&original, "id = ?", user.ID).Error; err != nil {
+				t.Fatalf("读取用户失败: %v", err)
+			}
+
+			target := orders[tt.refundIndex]
+			refund, _, err := CreateRefund(target.ID, tt.amount, "测试退款", uuid.New())
+			if err != nil {
+				t.Fatalf("创建退款失败: %v", err)
+			}
+			refund, err = CompleteRefund(refund.ID, "refund-no")
+			if err != nil {
+				t.Fatalf("完成退款失败: %v", err)
+			}
+
+			if got := time.Duration(refund.RollbackSeconds) * time.Second; absDuration(got-tt.wantRollback) > 2*time.Second {
+				t.Errorf("回退时长 = %s, 期望 %s", got, tt.wantRollback)
+			}
+			rollback := time.Duration(refund.RollbackSeconds) * time.Second
+
+			// 重复完成不会再次回退
+			if _, err := CompleteRefund(refund.ID, "refund-no"); err != nil {
+				t.Fatalf("重复完成退款失败: %v", err)
+			}
+
+			var refunded Order
+			if err := DB.First(&refunded, "id = ?", target.ID).Error; err != nil {
+				t.Fatalf("读取订单失败: %v", err)
+			}
+			if refunded.Status != tt.wantStatus {
+				t.Errorf("订单状态 = %s, 期望 %s", refunded.Status, tt.wantStatus)
+			}
+			assertNear(t, "退款订单的时段结束时间", *refunded.PeriodEnd, before[tt.refundIndex].PeriodEnd.Add(-rollback))
+
+			// 之前的订单不变，之后叠加的订单整体前移
+			for i := range orders {
+				if i == tt.refundIndex {
+					continue
+				}
+				var o Order
+				if err := DB.First(&o, "id = ?", orders[i].ID).Error; err != nil {
+					t.Fatalf("读取订单失败: %v", err)
+				}
+				shift := time.Duration(0)
+				if i > tt.refundIndex {
+					shift = rollback
+				}
+				assertNear(t, "订单时段开始时间", *o.PeriodStart, before[i].PeriodStart.Add(-shift))
+				assertNear(t, "订单时段结束时间", *o.PeriodEnd, before[i].PeriodEnd.Add(-shift))
+			}
+
+			var updated User
+			if err := DB.First(&updated, "id = ?", user.ID).Error; err != nil {
+				t.Fatalf("读取用户失败: %v", err)
+			}
+			assertNear(t, "会员到期时间", *updated.MemberExpiry, original.MemberExpiry.Add(-tt.wantRollback))
+		})
+	}
+}
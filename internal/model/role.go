@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"interviewGenius/internal/pkg/rbac"
 )
 
 // Role 角色模型
@@ -72,12 +73,32 @@ func GetRoleList(page, limit int) ([]*Role, int64, error) {
 
 // UpdateRole 更新角色
 func UpdateRole(role *Role) error {
-	return DB.Save(role).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(role).Error; err != nil {
+			return err
+		}
+
+		// 角色名写入了令牌，变更后持有该角色的用户需要刷新令牌
+		return bumpRoleUsersPermVersion(tx, role.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	rbac.Invalidate()
+	return nil
 }
 
 // DeleteRole 删除角色
 func DeleteRole(id uint) error {
+	defer rbac.Invalidate()
+
 	return DB.Transaction(func(tx *gorm.DB) error {
+		// 持有该角色的用户需要刷新令牌
+		if err := bumpRoleUsersPermVersion(tx, id); err != nil {
+			return err
+		}
+
 		// 删除角色与用户的关联
 		if err := tx.Exec("DELETE FROM user_role WHERE role_id = ?", id).Error; err != nil {
 			return err
@@ -88,6 +109,11 @@ func DeleteRole(id uint) error {
 			return err
 		}
 
+		// 吊销归属于该角色的 API Key
+		if err := revokeAPIKeys(tx.Where("role_id = ?", id), false); err != nil {
+			return err
+		}
+
 		// 删除角色
 		if err := tx.Delete(&Role{}, id).Error; err != nil {
 			return err
@@ -99,6 +125,8 @@ func DeleteRole(id uint) error {
 
 // AddPermissionsToRole 为角色添加权限
 func AddPermissionsToRole(roleID uint, permissionIDs []string) error {
+	defer rbac.Invalidate()
+
 	return DB.Transaction(func(tx *gorm.DB) error {
 		var role Role
 		if err := tx.First(&role, roleID).Error; err != nil {
@@ -134,6 +162,8 @@ func GetRolePermissions(roleID uint) ([]*Permission, error) {
 
 // RemovePermissionFromRole 从角色中移除权限
 func RemovePermissionFromRole(roleID uint, permissionID uuid.UUID) error {
+	defer rbac.Invalidate()
+
 	return DB.Transaction(func(tx *gorm.DB) error {
 		var role Role
 		if err := tx.First(&role, roleID).Error; err != nil {
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session 登录会话，ID 与该次登录的刷新令牌家族ID一致
+type Session struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	Device     string     `json:"device" gorm:"size:64"`
+	UserAgent  string     `json:"user_agent" gorm:"size:255"`
+	IP         string     `json:"ip" gorm:"size:45"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"` // 刷新令牌到期时间，每次刷新后延长
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// CreateSession 创建登录会话，设备名称和 User-Agent 超长时按字符截断
+func CreateSession(session *Session) error {
+	session.Device = truncateRunes(session.Device, 64)
+	session.UserAgent = truncateRunes(session.UserAgent, 255)
+	if session.LastSeenAt.IsZero() {
+		session.LastSeenAt = time.Now()
+	}
+	return DB.Create(session).Error
+}
+
+// GetSession 获取会话
+func GetSession(id uuid.UUID) (*Session, error) {
+	var session Session
+	if err := DB.First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetUserSessions 获取用户未吊销且未过期的会话，最近活跃的在前
+func GetUserSessions(userID uuid.UUID) ([]*Session, error) {
+	var sessions []*Session
+	err := DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// TouchSession 更新会话的最近活跃时间和IP
+func TouchSession(id uuid.UUID, ip string) error {
+	return DB.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip":           ip,
+		}).Error
+}
+
+// extendSession 刷新令牌轮换后延长会话有效期
+func extendSession(tx *gorm.DB, id uuid.UUID, expiresAt time.Time) error {
+	return tx.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumns(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// RevokeSession 吊销用户的指定会话及其刷新令牌
+func RevokeSession(userID, id uuid.UUID) error {
+	var session Session
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("会话不存在")
+		}
+		return err
+	}
+	return RevokeRefreshTokenFamily(id)
+}
+
+// revokeSessions 吊销满足条件的会话
+func revokeSessions(tx *gorm.DB, query string, args ...interface{}) error {
+	return tx.Model(&Session{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,230 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SubscriptionStatus 自动续费订阅状态
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusPending   SubscriptionStatus = "pending"   // 等待用户签约
+	SubscriptionStatusActive    SubscriptionStatus = "active"    // 已签约，按周期自动扣款
+	SubscriptionStatusPastDue   SubscriptionStatus = "past_due"  // 续费扣款失败，宽限期内按计划重试
+	SubscriptionStatusCancelled SubscriptionStatus = "cancelled" // 用户取消或解约
+	SubscriptionStatusExpired   SubscriptionStatus = "expired"   // 重试次数用完或会员卡已下架
+)
+
+// liveSubscriptionStatuses 仍在生效的订阅状态，每个用户最多一个
+var liveSubscriptionStatuses = []SubscriptionStatus{
+	SubscriptionStatusPending,
+	SubscriptionStatusActive,
+	SubscriptionStatusPastDue,
+}
+
+var (
+	ErrSubscriptionNotFound = errors.New("订阅不存在")
+	ErrSubscriptionExists   = errors.New("已有生效中的自动续费")
+	ErrSubscriptionChanged  = errors.New("订阅已被其他操作修改")
+)
+
+// Subscription 自动续费订阅，每期扣款生成一个普通订单并按会员卡延长会员有效期
+type Subscription struct {
+	ID                uuid.UUID          `json:"id" gorm:"type:char(36);primaryKey"` // 同时作为商户签约号
+	UserID            uuid.UUID          `json:"user_id" gorm:"type:char(36);not null;index"`
+	CardID            uint               `json:"card_id" gorm:"not null"`
+	Provider          string             `json:"provider" gorm:"size:20;not null"`
+	AgreementNo       string             `json:"agreement_no" gorm:"size:64"` // 支付渠道的协议号
+	Status            SubscriptionStatus `json:"status" gorm:"size:20;not null;index"`
+	CurrentPeriodEnd  *time.Time         `json:"current_period_end"`                    // 最近一期已支付的会员时段结束时间
+	NextChargeAt      *time.Time         `json:"next_charge_at" gorm:"index"`           // 下次扣款或重试时间
+	GraceUntil        *time.Time         `json:"grace_until"`                           // 续费失败时保留会员权益的截止时间
+	CancelAtPeriodEnd bool               `json:"cancel_at_period_end"`                  // 当前周期结束后不再续费
+	RetryCount        int                `json:"retry_count" gorm:"not null"`           // 本期已失败的扣款次数
+	PendingOrderID    *uuid.UUID         `json:"pending_order_id" gorm:"type:char(36)"` // 等待渠道结果的续费订单
+	LastError         string             `json:"last_error" gorm:"size:255"`
+	CancelledAt       *time.Time         `json:"cancelled_at"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+	Card              *MemberCard        `json:"card,omitempty" gorm:"foreignKey:CardID"`
+}
+
+// BeforeCreate 创建前生成UUID
+func (s *Subscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	if s.Status == "" {
+		s.Status = SubscriptionStatusPending
+	}
+	return nil
+}
+
+// CreateSubscription 创建待签约的订阅，用户已有签约成功的订阅时返回 ErrSubscriptionExists
+// 尚未签约的订阅视为放弃，改为已取消；配置要求验证邮箱而用户尚未验证时返回 ErrEmailNotVerified
+func CreateSubscription(sub *Subscription) error {
+	if err := checkUserCanOrder(sub.UserID); err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定用户，避免并发创建多个订阅
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&User{}, "id = ?", sub.UserID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&Subscription{}).
+			Where("user_id = ? AND status IN ?", sub.UserID,
+				[]SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusPastDue}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSubscriptionExists
+		}
+		if err := tx.Model(&Subscription{}).
+			Where("user_id = ? AND status = ?", sub.UserID, SubscriptionStatusPending).
+			Updates(map[string]interface{}{
+				"status":       SubscriptionStatusCancelled,
+				"last_error":   "重新发起签约",
+				"cancelled_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(sub).Error
+	})
+}
+
+// GetSubscriptionByID 根据ID获取订阅
+func GetSubscriptionByID(id uuid.UUID) (*Subscription, error) {
+	var sub Subscription
+	if err := DB.Preload("Card").First(&sub, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetUserSubscriptions 获取用户的订阅列表
+func GetUserSubscriptions(userID uuid.UUID) ([]*Subscription, error) {
+	var subs []*Subscription
+	if err := DB.Preload("Card").Where("user_id = ?", userID).Order("created_at DESC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// GetDueSubscriptions 获取到达扣款或重试时间的订阅，按时间排序
+func GetDueSubscriptions(now time.Time, limit int) ([]*Subscription, error) {
+	var subs []*Subscription
+	if err := DB.Where("status IN ? AND next_charge_at <= ?",
+		[]SubscriptionStatus{SubscriptionStatusActive, SubscriptionStatusPastDue}, now).
+		Order("next_charge_at").Limit(limit).Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// ActivateSubscription 签约成功后激活订阅并立即安排首期扣款，订阅已不是待签约状态时返回 false
+func ActivateSubscription(id uuid.UUID, agreementNo string) (bool, error) {
+	result := DB.Model(&Subscription{}).
+		Where("id = ? AND status = ?", id, SubscriptionStatusPending).
+		Updates(map[string]interface{}{
+			"status":         SubscriptionStatusActive,
+			"agreement_no":   agreementNo,
+			"next_charge_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateSubscription 更新生效中的订阅，订阅已结束时返回 false
+// 各操作只更新各自负责的字段，避免扣款任务覆盖用户同时提交的取消
+func UpdateSubscription(id uuid.UUID, updates map[string]interface{}) (bool, error) {
+	result := DB.Model(&Subscription{}).
+		Where("id = ? AND status IN ?", id, liveSubscriptionStatuses).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// EndSubscription 结束生效中的订阅，status 为取消或过期，订阅已结束时返回 false
+func EndSubscription(id uuid.UUID, status SubscriptionStatus, reason string) (bool, error) {
+	return UpdateSubscription(id, map[string]interface{}{
+		"status":           status,
+		"next_charge_at":   nil,
+		"grace_until":      nil,
+		"pending_order_id": nil,
+		"last_error":       truncateRunes(reason, 255),
+		"cancelled_at":     time.Now(),
+	})
+}
+
+// FinishSubscriptionCharge 续费订单有结果后清除等待中的订单并更新订阅
+// 订阅等待的已不是该订单时返回 false，避免重复处理同一订单
+func FinishSubscriptionCharge(id, orderID uuid.UUID, updates map[string]interface{}) (bool, error) {
+	values := map[string]interface{}{"pending_order_id": nil}
+	for column, value := range updates {
+		values[column] = value
+	}
+	result := DB.Model(&Subscription{}).
+		Where("id = ? AND pending_order_id = ? AND status IN ?", id, orderID, liveSubscriptionStatuses).
+		Updates(values)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateSubscriptionOrder 按会员卡当前价格创建续费订单，并记录为订阅等待结果的订单
+// 订阅已结束、已有等待中的订单或尚未到达扣款时间时返回 ErrSubscriptionChanged，
+// 签约通知和续费任务同时处理同一订阅时，每一期只会扣款一次；用户邮箱未验证时返回 ErrEmailNotVerified
+func CreateSubscriptionOrder(sub *Subscription, card *MemberCard) (*Order, error) {
+	if err := checkUserCanOrder(sub.UserID); err != nil {
+		return nil, err
+	}
+	order := &Order{
+		UserID:         sub.UserID,
+		CardID:         card.ID,
+		Amount:         card.Price,
+		OriginalAmount: card.Price,
+		Provider:       sub.Provider,
+		SubscriptionID: &sub.ID,
+		Status:         OrderStatusCreated,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&Subscription{}).
+			Where("id = ? AND status IN ? AND pending_order_id IS NULL AND next_charge_at <= ?",
+				sub.ID, liveSubscriptionStatuses, time.Now()).
+			Update("pending_order_id", order.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSubscriptionChanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// subscriptionGraceUntil 用户处于续费宽限期时返回宽限期截止时间，否则返回 nil
+func subscriptionGraceUntil(userID uuid.UUID, now time.Time) (*time.Time, error) {
+	var sub Subscription
+	err := DB.Where("user_id = ? AND status = ? AND grace_until > ?", userID, SubscriptionStatusPastDue, now).
+		Order("grace_until DESC").First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sub.GraceUntil, nil
+}
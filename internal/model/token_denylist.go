@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken 已吊销的访问令牌
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"size:36;primaryKey"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation 用户级令牌吊销记录，早于 RevokedAt 签发的令牌全部失效
+type UserTokenRevocation struct {
+	UserID    string    `json:"user_id" gorm:"type:char(36);primaryKey"`
+	RevokedAt time.Time `json:"revoked_at" gorm:"not null"`
+}
+
+// DenylistStore 基于数据库的令牌黑名单存储，适用于多实例部署
+type DenylistStore struct{}
+
+// NewDenylistStore 创建数据库黑名单存储
+func NewDenylistStore() *DenylistStore {
+	return &DenylistStore{}
+}
+
+// Revoke 吊销单个令牌
+func (s *DenylistStore) Revoke(jti string, expiresAt time.Time) error {
+	// 顺带清理已过期的记录
+	if err := DB.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// IsRevoked 判断令牌是否已被吊销
+func (s *DenylistStore) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := DB.Model(&RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, time.Now()).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeUser 吊销用户在指定时间之前签发的全部令牌
+func (s *DenylistStore) RevokeUser(userID string, at time.Time) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at"}),
+	}).Create(&UserTokenRevocation{
+		UserID:    userID,
+		RevokedAt: at,
+	}).Error
+}
+
+// UserRevokedAt 获取用户令牌的吊销时间
+func (s *DenylistStore) UserRevokedAt(userID string) (time.Time, error) {
+	var revocation UserTokenRevocation
+	if err := DB.Where("user_id = ?", userID).First(&revocation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return revocation.RevokedAt, nil
+}
@@ -3,27 +3,46 @@ package model
 import (
 	"errors"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"interviewGenius/internal/pkg/password"
 	"time"
 )
 
 // User 用户模型
 type User struct {
-	ID            uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
-	Username      string     `json:"username" gorm:"size:64;not null;unique"`
-	Password      string     `json:"-" gorm:"size:100;not null"`
-	Email         string     `json:"email" gorm:"size:100;unique"`
-	MemberExpiry  *time.Time `json:"member_expiry"`                    // 会员到期时间
-	LastUseDate   *time.Time `json:"last_use_date"`                    // 记录未买卡用户最后使用日期
-	DailyUseCount int        `json:"daily_use_count" gorm:"default:0"` // 当天已用次数
-	Roles         []*Role    `json:"roles,omitempty" gorm:"many2many:user_role;"`
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Username        string     `json:"username" gorm:"size:64;not null;unique"`
+	Password        string     `json:"-" gorm:"size:255;not null"`
+	Email           string     `json:"email" gorm:"size:100;unique"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`                // 邮箱验证时间，未验证为空
+	MemberExpiry    *time.Time `json:"member_expiry"`                    // 会员到期时间
+	LastUseDate     *time.Time `json:"last_use_date"`                    // 记录未买卡用户最后使用日期
+	DailyUseCount   int        `json:"daily_use_count" gorm:"default:0"` // 当天已用次数
+	PermVersion     int64      `json:"-" gorm:"default:0"`               // 权限版本，角色变更时递增
+	Roles           []*Role    `json:"roles,omitempty" gorm:"many2many:user_role;"`
 }
 
 // CheckPassword 检查密码是否正确
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// 校验成功且哈希的算法或参数已过时，使用当前配置重新计算并保存
+func (u *User) CheckPassword(plain string) bool {
+	ok, needsRehash := password.Verify(plain, u.Password)
+	if !ok {
+		return false
+	}
+
+	if needsRehash {
+		hashed, err := password.Hash(plain)
+		if err == nil {
+			err = DB.Model(&User{}).Where("id = ?", u.ID).UpdateColumn("password", hashed).Error
+		}
+		if err != nil {
+			zap.L().Warn("升级密码哈希失败", zap.String("user_id", u.ID.String()), zap.Error(err))
+		} else {
+			u.Password = hashed
+		}
+	}
+	return true
 }
 
 // BeforeCreate 创建前生成UUID和加密密码
@@ -33,11 +52,11 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 
 	if len(u.Password) > 0 {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		hashedPassword, err := password.Hash(u.Password)
 		if err != nil {
 			return err
 		}
-		u.Password = string(hashedPassword)
+		u.Password = hashedPassword
 	}
 	return nil
 }
@@ -45,11 +64,11 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 // BeforeUpdate 更新前加密密码
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	if tx.Statement.Changed("Password") {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		hashedPassword, err := password.Hash(u.Password)
 		if err != nil {
 			return err
 		}
-		u.Password = string(hashedPassword)
+		u.Password = hashedPassword
 	}
 	return nil
 }
@@ -75,6 +94,18 @@ func GetUserByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail 根据邮箱获取用户
+func GetUserByEmail(email string) (*User, error) {
+	var user User
+	if err := DB.Preload("Roles").Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 // CreateUser 创建用户
 func CreateUser(user *User) error {
 	return DB.Create(user).Error
@@ -85,6 +116,16 @@ func UpdateUser(user *User) error {
 	return DB.Save(user).Error
 }
 
+// UpdateUserPassword 加密并更新用户密码
+// Save 整体保存时 BeforeUpdate 无法判断密码是否变化，修改密码需使用此方法
+func UpdateUserPassword(userID uuid.UUID, plain string) error {
+	hashedPassword, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	return DB.Model(&User{}).Where("id = ?", userID).UpdateColumn("password", hashedPassword).Error
+}
+
 // DeleteUser 删除用户
 func DeleteUser(id uuid.UUID) error {
 	return DB.Delete(&User{}, "id = ?", id).Error
@@ -125,7 +166,7 @@ func AddRolesToUser(userID uuid.UUID, roleIDs []uint) error {
 			return err
 		}
 
-		return nil
+		return bumpUserPermVersion(tx, userID)
 	})
 }
 
@@ -156,10 +197,32 @@ func RemoveRoleFromUser(userID uuid.UUID, roleID uint) error {
 			return err
 		}
 
-		return nil
+		return bumpUserPermVersion(tx, userID)
 	})
 }
 
+// GetUserPermVersion 获取用户当前的权限版本
+func GetUserPermVersion(userID uuid.UUID) (int64, error) {
+	var user User
+	if err := DB.Select("id", "perm_version").First(&user, "id = ?", userID).Error; err != nil {
+		return 0, err
+	}
+	return user.PermVersion, nil
+}
+
+// bumpUserPermVersion 递增用户的权限版本，使已签发的令牌需要刷新
+func bumpUserPermVersion(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&User{}).Where("id = ?", userID).
+		UpdateColumn("perm_version", gorm.Expr("perm_version + ?", 1)).Error
+}
+
+// bumpRoleUsersPermVersion 递增拥有指定角色的全部用户的权限版本
+func bumpRoleUsersPermVersion(tx *gorm.DB, roleID uint) error {
+	subQuery := tx.Model(&UserRole{}).Select("user_id").Where("role_id = ?", roleID)
+	return tx.Model(&User{}).Where("id IN (?)", subQuery).
+		UpdateColumn("perm_version", gorm.Expr("perm_version + ?", 1)).Error
+}
+
 // CheckServiceAccess 检查用户是否可以使用服务
 func CheckServiceAccess(userID uuid.UUID) (bool, error) {
 	var user User
@@ -171,7 +234,10 @@ func CheckServiceAccess(userID uuid.UUID) (bool, error) {
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	// 检查是否是会员
-	isMember := user.MemberExpiry != nil && user.MemberExpiry.After(now)
+	isMember, err := memberActive(&user, now)
+	if err != nil {
+		return false, err
+	}
 
 	// 如果是会员，直接允许访问
 	if isMember {
@@ -210,7 +276,10 @@ func UseService(userID uuid.UUID) error {
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
 	// 检查是否是会员
-	isMember := user.MemberExpiry != nil && user.MemberExpiry.After(now)
+	isMember, err := memberActive(&user, now)
+	if err != nil {
+		return err
+	}
 
 	// 会员用户不消耗使用次数
 	if isMember {
@@ -240,7 +309,26 @@ func IsMember(userID uuid.UUID) (bool, *time.Time, error) {
 	}
 
 	now := time.Now()
-	isMember := user.MemberExpiry != nil && user.MemberExpiry.After(now)
+	if user.MemberExpiry != nil && user.MemberExpiry.After(now) {
+		return true, user.MemberExpiry, nil
+	}
+
+	// 自动续费失败的宽限期内仍是会员，到期时间为宽限期截止时间
+	graceUntil, err := subscriptionGraceUntil(userID, now)
+	if err != nil {
+		return false, nil, err
+	}
+	if graceUntil != nil {
+		return true, graceUntil, nil
+	}
+	return false, user.MemberExpiry, nil
+}
 
-	return isMember, user.MemberExpiry, nil
+// memberActive 会员未到期，或自动续费失败后仍在宽限期内
+func memberActive(user *User, now time.Time) (bool, error) {
+	if user.MemberExpiry != nil && user.MemberExpiry.After(now) {
+		return true, nil
+	}
+	graceUntil, err := subscriptionGraceUntil(user.ID, now)
+	return graceUntil != nil, err
 }
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrActionTokenInvalid 令牌不存在、已使用、已失效或已过期
+	ErrActionTokenInvalid = errors.New("链接无效或已过期")
+)
+
+// UserActionToken 邮箱验证、密码重置等一次性令牌的使用记录
+// 令牌本身为签名的 JWT，这里只记录 jti 保证只能使用一次
+type UserActionToken struct {
+	JTI       string     `json:"jti" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index:idx_action_user_purpose"`
+	Purpose   string     `json:"purpose" gorm:"size:32;not null;index:idx_action_user_purpose"`
+	Email     string     `json:"email" gorm:"size:100"` // 签发时的邮箱，邮箱变更后令牌失效
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateUserActionToken 记录新签发的令牌，同一用户同一用途的旧令牌随之作废
+func CreateUserActionToken(token *UserActionToken) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&UserActionToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// LastUserActionTokenAt 获取用户最近一次签发指定用途令牌的时间
+func LastUserActionTokenAt(userID uuid.UUID, purpose string) (*time.Time, error) {
+	var token UserActionToken
+	err := DB.Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC").
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token.CreatedAt, nil
+}
+
+// ConsumeUserActionToken 使用令牌，每个令牌只能成功使用一次
+func ConsumeUserActionToken(jti, purpose string) (*UserActionToken, error) {
+	var token UserActionToken
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("jti = ? AND purpose = ?", jti, purpose).
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrActionTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if token.UsedAt != nil || now.After(token.ExpiresAt) {
+			return ErrActionTokenInvalid
+		}
+
+		token.UsedAt = &now
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkEmailVerified 标记邮箱已验证，邮箱已变更时不生效
+func MarkEmailVerified(userID uuid.UUID, email string) error {
+	result := DB.Model(&User{}).
+		Where("id = ? AND email = ?", userID, email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrActionTokenInvalid
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOAuthStateInvalid state 不存在、已使用或已过期
+	ErrOAuthStateInvalid = errors.New("登录请求无效或已过期，请重新登录")
+)
+
+// UserIdentity 用户绑定的第三方账号
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	Provider  string    `json:"provider" gorm:"size:32;not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"subject" gorm:"size:191;not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `json:"email" gorm:"size:100"`
+	Name      string    `json:"name" gorm:"size:100"`
+	AvatarURL string    `json:"avatar_url" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthState 第三方登录的 state 与 PKCE 校验参数，回调时一次性使用
+type OAuthState struct {
+	State        string     `json:"state" gorm:"size:64;primaryKey"`
+	Provider     string     `json:"provider" gorm:"size:32;not null"`
+	CodeVerifier string     `json:"-" gorm:"size:64"`
+	UserID       *uuid.UUID `json:"user_id" gorm:"type:char(36)"` // 绑定流程中发起绑定的用户，登录流程为空
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OAuthState) TableName() string {
+	return "oauth_state"
+}
+
+// CreateOAuthState 保存 state，同时清理已过期的记录
+func CreateOAuthState(state *OAuthState) error {
+	if err := DB.Where("expires_at < ?", time.Now()).Delete(&OAuthState{}).Error; err != nil {
+		return err
+	}
+	return DB.Create(state).Error
+}
+
+// ConsumeOAuthState 校验并删除 state，每个 state 只能使用一次
+func ConsumeOAuthState(state, provider string) (*OAuthState, error) {
+	var record OAuthState
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state = ? AND provider = ?", state, provider).
+			First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOAuthStateInvalid
+			}
+			return err
+		}
+
+		if err := tx.Delete(&record).Error; err != nil {
+			return err
+		}
+		if time.Now().After(record.ExpiresAt) {
+			return ErrOAuthStateInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetUserIdentity 根据第三方账号获取绑定记录，未绑定时返回 nil
+func GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	if err := DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户绑定的全部第三方账号
+func GetUserIdentities(userID uuid.UUID) ([]*UserIdentity, error) {
+	var identities []*UserIdentity
+	if err := DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// CreateUserIdentity 绑定第三方账号
+func CreateUserIdentity(identity *UserIdentity) error {
+	return DB.Create(identity).Error
+}
+
+// DeleteUserIdentity 解除绑定
+func DeleteUserIdentity(userID uuid.UUID, provider string) error {
+	result := DB.Where("user_id = ? AND provider = ?", userID, provider).Delete(&UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("未绑定该第三方账号")
+	}
+	return nil
+}
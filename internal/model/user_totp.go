@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserTOTP 用户的TOTP两步验证配置
+type UserTOTP struct {
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:char(36);primaryKey"`
+	Secret      string     `json:"-" gorm:"size:64;not null"`
+	Enabled     bool       `json:"enabled" gorm:"default:false"`
+	LastCounter int64      `json:"-" gorm:"default:0"` // 最近一次使用的时间步，防止验证码重放
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode 两步验证的一次性恢复码
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// GetUserTOTP 获取用户的TOTP配置，未设置时返回 nil
+func GetUserTOTP(userID uuid.UUID) (*UserTOTP, error) {
+	var totp UserTOTP
+	if err := DB.Where("user_id = ?", userID).First(&totp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SaveTOTPSecret 保存待确认的TOTP密钥，覆盖尚未启用的旧密钥
+func SaveTOTPSecret(userID uuid.UUID, secret string) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_counter", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "user_totp.enabled", Value: false}}},
+	}).Create(&UserTOTP{
+		UserID: userID,
+		Secret: secret,
+	}).Error
+}
+
+// EnableTOTP 启用两步验证并替换全部恢复码
+func EnableTOTP(userID uuid.UUID, counter int64, codeHashes []string) error {
+	now := time.Now()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UserTOTP{}).Where("user_id = ? AND enabled = ?", userID, false).Updates(map[string]interface{}{
+			"enabled":      true,
+			"last_counter": counter,
+			"confirmed_at": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("两步验证已启用或尚未开始设置")
+		}
+
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableTOTP 关闭两步验证并删除恢复码
+func DisableTOTP(userID uuid.UUID) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&UserTOTP{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPCounter 记录已使用的时间步，同一时间步或更早的验证码不能再次使用
+func UseTOTPCounter(userID uuid.UUID, counter int64) (bool, error) {
+	result := DB.Model(&UserTOTP{}).
+		Where("user_id = ? AND last_counter < ?", userID, counter).
+		Update("last_counter", counter)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode 使用一次性恢复码
+func UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	result := DB.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// replaceRecoveryCodes 替换用户的全部恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
@@ -0,0 +1,95 @@
+package denylist
+
+import (
+	"sync"
+	"time"
+)
+
+// Store 访问令牌黑名单存储
+type Store interface {
+	// Revoke 吊销单个令牌，直到令牌自然过期
+	Revoke(jti string, expiresAt time.Time) error
+	// IsRevoked 判断令牌是否已被吊销
+	IsRevoked(jti string) (bool, error)
+	// RevokeUser 吊销用户在指定时间之前签发的全部令牌
+	RevokeUser(userID string, at time.Time) error
+	// UserRevokedAt 获取用户令牌的吊销时间，未吊销时返回零值
+	UserRevokedAt(userID string) (time.Time, error)
+}
+
+var (
+	mu    sync.RWMutex
+	store Store = NewMemoryStore()
+)
+
+// SetStore 设置全局黑名单存储
+func SetStore(s Store) {
+	mu.Lock()
+	store = s
+	mu.Unlock()
+}
+
+// Default 获取全局黑名单存储
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// MemoryStore 进程内黑名单存储，适用于单实例部署
+type MemoryStore struct {
+	mu    sync.Mutex
+	jtis  map[string]time.Time
+	users map[string]time.Time
+}
+
+// NewMemoryStore 创建进程内黑名单存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jtis:  make(map[string]time.Time),
+		users: make(map[string]time.Time),
+	}
+}
+
+// Revoke 吊销单个令牌
+func (s *MemoryStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 顺带清理已过期的记录
+	now := time.Now()
+	for k, exp := range s.jtis {
+		if exp.Before(now) {
+			delete(s.jtis, k)
+		}
+	}
+
+	s.jtis[jti] = expiresAt
+	return nil
+}
+
+// IsRevoked 判断令牌是否已被吊销
+func (s *MemoryStore) IsRevoked(jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, ok := s.jtis[jti]
+	return ok && exp.After(time.Now()), nil
+}
+
+// RevokeUser 吊销用户在指定时间之前签发的全部令牌
+func (s *MemoryStore) RevokeUser(userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID] = at
+	return nil
+}
+
+// UserRevokedAt 获取用户令牌的吊销时间
+func (s *MemoryStore) UserRevokedAt(userID string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[userID], nil
+}
@@ -0,0 +1,181 @@
+package loginguard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Policy 登录失败的退避与锁定策略
+type Policy struct {
+	FreeAttempts int           // 不触发退避的失败次数
+	MaxFailures  int           // 达到后锁定
+	BaseDelay    time.Duration // 首次退避时长，之后每次失败翻倍
+	MaxDelay     time.Duration // 退避时长上限
+	LockDuration time.Duration // 锁定时长
+	Window       time.Duration // 距上次失败超过该时长后重新计数
+}
+
+// BlockFor 根据累计失败次数计算需要等待的时长
+func (p Policy) BlockFor(failures int) (time.Duration, bool) {
+	if p.MaxFailures > 0 && failures >= p.MaxFailures {
+		return p.LockDuration, true
+	}
+	if failures <= p.FreeAttempts {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay, false
+		}
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// Attempt 登录失败记录
+type Attempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	BlockedUntil  time.Time
+}
+
+// Store 登录失败记录存储
+type Store interface {
+	// Get 获取记录，不存在时返回零值
+	Get(key string) (Attempt, error)
+	// Incr 原子地累加失败次数，距上次失败超过 window 时从1重新计数
+	Incr(key string, now time.Time, window time.Duration) (Attempt, error)
+	// Block 设置解除限制的时间
+	Block(key string, until time.Time) error
+	// Reset 清除记录
+	Reset(key string) error
+}
+
+// BlockedError 登录被限制
+type BlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool // 是否为锁定（而非退避）
+}
+
+func (e *BlockedError) Error() string {
+	seconds := int(e.RetryAfter.Round(time.Second).Seconds())
+	if e.Locked {
+		return fmt.Sprintf("登录失败次数过多，账户已临时锁定，请%d秒后重试", seconds)
+	}
+	return fmt.Sprintf("登录尝试过于频繁，请%d秒后重试", seconds)
+}
+
+var (
+	mu    sync.RWMutex
+	store Store = NewMemoryStore()
+)
+
+// SetStore 设置全局存储
+func SetStore(s Store) {
+	mu.Lock()
+	store = s
+	mu.Unlock()
+}
+
+// Default 获取全局存储
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// Check 检查键当前是否处于限制中
+func Check(key string, policy Policy) error {
+	attempt, err := Default().Get(key)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if attempt.BlockedUntil.After(now) {
+		_, locked := policy.BlockFor(attempt.Failures)
+		return &BlockedError{RetryAfter: attempt.BlockedUntil.Sub(now), Locked: locked}
+	}
+	return nil
+}
+
+// Fail 记录一次失败，并按策略设置退避或锁定
+func Fail(key string, policy Policy) error {
+	now := time.Now()
+	attempt, err := Default().Incr(key, now, policy.Window)
+	if err != nil {
+		return err
+	}
+
+	delay, _ := policy.BlockFor(attempt.Failures)
+	if delay <= 0 {
+		return nil
+	}
+	return Default().Block(key, now.Add(delay))
+}
+
+// Reset 清除键的失败记录
+func Reset(key string) error {
+	return Default().Reset(key)
+}
+
+// MemoryStore 进程内存储，适用于单实例部署
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]Attempt
+}
+
+// NewMemoryStore 创建进程内存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]Attempt)}
+}
+
+// Get 获取记录
+func (s *MemoryStore) Get(key string) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[key], nil
+}
+
+// Incr 累加失败次数
+func (s *MemoryStore) Incr(key string, now time.Time, window time.Duration) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	if window > 0 && now.Sub(attempt.LastFailureAt) > window && attempt.BlockedUntil.Before(now) {
+		attempt = Attempt{}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	return attempt, nil
+}
+
+// Block 设置解除限制的时间
+func (s *MemoryStore) Block(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	attempt.BlockedUntil = until
+	s.attempts[key] = attempt
+	return nil
+}
+
+// Reset 清除记录
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// LogMailer 不实际发送邮件，开发和测试环境使用
+// Dir 为空时写入日志，否则每封邮件保存为 Dir 下的 .eml 文件
+type LogMailer struct {
+	Dir  string
+	From string
+}
+
+// NewLogMailer 创建日志邮件发送器
+func NewLogMailer(dir string) *LogMailer {
+	return &LogMailer{Dir: dir, From: "noreply@localhost"}
+}
+
+// Send 记录邮件
+func (m *LogMailer) Send(msg *Message) error {
+	if m.Dir == "" {
+		zap.L().Info("邮件（未发送）",
+			zap.String("to", msg.To),
+			zap.String("subject", msg.Subject),
+			zap.String("body", msg.Body),
+		)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s_%s.eml", time.Now().Format("20060102150405"), recipient, uuid.NewString()[:8])
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMessage(m.From, msg), 0o600)
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import "sync"
+
+// Message 邮件内容
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+var (
+	mu      sync.RWMutex
+	current Mailer = NewLogMailer("")
+)
+
+// SetMailer 替换全局邮件发送器
+func SetMailer(m Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = m
+}
+
+// Default 获取全局邮件发送器，默认只写日志
+func Default() Mailer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
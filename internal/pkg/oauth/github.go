@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// GitHubProvider GitHub 登录
+type GitHubProvider struct {
+	cfg Config
+}
+
+// NewGitHubProvider 创建 GitHub 提供方，未配置的端点使用 GitHub 默认地址
+func NewGitHubProvider(cfg Config) *GitHubProvider {
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = "https://github.com/login/oauth/authorize"
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = "https://github.com/login/oauth/access_token"
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = "https://api.github.com/user"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+	return &GitHubProvider{cfg: cfg}
+}
+
+// Name 提供方名称
+func (p *GitHubProvider) Name() string {
+	return p.cfg.Name
+}
+
+// SupportsPKCE 是否支持 PKCE
+func (p *GitHubProvider) SupportsPKCE() bool {
+	return true
+}
+
+// AuthCodeURL 生成授权地址
+func (p *GitHubProvider) AuthCodeURL(state, codeChallenge string) string {
+	oidc := &OIDCProvider{cfg: p.cfg}
+	return oidc.AuthCodeURL(state, codeChallenge)
+}
+
+// Exchange 使用授权码换取访问令牌并获取用户信息
+// GitHub 用户的邮箱可能不公开，从邮箱列表中取已验证的主邮箱
+func (p *GitHubProvider) Exchange(ctx context.Context, code, codeVerifier string) (*UserInfo, error) {
+	accessToken, err := exchangeCode(ctx, p.cfg, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.get(ctx, p.cfg.UserInfoURL, accessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, errors.New("GitHub 用户信息缺少 id")
+	}
+
+	info := &UserInfo{
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if info.Name == "" {
+		info.Name = user.Login
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, p.cfg.UserInfoURL+"/emails", accessToken, &emails); err == nil {
+		for _, email := range emails {
+			if email.Primary {
+				info.Email = email.Email
+				info.EmailVerified = email.Verified
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// get 调用 GitHub API
+func (p *GitHubProvider) get(ctx context.Context, url, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return doJSON(req, out)
+}
@@ -0,0 +1,170 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// MockServer 本地模拟的 OIDC 提供方，用于测试和本地联调
+// 授权端点不做交互，直接以 User 身份签发授权码并重定向回 redirect_uri
+//
+//	srv := httptest.NewServer(nil)
+//	mock := oauth.NewMockServer(srv.URL, oauth.UserInfo{Subject: "u1", Email: "a@example.com", EmailVerified: true})
+//	srv.Config.Handler = mock
+type MockServer struct {
+	Issuer string
+	User   UserInfo
+
+	mu     sync.Mutex
+	codes  map[string]mockCode
+	tokens map[string]UserInfo
+}
+
+// mockCode 已签发的授权码
+type mockCode struct {
+	user          UserInfo
+	redirectURI   string
+	codeChallenge string
+}
+
+// NewMockServer 创建模拟提供方，issuer 为其对外访问地址
+func NewMockServer(issuer string, user UserInfo) *MockServer {
+	return &MockServer{
+		Issuer: strings.TrimSuffix(issuer, "/"),
+		User:   user,
+		codes:  make(map[string]mockCode),
+		tokens: make(map[string]UserInfo),
+	}
+}
+
+// ServeHTTP 处理发现、授权、令牌和用户信息请求
+func (m *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.Issuer,
+			"authorization_endpoint": m.Issuer + "/authorize",
+			"token_endpoint":         m.Issuer + "/token",
+			"userinfo_endpoint":      m.Issuer + "/userinfo",
+		})
+	case "/authorize":
+		m.authorize(w, r)
+	case "/token":
+		m.token(w, r)
+	case "/userinfo":
+		m.userinfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize 签发授权码，login_hint 参数可覆盖模拟用户的 sub
+func (m *MockServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if redirectURI == "" || query.Get("response_type") != "code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	user := m.User
+	if hint := query.Get("login_hint"); hint != "" {
+		user.Subject = hint
+	}
+
+	code, err := randomString(16)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	m.mu.Lock()
+	m.codes[code] = mockCode{
+		user:          user,
+		redirectURI:   redirectURI,
+		codeChallenge: query.Get("code_challenge"),
+	}
+	m.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	target.RawQuery = params.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token 校验授权码、redirect_uri 和 PKCE 后签发访问令牌
+func (m *MockServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	code, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if code.codeChallenge != "" && ChallengeS256(r.PostForm.Get("code_verifier")) != code.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE 校验失败"})
+		return
+	}
+
+	accessToken, err := randomString(16)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	m.mu.Lock()
+	m.tokens[accessToken] = code.user
+	m.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+// userinfo 返回访问令牌对应的用户信息
+func (m *MockServer) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	m.mu.Lock()
+	user, ok := m.tokens[accessToken]
+	m.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":            user.Subject,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"name":           user.Name,
+		"picture":        user.AvatarURL,
+	})
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
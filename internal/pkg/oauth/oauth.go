@@ -0,0 +1,145 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserInfo 第三方账号信息
+type UserInfo struct {
+	Subject       string // 第三方平台内的唯一标识
+	Email         string
+	EmailVerified bool // 第三方平台确认过该邮箱
+	Name          string
+	AvatarURL     string
+}
+
+// Provider 第三方登录提供方
+type Provider interface {
+	// Name 提供方名称，对应路由中的 :provider
+	Name() string
+	// AuthCodeURL 生成授权地址，codeChallenge 为空时不使用 PKCE
+	AuthCodeURL(state, codeChallenge string) string
+	// Exchange 使用授权码换取令牌并获取用户信息
+	Exchange(ctx context.Context, code, codeVerifier string) (*UserInfo, error)
+	// SupportsPKCE 是否支持 PKCE
+	SupportsPKCE() bool
+}
+
+// Config 提供方配置
+type Config struct {
+	Name         string
+	Type         string // oidc / github / wechat
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Issuer       string // oidc 发现地址的前缀，配置后自动获取以下端点
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+}
+
+// httpClient 访问第三方平台使用的客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	mu        sync.RWMutex
+	providers = make(map[string]Provider)
+)
+
+// NewProvider 根据配置创建提供方
+func NewProvider(ctx context.Context, cfg Config) (Provider, error) {
+	switch cfg.Type {
+	case "oidc", "":
+		return NewOIDCProvider(ctx, cfg)
+	case "github":
+		return NewGitHubProvider(cfg), nil
+	case "wechat":
+		return NewWeChatProvider(cfg), nil
+	}
+	return nil, fmt.Errorf("不支持的第三方登录类型: %s", cfg.Type)
+}
+
+// Register 注册提供方，同名提供方会被替换
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get 获取提供方
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 获取全部已注册的提供方名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateState 生成随机的 state 参数
+func GenerateState() (string, error) {
+	return randomString(32)
+}
+
+// GenerateVerifier 生成 PKCE code_verifier
+func GenerateVerifier() (string, error) {
+	return randomString(32)
+}
+
+// ChallengeS256 计算 PKCE S256 code_challenge
+func ChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString 生成 base64url 编码的随机字符串
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// doJSON 发送请求并解析 JSON 响应
+func doJSON(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("请求 %s 失败: %s %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
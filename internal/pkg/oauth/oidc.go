@@ -0,0 +1,169 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OIDCProvider 通用的 OAuth2 / OpenID Connect 提供方
+// 使用授权码模式和 PKCE，通过 userinfo 端点获取用户信息
+type OIDCProvider struct {
+	cfg Config
+}
+
+// discoveryDocument OIDC 发现文档
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// tokenResponse 令牌端点响应
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewOIDCProvider 创建 OIDC 提供方，配置了 Issuer 时通过发现文档补全端点
+func NewOIDCProvider(ctx context.Context, cfg Config) (*OIDCProvider, error) {
+	if cfg.Issuer != "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		discoveryURL := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc discoveryDocument
+		if err := doJSON(req, &doc); err != nil {
+			return nil, err
+		}
+		if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+			return nil, errors.New("发现文档中的 issuer 与配置不一致")
+		}
+
+		if cfg.AuthURL == "" {
+			cfg.AuthURL = doc.AuthorizationEndpoint
+		}
+		if cfg.TokenURL == "" {
+			cfg.TokenURL = doc.TokenEndpoint
+		}
+		if cfg.UserInfoURL == "" {
+			cfg.UserInfoURL = doc.UserinfoEndpoint
+		}
+	}
+
+	if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+		return nil, errors.New("缺少授权、令牌或用户信息端点")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{cfg: cfg}, nil
+}
+
+// Name 提供方名称
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// SupportsPKCE 是否支持 PKCE
+func (p *OIDCProvider) SupportsPKCE() bool {
+	return true
+}
+
+// AuthCodeURL 生成授权地址
+func (p *OIDCProvider) AuthCodeURL(state, codeChallenge string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	return appendQuery(p.cfg.AuthURL, params)
+}
+
+// Exchange 使用授权码换取访问令牌并获取用户信息
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier string) (*UserInfo, error) {
+	accessToken, err := exchangeCode(ctx, p.cfg, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var claims struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := doJSON(req, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("用户信息缺少 sub")
+	}
+
+	return &UserInfo{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+// exchangeCode 标准 OAuth2 授权码换取访问令牌
+func exchangeCode(ctx context.Context, cfg Config, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("client_id", cfg.ClientID)
+	form.Set("client_secret", cfg.ClientSecret)
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token tokenResponse
+	if err := doJSON(req, &token); err != nil {
+		return "", err
+	}
+	if token.Error != "" {
+		return "", errors.New(token.Error + ": " + token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("令牌响应缺少 access_token")
+	}
+	return token.AccessToken, nil
+}
+
+// appendQuery 将参数附加到地址
+func appendQuery(rawURL string, params url.Values) string {
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + params.Encode()
+	}
+	return rawURL + "?" + params.Encode()
+}
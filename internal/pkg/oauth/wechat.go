@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// WeChatProvider 微信开放平台网站应用扫码登录
+// 微信不支持 PKCE，也不提供邮箱；有 unionid 时以 unionid 作为唯一标识
+type WeChatProvider struct {
+	cfg Config
+}
+
+// wechatError 微信接口错误
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// NewWeChatProvider 创建微信提供方，未配置的端点使用微信默认地址
+func NewWeChatProvider(cfg Config) *WeChatProvider {
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = "https://open.weixin.qq.com/connect/qrconnect"
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = "https://api.weixin.qq.com/sns/oauth2/access_token"
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = "https://api.weixin.qq.com/sns/userinfo"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"snsapi_login"}
+	}
+	return &WeChatProvider{cfg: cfg}
+}
+
+// Name 提供方名称
+func (p *WeChatProvider) Name() string {
+	return p.cfg.Name
+}
+
+// SupportsPKCE 是否支持 PKCE
+func (p *WeChatProvider) SupportsPKCE() bool {
+	return false
+}
+
+// AuthCodeURL 生成授权地址，微信要求参数按固定顺序并以 #wechat_redirect 结尾
+func (p *WeChatProvider) AuthCodeURL(state, _ string) string {
+	return fmt.Sprintf("%s?appid=%s&redirect_uri=%s&response_type=code&scope=%s&state=%s#wechat_redirect",
+		p.cfg.AuthURL,
+		url.QueryEscape(p.cfg.ClientID),
+		url.QueryEscape(p.cfg.RedirectURL),
+		url.QueryEscape(p.cfg.Scopes[0]),
+		url.QueryEscape(state),
+	)
+}
+
+// Exchange 使用授权码换取访问令牌并获取用户信息
+func (p *WeChatProvider) Exchange(ctx context.Context, code, _ string) (*UserInfo, error) {
+	params := url.Values{}
+	params.Set("appid", p.cfg.ClientID)
+	params.Set("secret", p.cfg.ClientSecret)
+	params.Set("code", code)
+	params.Set("grant_type", "authorization_code")
+
+	var token struct {
+		wechatError
+		AccessToken string `json:"access_token"`
+		OpenID      string `json:"openid"`
+		UnionID     string `json:"unionid"`
+	}
+	if err := p.get(ctx, appendQuery(p.cfg.TokenURL, params), &token); err != nil {
+		return nil, err
+	}
+	if token.ErrCode != 0 {
+		return nil, fmt.Errorf("微信授权失败: %d %s", token.ErrCode, token.ErrMsg)
+	}
+	if token.OpenID == "" {
+		return nil, errors.New("微信授权响应缺少 openid")
+	}
+
+	params = url.Values{}
+	params.Set("access_token", token.AccessToken)
+	params.Set("openid", token.OpenID)
+
+	var user struct {
+		wechatError
+		Nickname   string `json:"nickname"`
+		HeadImgURL string `json:"headimgurl"`
+		UnionID    string `json:"unionid"`
+	}
+	if err := p.get(ctx, appendQuery(p.cfg.UserInfoURL, params), &user); err != nil {
+		return nil, err
+	}
+	if user.ErrCode != 0 {
+		return nil, fmt.Errorf("获取微信用户信息失败: %d %s", user.ErrCode, user.ErrMsg)
+	}
+
+	subject := token.OpenID
+	if user.UnionID != "" {
+		subject = user.UnionID
+	} else if token.UnionID != "" {
+		subject = token.UnionID
+	}
+
+	return &UserInfo{
+		Subject:   subject,
+		Name:      user.Nickname,
+		AvatarURL: user.HeadImgURL,
+	}, nil
+}
+
+// get 调用微信接口
+func (p *WeChatProvider) get(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(req, out)
+}
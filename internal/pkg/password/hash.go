@@ -0,0 +1,160 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// AlgorithmBcrypt bcrypt 哈希
+	AlgorithmBcrypt = "bcrypt"
+	// AlgorithmArgon2id Argon2id 哈希
+	AlgorithmArgon2id = "argon2id"
+)
+
+// HashConfig 密码哈希配置
+type HashConfig struct {
+	Algorithm         string // bcrypt / argon2id
+	BcryptCost        int
+	Argon2Memory      uint32 // 内存（KiB）
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// DefaultHashConfig 默认哈希配置，与早期版本一致使用 bcrypt
+var DefaultHashConfig = HashConfig{
+	Algorithm:         AlgorithmBcrypt,
+	BcryptCost:        bcrypt.DefaultCost,
+	Argon2Memory:      64 * 1024,
+	Argon2Iterations:  3,
+	Argon2Parallelism: 2,
+}
+
+var (
+	mu         sync.RWMutex
+	hashConfig = DefaultHashConfig
+)
+
+// SetHashConfig 设置全局哈希配置，未设置的参数使用默认值
+func SetHashConfig(cfg HashConfig) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = DefaultHashConfig.Algorithm
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		cfg.BcryptCost = DefaultHashConfig.BcryptCost
+	}
+	if cfg.Argon2Memory == 0 {
+		cfg.Argon2Memory = DefaultHashConfig.Argon2Memory
+	}
+	if cfg.Argon2Iterations == 0 {
+		cfg.Argon2Iterations = DefaultHashConfig.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism == 0 {
+		cfg.Argon2Parallelism = DefaultHashConfig.Argon2Parallelism
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	hashConfig = cfg
+}
+
+// currentHashConfig 获取当前哈希配置
+func currentHashConfig() HashConfig {
+	mu.RLock()
+	defer mu.RUnlock()
+	return hashConfig
+}
+
+// Hash 使用当前配置的算法计算密码哈希
+func Hash(plain string) (string, error) {
+	cfg := currentHashConfig()
+	if cfg.Algorithm == AlgorithmArgon2id {
+		return hashArgon2id(plain, cfg)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), cfg.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验密码，needsRehash 表示哈希的算法或参数与当前配置不一致，应在校验成功后重新计算
+func Verify(plain, hashed string) (ok bool, needsRehash bool) {
+	cfg := currentHashConfig()
+
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hashed)
+		if err != nil {
+			return false, false
+		}
+		actual := argon2.IDKey([]byte(plain), salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return false, false
+		}
+		return true, cfg.Algorithm != AlgorithmArgon2id ||
+			params.Argon2Memory != cfg.Argon2Memory ||
+			params.Argon2Iterations != cfg.Argon2Iterations ||
+			params.Argon2Parallelism != cfg.Argon2Parallelism
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return true, cfg.Algorithm != AlgorithmBcrypt || err != nil || cost != cfg.BcryptCost
+}
+
+// hashArgon2id 计算 Argon2id 哈希，使用 PHC 字符串格式保存参数和盐
+func hashArgon2id(plain string, cfg HashConfig) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, cfg.Argon2Iterations, cfg.Argon2Memory, cfg.Argon2Parallelism, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		cfg.Argon2Memory,
+		cfg.Argon2Iterations,
+		cfg.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id 解析 Argon2id 哈希
+func decodeArgon2id(hashed string) (HashConfig, []byte, []byte, error) {
+	var params HashConfig
+
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("无效的 argon2id 哈希")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("不支持的 argon2 版本")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Iterations, &params.Argon2Parallelism); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,203 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var embeddedCommonPasswords string
+
+// Policy 密码策略
+type Policy struct {
+	MinLength        int
+	MaxLength        int
+	MinEntropy       float64 // 估算熵的下限（位）
+	MinClasses       int     // 至少包含的字符类别数（大写、小写、数字、符号）
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	CheckCommon      bool   // 拒绝常见或已泄露的密码
+	CommonListFile   string // 额外的弱密码列表文件，每行一个
+	DisallowUserInfo bool   // 不能包含用户名或邮箱前缀
+}
+
+// FieldError 字段校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PolicyError 密码不符合策略
+type PolicyError struct {
+	Errors []FieldError
+}
+
+// Error 实现 error 接口
+func (e *PolicyError) Error() string {
+	if len(e.Errors) == 0 {
+		return "密码不符合要求"
+	}
+	return e.Errors[0].Message
+}
+
+// Validate 按策略校验密码，field 为请求中的字段名，通过时返回 nil
+func (p Policy) Validate(field, plain, username, email string) []FieldError {
+	var errs []FieldError
+	add := func(code, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := len([]rune(plain))
+	if p.MinLength > 0 && length < p.MinLength {
+		add("too_short", "密码长度不能少于%d位", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add("too_long", "密码长度不能超过%d位", p.MaxLength)
+	}
+	// bcrypt 只使用前72字节
+	if len(plain) > 72 {
+		add("too_long", "密码不能超过72字节")
+	}
+
+	upper, lower, digit, symbol := classes(plain)
+	if p.RequireUpper && !upper {
+		add("missing_upper", "密码必须包含大写字母")
+	}
+	if p.RequireLower && !lower {
+		add("missing_lower", "密码必须包含小写字母")
+	}
+	if p.RequireDigit && !digit {
+		add("missing_digit", "密码必须包含数字")
+	}
+	if p.RequireSymbol && !symbol {
+		add("missing_symbol", "密码必须包含符号")
+	}
+	if p.MinClasses > 0 && countTrue(upper, lower, digit, symbol) < p.MinClasses {
+		add("too_few_classes", "密码至少需要包含大写字母、小写字母、数字、符号中的%d类", p.MinClasses)
+	}
+
+	if p.MinEntropy > 0 && Entropy(plain) < p.MinEntropy {
+		add("low_entropy", "密码过于简单，请使用更长或更复杂的密码")
+	}
+
+	lowered := strings.ToLower(plain)
+	if p.CheckCommon && isCommon(lowered, p.CommonListFile) {
+		add("common", "密码过于常见或已出现在泄露列表中")
+	}
+
+	if p.DisallowUserInfo {
+		if name := strings.ToLower(username); len(name) >= 3 && strings.Contains(lowered, name) {
+			add("contains_username", "密码不能包含用户名")
+		}
+		local := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+		if len(local) >= 3 && strings.Contains(lowered, local) {
+			add("contains_email", "密码不能包含邮箱")
+		}
+	}
+
+	return errs
+}
+
+// Entropy 估算密码熵（位）：长度乘以所用字符类别合计大小的对数，连续重复的字符不计入长度
+func Entropy(plain string) float64 {
+	upper, lower, digit, symbol := classes(plain)
+
+	pool := 0
+	if upper {
+		pool += 26
+	}
+	if lower {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	length := 0
+	var prev rune
+	for i, r := range plain {
+		if i == 0 || r != prev {
+			length++
+		}
+		prev = r
+	}
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// classes 判断密码包含的字符类别
+func classes(plain string) (upper, lower, digit, symbol bool) {
+	for _, r := range plain {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	return
+}
+
+// countTrue 统计为 true 的个数
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	commonOnce sync.Once
+	commonSet  map[string]struct{}
+)
+
+// isCommon 判断是否为弱密码，内置列表与额外列表文件在首次使用时加载
+func isCommon(lowered, extraFile string) bool {
+	commonOnce.Do(func() {
+		commonSet = make(map[string]struct{})
+		loadCommon(strings.NewReader(embeddedCommonPasswords))
+		if extraFile != "" {
+			if f, err := os.Open(extraFile); err == nil {
+				loadCommon(f)
+				f.Close()
+			}
+		}
+	})
+
+	_, ok := commonSet[lowered]
+	return ok
+}
+
+// loadCommon 读取弱密码列表，忽略空行和 # 开头的注释
+func loadCommon(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commonSet[strings.ToLower(line)] = struct{}{}
+	}
+}
@@ -0,0 +1,62 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// AgreementStatus 代扣协议状态
+type AgreementStatus string
+
+const (
+	AgreementStatusPending    AgreementStatus = "pending"    // 等待用户签约
+	AgreementStatusActive     AgreementStatus = "active"     // 已签约，可以代扣
+	AgreementStatusTerminated AgreementStatus = "terminated" // 已解约或用户拒绝签约
+)
+
+// ErrAgreementNotFound 支付渠道中不存在该协议
+var ErrAgreementNotFound = errors.New("代扣协议不存在")
+
+// SignRequest 发起签约请求
+type SignRequest struct {
+	ExternalAgreementNo string // 商户签约号，同一订阅重试时保持不变
+	Subject             string // 签约页面展示的商品名称
+	Amount              int    // 每期扣款金额（单位：分），仅用于展示
+	PeriodDays          int    // 扣款周期（天）
+}
+
+// SignResult 发起签约结果
+type SignResult struct {
+	SignURL string // 用户签约页面地址
+}
+
+// Agreement 签约通知或查询到的协议
+type Agreement struct {
+	ExternalAgreementNo string
+	AgreementNo         string // 支付渠道的协议号，签约成功后才有
+	Status              AgreementStatus
+}
+
+// ChargeRequest 按协议扣款请求
+type ChargeRequest struct {
+	OutTradeNo  string // 商户订单号
+	AgreementNo string
+	Amount      int // 金额（单位：分）
+	Subject     string
+}
+
+// AgreementProvider 支持签约代扣的渠道，用于自动续费
+// 扣款结果可能是处理中，之后通过 Provider.Query 或支付通知获取最终状态
+type AgreementProvider interface {
+	// SignAgreement 发起签约，返回用户签约页面地址
+	SignAgreement(ctx context.Context, req *SignRequest) (*SignResult, error)
+	// VerifyAgreementNotify 校验签约、解约通知，应答使用 Provider.AckNotify
+	VerifyAgreementNotify(header http.Header, body []byte) (*Agreement, error)
+	// QueryAgreement 按商户签约号查询协议，不存在时返回 ErrAgreementNotFound
+	QueryAgreement(ctx context.Context, externalAgreementNo string) (*Agreement, error)
+	// ChargeAgreement 按协议扣款，同一商户订单号重复扣款时返回已有交易
+	ChargeAgreement(ctx context.Context, req *ChargeRequest) (*Trade, error)
+	// TerminateAgreement 解约，协议已解约时不返回错误
+	TerminateAgreement(ctx context.Context, agreementNo string) error
+}
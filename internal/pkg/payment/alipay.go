@@ -0,0 +1,303 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"github.com/smartwalle/alipay/v3"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AlipayConfig 支付宝配置，配置了应用公钥证书时使用公钥证书模式，否则使用普通公钥模式
+type AlipayConfig struct {
+	AppID        string
+	PrivateKey   string // 应用私钥
+	AliPublicKey string // 公钥模式：支付宝公钥
+
+	AppPublicCert    string // 证书模式：应用公钥证书内容
+	AlipayRootCert   string // 证书模式：支付宝根证书内容
+	AlipayPublicCert string // 证书模式：支付宝公钥证书内容
+
+	NotifyURL    string
+	ReturnURL    string
+	IsProduction bool
+}
+
+// CertMode 是否使用公钥证书模式
+func (c *AlipayConfig) CertMode() bool {
+	return c.AppPublicCert != ""
+}
+
+// placeholderMarkers 配置模板和示例中应用ID的占位内容
+var placeholderMarkers = []string{"您的", "你的", "your", "placeholder", "changeme", "xxx"}
+
+// placeholderSecrets 配置模板和示例中密钥、证书的占位值。密钥和证书是随机的
+// base64 内容，只做精确比较，子串匹配会误判真实密钥
+var placeholderSecrets = map[string]bool{
+	"您的应用私钥":    true,
+	"应用公钥证书内容":  true,
+	"支付宝根证书内容":  true,
+	"支付宝公钥证书内容": true,
+}
+
+// CheckPlaceholder 检查应用ID和密钥是否仍是占位内容，生产环境启动前调用
+func (c *AlipayConfig) CheckPlaceholder() error {
+	appID := strings.ToLower(strings.TrimSpace(c.AppID))
+	if appID == "" {
+		return fmt.Errorf("支付宝配置 appID 为空")
+	}
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(appID, marker) {
+			return fmt.Errorf("支付宝配置 appID 疑似占位内容")
+		}
+	}
+
+	type field struct{ name, value string }
+	fields := []field{{"privateKey", c.PrivateKey}}
+	if c.CertMode() {
+		fields = append(fields,
+			field{"appPublicCert", c.AppPublicCert},
+			field{"alipayRootCert", c.AlipayRootCert},
+			field{"alipayPublicCert", c.AlipayPublicCert})
+	} else {
+		fields = append(fields, field{"alipayPublicKey", c.AliPublicKey})
+	}
+
+	for _, f := range fields {
+		value := strings.TrimSpace(f.value)
+		if value == "" {
+			return fmt.Errorf("支付宝配置 %s 为空", f.name)
+		}
+		if placeholderSecrets[value] {
+			return fmt.Errorf("支付宝配置 %s 疑似占位内容", f.name)
+		}
+	}
+	return nil
+}
+
+// AlipayService 支付宝电脑网站支付
+type AlipayService struct {
+	client *alipay.Client
+	config *AlipayConfig
+}
+
+func NewAlipayService(config *AlipayConfig) (*AlipayService, error) {
+	client, err := alipay.New(config.AppID, config.PrivateKey, config.IsProduction)
+	if err != nil {
+		return nil, fmt.Errorf("创建支付宝客户端失败: %v", err)
+	}
+
+	if config.CertMode() {
+		if err := client.LoadAppPublicCert(config.AppPublicCert); err != nil {
+			return nil, fmt.Errorf("加载应用公钥证书失败: %v", err)
+		}
+		if err := client.LoadAliPayRootCert(config.AlipayRootCert); err != nil {
+			return nil, fmt.Errorf("加载支付宝根证书失败: %v", err)
+		}
+		if err := client.LoadAliPayPublicCert(config.AlipayPublicCert); err != nil {
+			return nil, fmt.Errorf("加载支付宝公钥证书失败: %v", err)
+		}
+	} else if err := client.LoadAliPayPublicKey(config.AliPublicKey); err != nil {
+		return nil, fmt.Errorf("加载支付宝公钥失败: %v", err)
+	}
+
+	return &AlipayService{
+		client: client,
+		config: config,
+	}, nil
+}
+
+// Name 渠道名称
+func (s *AlipayService) Name() string {
+	return "alipay"
+}
+
+// CreatePayment 创建电脑网站支付，返回跳转地址
+func (s *AlipayService) CreatePayment(ctx context.Context, req *CreateRequest) (*CreateResult, error) {
+	var p = alipay.TradePagePay{}
+	p.NotifyURL = s.config.NotifyURL
+	p.ReturnURL = s.config.ReturnURL
+	p.Subject = req.Subject
+	p.OutTradeNo = req.OutTradeNo
+	p.TotalAmount = FormatAmount(req.Amount)
+	p.ProductCode = "FAST_INSTANT_TRADE_PAY"
+
+	url, err := s.client.TradePagePay(p)
+	if err != nil {
+		return nil, fmt.Errorf("创建支付订单失败: %v", err)
+	}
+
+	return &CreateResult{PayURL: url.String()}, nil
+}
+
+// VerifyNotify 校验支付宝以表单 POST 的异步通知
+func (s *AlipayService) VerifyNotify(header http.Header, body []byte) (*Notification, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析通知失败: %v", err)
+	}
+
+	notification, err := s.VerifyNotification(values)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := ParseAmount(notification.TotalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notification{
+		NotifyID:   notification.NotifyId,
+		OutTradeNo: notification.OutTradeNo,
+		TradeNo:    notification.TradeNo,
+		Status:     alipayTradeStatus(notification.TradeStatus),
+		Amount:     amount,
+	}, nil
+}
+
+// AckNotify 处理成功应答 success，否则支付宝会按策略重试
+func (s *AlipayService) AckNotify(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("fail"))
+		return
+	}
+	alipay.ACKNotification(w)
+}
+
+// VerifyNotification 校验异步通知的签名和 app_id，返回解析后的通知
+// values 为支付宝 POST 的表单参数
+func (s *AlipayService) VerifyNotification(values url.Values) (*alipay.Notification, error) {
+	notification, err := s.client.DecodeNotification(values)
+	if err != nil {
+		return nil, fmt.Errorf("验证通知失败: %v", err)
+	}
+
+	if notification.AppId != s.config.AppID {
+		return nil, fmt.Errorf("通知的 app_id 不匹配: %s", notification.AppId)
+	}
+
+	return notification, nil
+}
+
+// VerifyReturn 校验同步跳转参数的签名和 app_id，返回商户订单号
+func (s *AlipayService) VerifyReturn(values url.Values) (string, error) {
+	if err := s.client.VerifySign(values); err != nil {
+		return "", fmt.Errorf("验证签名失败: %v", err)
+	}
+
+	if values.Get("app_id") != s.config.AppID {
+		return "", fmt.Errorf("app_id 不匹配: %s", values.Get("app_id"))
+	}
+
+	return values.Get("out_trade_no"), nil
+}
+
+// Query 查询交易状态
+func (s *AlipayService) Query(ctx context.Context, outTradeNo string) (*Trade, error) {
+	rsp, err := s.client.TradeQuery(alipay.TradeQuery{OutTradeNo: outTradeNo})
+	if err != nil {
+		return nil, alipayError(err)
+	}
+	if rsp.IsFailure() {
+		return nil, alipayError(rsp.Error)
+	}
+
+	amount, err := ParseAmount(rsp.TotalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Trade{
+		OutTradeNo: rsp.OutTradeNo,
+		TradeNo:    rsp.TradeNo,
+		Status:     alipayTradeStatus(rsp.TradeStatus),
+		Amount:     amount,
+	}, nil
+}
+
+// Refund 发起退款，支付宝的退款接口同步返回结果
+func (s *AlipayService) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	rsp, err := s.client.TradeRefund(alipay.TradeRefund{
+		OutTradeNo:   req.OutTradeNo,
+		RefundAmount: FormatAmount(req.Amount),
+		RefundReason: req.Reason,
+		OutRequestNo: req.OutRefundNo,
+	})
+	if err != nil {
+		return nil, alipayError(err)
+	}
+	if rsp.IsFailure() {
+		return nil, alipayError(rsp.Error)
+	}
+
+	return &RefundResult{
+		RefundNo: rsp.TradeNo,
+		Status:   RefundStatusSuccess,
+	}, nil
+}
+
+// QueryRefund 查询退款结果，未返回退款状态表示退款请求未收到或退款失败
+func (s *AlipayService) QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error) {
+	rsp, err := s.client.TradeFastPayRefundQuery(alipay.TradeFastPayRefundQuery{
+		OutTradeNo:   outTradeNo,
+		OutRequestNo: outRefundNo,
+	})
+	if err != nil {
+		return nil, alipayError(err)
+	}
+	if rsp.IsFailure() {
+		return nil, alipayError(rsp.Error)
+	}
+
+	status := RefundStatusFailed
+	if rsp.RefundStatus == "REFUND_SUCCESS" {
+		status = RefundStatusSuccess
+	}
+	return &RefundResult{RefundNo: rsp.TradeNo, Status: status}, nil
+}
+
+// Close 关闭未支付的交易，用户未打开支付页面时支付宝中没有该交易，视为已关闭
+func (s *AlipayService) Close(ctx context.Context, outTradeNo string) error {
+	rsp, err := s.client.TradeClose(alipay.TradeClose{OutTradeNo: outTradeNo})
+	if err != nil {
+		err = alipayError(err)
+	} else if rsp.IsFailure() {
+		err = alipayError(rsp.Error)
+	}
+	if err == ErrTradeNotFound {
+		return nil
+	}
+	return err
+}
+
+// alipayTradeStatus 转换支付宝交易状态
+func alipayTradeStatus(status alipay.TradeStatus) TradeStatus {
+	switch status {
+	case alipay.TradeStatusSuccess, alipay.TradeStatusFinished:
+		return TradeStatusSuccess
+	case alipay.TradeStatusClosed:
+		return TradeStatusClosed
+	}
+	return TradeStatusPending
+}
+
+// alipayError 转换支付宝接口错误，交易不存在时返回 ErrTradeNotFound
+func alipayError(err error) error {
+	var rspErr alipay.Error
+	switch e := err.(type) {
+	case *alipay.Error:
+		rspErr = *e
+	case alipay.Error:
+		rspErr = e
+	default:
+		return fmt.Errorf("调用支付宝接口失败: %v", err)
+	}
+
+	if rspErr.SubCode == "ACQ.TRADE_NOT_EXIST" {
+		return ErrTradeNotFound
+	}
+	return fmt.Errorf("调用支付宝接口失败: %s %s", rspErr.SubCode, rspErr.SubMsg)
+}
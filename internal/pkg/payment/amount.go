@@ -0,0 +1,41 @@
+package payment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatAmount 将金额从分转换为元，如 998 -> "9.98"
+func FormatAmount(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// ParseAmount 将以元为单位的金额字符串转换为分，不使用浮点数避免精度误差
+func ParseAmount(s string) (int, error) {
+	yuan, fen, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if yuan == "" && fen != "" {
+		yuan = "0"
+	}
+	if !isDigits(yuan) || len(yuan) > 9 || len(fen) > 2 || (fen != "" && !isDigits(fen)) {
+		return 0, fmt.Errorf("无效的金额: %q", s)
+	}
+	fen += strings.Repeat("0", 2-len(fen))
+
+	y, _ := strconv.Atoi(yuan)
+	f, _ := strconv.Atoi(fen)
+	return y*100 + f, nil
+}
+
+// isDigits 判断字符串是否只包含数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
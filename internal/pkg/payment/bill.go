@@ -0,0 +1,164 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// BillType 对账单记录类型
+type BillType string
+
+const (
+	BillTypePayment BillType = "payment" // 支付
+	BillTypeRefund  BillType = "refund"  // 退款
+)
+
+// BillRecord 对账单中的一条交易记录
+type BillRecord struct {
+	OutTradeNo string
+	TradeNo    string
+	Type       BillType
+	Amount     int // 支付或退款金额（单位：分），均为正数
+}
+
+// BillDownloader 可以直接提供对账单的渠道，返回的内容与 ParseBill 的格式一致
+type BillDownloader interface {
+	DownloadBill(ctx context.Context, date time.Time) (io.ReadCloser, error)
+}
+
+// 对账单表头的别名，兼容统一格式和支付宝业务明细；支付宝退款记录的退款金额在商家实收列
+var billColumns = map[string][]string{
+	"out_trade_no":  {"out_trade_no", "商户订单号"},
+	"trade_no":      {"trade_no", "支付宝交易号", "微信支付订单号"},
+	"type":          {"type", "业务类型"},
+	"amount":        {"amount", "订单金额（元）", "订单金额(元)"},
+	"refund_amount": {"refund_amount", "退款金额（元）", "退款金额(元)", "商家实收（元）", "商家实收(元)"},
+}
+
+// ParseBill 解析 CSV 对账单
+// 支持 UTF-8 和 GBK 编码，忽略 # 开头的说明行和表头之前的内容；
+// 金额以元为单位，退款记录优先使用退款金额列
+func ParseBill(r io.Reader) ([]BillRecord, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		if data, err = simplifiedchinese.GBK.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("对账单编码无法识别: %v", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var (
+		columns map[string]int
+		records []BillRecord
+	)
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行格式错误: %v", line, err)
+		}
+		for i := range row {
+			row[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(row[i]), "`"))
+		}
+		if len(row) == 0 || row[0] == "" || strings.HasPrefix(row[0], "#") {
+			continue
+		}
+
+		// 找到表头后才开始解析记录
+		if columns == nil {
+			columns = billHeader(row)
+			continue
+		}
+
+		record, ok, err := billRecord(row, columns)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", line, err)
+		}
+		if ok {
+			records = append(records, record)
+		}
+	}
+
+	if columns == nil {
+		return nil, errors.New("对账单缺少表头")
+	}
+	return records, nil
+}
+
+// billHeader 识别表头，缺少商户订单号或金额列时返回 nil
+func billHeader(row []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range row {
+		for key, aliases := range billColumns {
+			for _, alias := range aliases {
+				if name == alias {
+					columns[key] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["out_trade_no"]; !ok {
+		return nil
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil
+	}
+	return columns
+}
+
+// billRecord 解析一行记录，汇总行等无商户订单号的行返回 false
+func billRecord(row []string, columns map[string]int) (BillRecord, bool, error) {
+	field := func(key string) string {
+		i, ok := columns[key]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	record := BillRecord{
+		OutTradeNo: field("out_trade_no"),
+		TradeNo:    field("trade_no"),
+		Type:       BillTypePayment,
+	}
+	if record.OutTradeNo == "" {
+		return record, false, nil
+	}
+
+	switch strings.ToLower(field("type")) {
+	case "", "payment", "交易", "success":
+	case "refund", "退款":
+		record.Type = BillTypeRefund
+	default:
+		// 其他业务类型（如分账、冻结）不参与对账
+		return record, false, nil
+	}
+
+	amount := field("amount")
+	if record.Type == BillTypeRefund && field("refund_amount") != "" {
+		amount = field("refund_amount")
+	}
+	cents, err := ParseAmount(strings.TrimPrefix(amount, "-"))
+	if err != nil {
+		return record, false, err
+	}
+	record.Amount = cents
+	return record, true, nil
+}
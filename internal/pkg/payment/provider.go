@@ -0,0 +1,158 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// TradeStatus 统一的交易状态
+type TradeStatus string
+
+const (
+	TradeStatusPending TradeStatus = "pending" // 等待支付
+	TradeStatusSuccess TradeStatus = "success" // 支付成功
+	TradeStatusClosed  TradeStatus = "closed"  // 已关闭或已全额退款
+	TradeStatusFailed  TradeStatus = "failed"  // 支付失败
+)
+
+// RefundStatus 统一的退款状态
+type RefundStatus string
+
+const (
+	RefundStatusProcessing RefundStatus = "processing" // 退款处理中
+	RefundStatusSuccess    RefundStatus = "success"    // 退款成功
+	RefundStatusFailed     RefundStatus = "failed"     // 退款失败
+)
+
+// ErrTradeNotFound 支付渠道中不存在该交易，通常是用户未打开支付页面
+var ErrTradeNotFound = errors.New("交易不存在")
+
+// CreateRequest 创建支付请求
+type CreateRequest struct {
+	OutTradeNo string // 商户订单号
+	Amount     int    // 金额（单位：分）
+	Subject    string // 商品名称
+}
+
+// CreateResult 创建支付结果
+type CreateResult struct {
+	PayURL string // 支付地址：跳转地址或二维码内容
+}
+
+// Notification 校验通过的异步通知
+type Notification struct {
+	NotifyID   string
+	OutTradeNo string
+	TradeNo    string // 支付渠道的交易号
+	Status     TradeStatus
+	Amount     int // 订单金额（单位：分）
+}
+
+// Trade 查询到的交易
+type Trade struct {
+	OutTradeNo string
+	TradeNo    string
+	Status     TradeStatus
+	Amount     int
+}
+
+// RefundRequest 退款请求
+type RefundRequest struct {
+	OutTradeNo  string
+	OutRefundNo string // 商户退款单号，同一笔退款重试时保持不变
+	Amount      int    // 本次退款金额（单位：分）
+	TotalAmount int    // 订单金额（单位：分）
+	Reason      string
+}
+
+// RefundResult 退款结果
+type RefundResult struct {
+	RefundNo string // 支付渠道的退款单号
+	Status   RefundStatus
+}
+
+// Provider 支付渠道
+type Provider interface {
+	// Name 渠道名称，对应路由中的 :provider
+	Name() string
+	// CreatePayment 创建支付，返回支付地址
+	CreatePayment(ctx context.Context, req *CreateRequest) (*CreateResult, error)
+	// VerifyNotify 校验异步通知的签名并解析
+	VerifyNotify(header http.Header, body []byte) (*Notification, error)
+	// AckNotify 按渠道要求的格式应答异步通知，err 不为空表示处理失败需要重试
+	AckNotify(w http.ResponseWriter, err error)
+	// Query 查询交易状态，交易不存在时返回 ErrTradeNotFound
+	Query(ctx context.Context, outTradeNo string) (*Trade, error)
+	// Refund 发起退款，同一 OutRefundNo 重复调用不会重复退款
+	Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error)
+	// QueryRefund 查询退款结果
+	QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error)
+	// Close 关闭未支付的交易
+	Close(ctx context.Context, outTradeNo string) error
+}
+
+// ReturnVerifier 支持同步跳转的渠道，校验跳转参数并返回商户订单号
+type ReturnVerifier interface {
+	VerifyReturn(values url.Values) (string, error)
+}
+
+var (
+	mu              sync.RWMutex
+	providers       = make(map[string]Provider)
+	defaultProvider string
+)
+
+// Register 注册支付渠道，同名渠道会被替换；第一个注册的渠道作为默认渠道
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Name()] = p
+	if defaultProvider == "" {
+		defaultProvider = p.Name()
+	}
+}
+
+// SetDefault 设置默认支付渠道
+func SetDefault(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultProvider = name
+}
+
+// Get 获取支付渠道，name 为空时返回默认渠道
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if name == "" {
+		name = defaultProvider
+	}
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 获取全部已注册的支付渠道名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	_ Provider          = (*AlipayService)(nil)
+	_ Provider          = (*WeChatService)(nil)
+	_ Provider          = (*SandboxService)(nil)
+	_ ReturnVerifier    = (*AlipayService)(nil)
+	_ ReturnVerifier    = (*SandboxService)(nil)
+	_ BillDownloader    = (*SandboxService)(nil)
+	_ AgreementProvider = (*SandboxService)(nil)
+)
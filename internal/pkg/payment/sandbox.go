@@ -0,0 +1,397 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SandboxConfig 本地沙箱渠道配置
+type SandboxConfig struct {
+	Secret             string        // 通知签名密钥
+	CheckoutURL        string        // 沙箱收银台地址，指向 /api/v1/payment/sandbox/checkout
+	NotifyURL          string        // 异步通知地址，指向 /api/v1/payment/notify/sandbox
+	AgreementNotifyURL string        // 签约通知地址，指向 /api/v1/payment/agreement/notify/sandbox
+	ReturnURL          string        // 支付完成后的跳转地址，为空时显示结果页
+	NotifyDelay        time.Duration // 支付后延迟发送异步通知
+}
+
+// SandboxService 完全离线的模拟支付渠道，供开发和测试使用
+// 交易保存在内存中，收银台页面模拟用户支付，随后向 NotifyURL 发送签名的异步通知
+type SandboxService struct {
+	config *SandboxConfig
+	client *http.Client
+
+	mu         sync.Mutex
+	trades     map[string]*sandboxTrade
+	refunds    map[string]*sandboxRefund    // 键为商户退款单号
+	agreements map[string]*sandboxAgreement // 键为商户签约号
+	seq        int
+}
+
+type sandboxTrade struct {
+	Trade
+	Subject  string
+	Refunded int
+	PaidAt   time.Time
+}
+
+type sandboxRefund struct {
+	RefundResult
+	OutTradeNo string
+	Amount     int
+	RefundedAt time.Time
+}
+
+func NewSandboxService(config *SandboxConfig) (*SandboxService, error) {
+	if config.Secret == "" {
+		return nil, errors.New("沙箱支付渠道需要配置签名密钥")
+	}
+	return &SandboxService{
+		config:     config,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		trades:     make(map[string]*sandboxTrade),
+		refunds:    make(map[string]*sandboxRefund),
+		agreements: make(map[string]*sandboxAgreement),
+	}, nil
+}
+
+// Name 渠道名称
+func (s *SandboxService) Name() string {
+	return "sandbox"
+}
+
+// CreatePayment 创建模拟交易，返回沙箱收银台地址
+func (s *SandboxService) CreatePayment(ctx context.Context, req *CreateRequest) (*CreateResult, error) {
+	s.mu.Lock()
+	// 同一订单再次下单时沿用已有交易，与真实渠道一致
+	if _, ok := s.trades[req.OutTradeNo]; !ok {
+		s.trades[req.OutTradeNo] = &sandboxTrade{
+			Trade: Trade{
+				OutTradeNo: req.OutTradeNo,
+				Status:     TradeStatusPending,
+				Amount:     req.Amount,
+			},
+			Subject: req.Subject,
+		}
+	}
+	s.mu.Unlock()
+
+	return &CreateResult{PayURL: appendQuery(s.config.CheckoutURL, url.Values{"out_trade_no": {req.OutTradeNo}})}, nil
+}
+
+// VerifyNotify 校验沙箱通知的签名
+func (s *SandboxService) VerifyNotify(header http.Header, body []byte) (*Notification, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析通知失败: %v", err)
+	}
+	if !s.verify(values) {
+		return nil, errors.New("沙箱通知签名校验失败")
+	}
+
+	amount, err := ParseAmount(values.Get("total_amount"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notification{
+		NotifyID:   values.Get("notify_id"),
+		OutTradeNo: values.Get("out_trade_no"),
+		TradeNo:    values.Get("trade_no"),
+		Status:     TradeStatus(values.Get("trade_status")),
+		Amount:     amount,
+	}, nil
+}
+
+// AckNotify 与支付宝一致，成功应答 success
+func (s *SandboxService) AckNotify(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("fail"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("success"))
+}
+
+// VerifyReturn 校验跳转参数的签名，返回商户订单号
+func (s *SandboxService) VerifyReturn(values url.Values) (string, error) {
+	if !s.verify(values) {
+		return "", errors.New("沙箱跳转签名校验失败")
+	}
+	return values.Get("out_trade_no"), nil
+}
+
+// Query 查询模拟交易
+func (s *SandboxService) Query(ctx context.Context, outTradeNo string) (*Trade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trade, ok := s.trades[outTradeNo]
+	if !ok {
+		return nil, ErrTradeNotFound
+	}
+	result := trade.Trade
+	return &result, nil
+}
+
+// Refund 模拟退款，立即成功
+func (s *SandboxService) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if refund, ok := s.refunds[req.OutRefundNo]; ok {
+		result := refund.RefundResult
+		return &result, nil
+	}
+
+	trade, ok := s.trades[req.OutTradeNo]
+	if !ok {
+		return nil, ErrTradeNotFound
+	}
+	if trade.Status != TradeStatusSuccess {
+		return nil, errors.New("交易未支付，不能退款")
+	}
+	if trade.Refunded+req.Amount > trade.Amount {
+		return nil, errors.New("退款金额超过订单金额")
+	}
+
+	trade.Refunded += req.Amount
+	if trade.Refunded == trade.Amount {
+		trade.Status = TradeStatusClosed
+	}
+	s.seq++
+	refund := &sandboxRefund{
+		RefundResult: RefundResult{
+			RefundNo: "SANDBOX-R" + strconv.Itoa(s.seq),
+			Status:   RefundStatusSuccess,
+		},
+		OutTradeNo: req.OutTradeNo,
+		Amount:     req.Amount,
+		RefundedAt: time.Now(),
+	}
+	s.refunds[req.OutRefundNo] = refund
+	result := refund.RefundResult
+	return &result, nil
+}
+
+// QueryRefund 查询模拟退款
+func (s *SandboxService) QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund, ok := s.refunds[outRefundNo]
+	if !ok {
+		return nil, ErrTradeNotFound
+	}
+	result := refund.RefundResult
+	return &result, nil
+}
+
+// DownloadBill 生成指定日期的模拟对账单，包含当天支付成功的交易和退款
+func (s *SandboxService) DownloadBill(ctx context.Context, date time.Time) (io.ReadCloser, error) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+	inDay := func(t time.Time) bool {
+		return !t.Before(start) && t.Before(end)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"out_trade_no", "trade_no", "type", "amount"})
+	for _, trade := range s.trades {
+		if trade.TradeNo != "" && inDay(trade.PaidAt) {
+			w.Write([]string{trade.OutTradeNo, trade.TradeNo, string(BillTypePayment), FormatAmount(trade.Amount)})
+		}
+	}
+	for _, refund := range s.refunds {
+		if inDay(refund.RefundedAt) {
+			w.Write([]string{refund.OutTradeNo, refund.RefundNo, string(BillTypeRefund), FormatAmount(refund.Amount)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// Close 关闭未支付的模拟交易
+func (s *SandboxService) Close(ctx context.Context, outTradeNo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trade, ok := s.trades[outTradeNo]
+	if !ok {
+		return nil
+	}
+	if trade.Status == TradeStatusSuccess {
+		return errors.New("交易已支付，不能关闭")
+	}
+	trade.Status = TradeStatusClosed
+	return nil
+}
+
+var sandboxCheckoutPage = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>沙箱收银台</title></head>
+<body>
+<h3>沙箱收银台</h3>
+<p>订单号：{{.OutTradeNo}}</p>
+<p>商品：{{.Subject}}</p>
+<p>金额：{{.Amount}} 元</p>
+<p>状态：{{.Status}}</p>
+{{if .Pending}}<p><a href="{{.PayURL}}">模拟支付成功</a> | <a href="{{.CancelURL}}">取消支付</a></p>{{end}}
+</body></html>`))
+
+// ServeHTTP 沙箱收银台
+// 不带 action 时显示订单信息；action=pay 模拟支付成功并发送异步通知；action=cancel 关闭交易
+// 带 external_agreement_no 时为签约页面，见 serveAgreement
+func (s *SandboxService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("external_agreement_no") != "" {
+		s.serveAgreement(w, r)
+		return
+	}
+	outTradeNo := r.URL.Query().Get("out_trade_no")
+
+	s.mu.Lock()
+	trade, ok := s.trades[outTradeNo]
+	var snapshot sandboxTrade
+	if ok {
+		snapshot = *trade
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "交易不存在", http.StatusNotFound)
+		return
+	}
+
+	switch r.URL.Query().Get("action") {
+	case "pay":
+		s.pay(w, r, outTradeNo)
+	case "cancel":
+		_ = s.Close(r.Context(), outTradeNo)
+		fmt.Fprint(w, "已取消支付")
+	default:
+		base := *r.URL
+		query := url.Values{"out_trade_no": {outTradeNo}}
+		query.Set("action", "pay")
+		base.RawQuery = query.Encode()
+		payURL := base.String()
+		query.Set("action", "cancel")
+		base.RawQuery = query.Encode()
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		sandboxCheckoutPage.Execute(w, map[string]interface{}{
+			"OutTradeNo": snapshot.OutTradeNo,
+			"Subject":    snapshot.Subject,
+			"Amount":     FormatAmount(snapshot.Amount),
+			"Status":     snapshot.Status,
+			"Pending":    snapshot.Status == TradeStatusPending,
+			"PayURL":     payURL,
+			"CancelURL":  base.String(),
+		})
+	}
+}
+
+// pay 模拟支付成功，异步发送通知后跳转到 ReturnURL
+func (s *SandboxService) pay(w http.ResponseWriter, r *http.Request, outTradeNo string) {
+	s.mu.Lock()
+	trade := s.trades[outTradeNo]
+	if trade.Status != TradeStatusPending {
+		s.mu.Unlock()
+		http.Error(w, "交易状态为 "+string(trade.Status)+"，不能支付", http.StatusConflict)
+		return
+	}
+	s.seq++
+	trade.Status = TradeStatusSuccess
+	trade.PaidAt = time.Now()
+	trade.TradeNo = "SANDBOX-" + strconv.FormatInt(time.Now().Unix(), 10) + "-" + strconv.Itoa(s.seq)
+	values := url.Values{
+		"notify_id":    {strconv.Itoa(s.seq)},
+		"out_trade_no": {trade.OutTradeNo},
+		"trade_no":     {trade.TradeNo},
+		"trade_status": {string(TradeStatusSuccess)},
+		"total_amount": {FormatAmount(trade.Amount)},
+	}
+	s.mu.Unlock()
+
+	s.sign(values)
+	go s.notify(s.config.NotifyURL, values)
+
+	if s.config.ReturnURL == "" {
+		fmt.Fprint(w, "支付成功")
+		return
+	}
+	returnValues := url.Values{"out_trade_no": {outTradeNo}}
+	s.sign(returnValues)
+	http.Redirect(w, r, appendQuery(s.config.ReturnURL, returnValues), http.StatusFound)
+}
+
+// notify 向通知地址发送异步通知，失败时重试
+func (s *SandboxService) notify(target string, values url.Values) {
+	for attempt := 0; attempt < 3; attempt++ {
+		time.Sleep(s.config.NotifyDelay + time.Duration(attempt)*time.Second)
+
+		resp, err := s.client.PostForm(target, values)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+			err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		zap.L().Warn("发送沙箱通知失败", zap.String("url", target), zap.String("out_trade_no", values.Get("out_trade_no")), zap.Error(err))
+	}
+}
+
+// sign 计算参数签名并写入 sign 字段
+func (s *SandboxService) sign(values url.Values) {
+	values.Del("sign")
+	values.Set("sign", s.signature(values))
+}
+
+// verify 校验参数签名
+func (s *SandboxService) verify(values url.Values) bool {
+	sig := values.Get("sign")
+	unsigned := url.Values{}
+	for k, v := range values {
+		if k != "sign" {
+			unsigned[k] = v
+		}
+	}
+	return sig != "" && hmac.Equal([]byte(sig), []byte(s.signature(unsigned)))
+}
+
+// signature 对按键排序的参数计算 HMAC-SHA256
+func (s *SandboxService) signature(values url.Values) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Secret))
+	mac.Write([]byte(values.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// appendQuery 向地址追加查询参数
+func appendQuery(rawURL string, values url.Values) string {
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + values.Encode()
+	}
+	return rawURL + "?" + values.Encode()
+}
@@ -0,0 +1,216 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type sandboxAgreement struct {
+	Agreement
+	Subject     string
+	Amount      int
+	PeriodDays  int
+	FailCharges bool // 签约时选择模拟扣款失败，用于测试续费重试
+}
+
+// SignAgreement 创建待签约的模拟协议，返回沙箱签约页面地址
+func (s *SandboxService) SignAgreement(ctx context.Context, req *SignRequest) (*SignResult, error) {
+	if s.config.AgreementNotifyURL == "" {
+		return nil, errors.New("沙箱支付渠道未配置签约通知地址")
+	}
+
+	s.mu.Lock()
+	if _, ok := s.agreements[req.ExternalAgreementNo]; !ok {
+		s.agreements[req.ExternalAgreementNo] = &sandboxAgreement{
+			Agreement: Agreement{
+				ExternalAgreementNo: req.ExternalAgreementNo,
+				Status:              AgreementStatusPending,
+			},
+			Subject:    req.Subject,
+			Amount:     req.Amount,
+			PeriodDays: req.PeriodDays,
+		}
+	}
+	s.mu.Unlock()
+
+	return &SignResult{SignURL: appendQuery(s.config.CheckoutURL, url.Values{"external_agreement_no": {req.ExternalAgreementNo}})}, nil
+}
+
+// VerifyAgreementNotify 校验沙箱签约通知的签名
+func (s *SandboxService) VerifyAgreementNotify(header http.Header, body []byte) (*Agreement, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析通知失败: %v", err)
+	}
+	if !s.verify(values) {
+		return nil, errors.New("沙箱通知签名校验失败")
+	}
+	return &Agreement{
+		ExternalAgreementNo: values.Get("external_agreement_no"),
+		AgreementNo:         values.Get("agreement_no"),
+		Status:              AgreementStatus(values.Get("agreement_status")),
+	}, nil
+}
+
+// QueryAgreement 查询模拟协议
+func (s *SandboxService) QueryAgreement(ctx context.Context, externalAgreementNo string) (*Agreement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agreement, ok := s.agreements[externalAgreementNo]
+	if !ok {
+		return nil, ErrAgreementNotFound
+	}
+	result := agreement.Agreement
+	return &result, nil
+}
+
+// ChargeAgreement 模拟代扣，立即返回成功或失败
+func (s *SandboxService) ChargeAgreement(ctx context.Context, req *ChargeRequest) (*Trade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if trade, ok := s.trades[req.OutTradeNo]; ok {
+		result := trade.Trade
+		return &result, nil
+	}
+
+	agreement := s.agreementByNo(req.AgreementNo)
+	if agreement == nil {
+		return nil, ErrAgreementNotFound
+	}
+	if agreement.Status != AgreementStatusActive {
+		return nil, errors.New("协议已解约，不能扣款")
+	}
+
+	trade := &sandboxTrade{
+		Trade: Trade{
+			OutTradeNo: req.OutTradeNo,
+			Status:     TradeStatusFailed,
+			Amount:     req.Amount,
+		},
+		Subject: req.Subject,
+	}
+	if !agreement.FailCharges {
+		s.seq++
+		trade.Status = TradeStatusSuccess
+		trade.PaidAt = time.Now()
+		trade.TradeNo = "SANDBOX-" + strconv.FormatInt(time.Now().Unix(), 10) + "-" + strconv.Itoa(s.seq)
+	}
+	s.trades[req.OutTradeNo] = trade
+	result := trade.Trade
+	return &result, nil
+}
+
+// TerminateAgreement 解约模拟协议
+func (s *SandboxService) TerminateAgreement(ctx context.Context, agreementNo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agreement := s.agreementByNo(agreementNo)
+	if agreement == nil {
+		return ErrAgreementNotFound
+	}
+	agreement.Status = AgreementStatusTerminated
+	return nil
+}
+
+// agreementByNo 按渠道协议号查找协议，调用方需持有锁
+func (s *SandboxService) agreementByNo(agreementNo string) *sandboxAgreement {
+	for _, agreement := range s.agreements {
+		if agreementNo != "" && agreement.AgreementNo == agreementNo {
+			return agreement
+		}
+	}
+	return nil
+}
+
+var sandboxSignPage = template.Must(template.New("sign").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>沙箱签约</title></head>
+<body>
+<h3>沙箱自动续费签约</h3>
+<p>签约号：{{.ExternalAgreementNo}}</p>
+<p>商品：{{.Subject}}</p>
+<p>每 {{.PeriodDays}} 天扣款 {{.Amount}} 元</p>
+<p>状态：{{.Status}}</p>
+{{if .Pending}}<p><a href="{{.SignURL}}">同意签约</a> | <a href="{{.SignFailURL}}">签约（之后扣款均失败）</a> | <a href="{{.RejectURL}}">拒绝签约</a></p>{{end}}
+</body></html>`))
+
+// serveAgreement 沙箱签约页面
+// 不带 action 时显示协议信息；action=sign 签约成功；action=sign_fail 签约成功但之后的扣款均失败；
+// action=reject 拒绝签约。签约结果通过 AgreementNotifyURL 异步通知
+func (s *SandboxService) serveAgreement(w http.ResponseWriter, r *http.Request) {
+	externalAgreementNo := r.URL.Query().Get("external_agreement_no")
+	action := r.URL.Query().Get("action")
+
+	s.mu.Lock()
+	agreement, ok := s.agreements[externalAgreementNo]
+	if !ok {
+		s.mu.Unlock()
+		http.Error(w, "协议不存在", http.StatusNotFound)
+		return
+	}
+	snapshot := *agreement
+	if action == "" {
+		s.mu.Unlock()
+
+		base := *r.URL
+		link := func(action string) string {
+			base.RawQuery = url.Values{"external_agreement_no": {externalAgreementNo}, "action": {action}}.Encode()
+			return base.String()
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		sandboxSignPage.Execute(w, map[string]interface{}{
+			"ExternalAgreementNo": snapshot.ExternalAgreementNo,
+			"Subject":             snapshot.Subject,
+			"PeriodDays":          snapshot.PeriodDays,
+			"Amount":              FormatAmount(snapshot.Amount),
+			"Status":              snapshot.Status,
+			"Pending":             snapshot.Status == AgreementStatusPending,
+			"SignURL":             link("sign"),
+			"SignFailURL":         link("sign_fail"),
+			"RejectURL":           link("reject"),
+		})
+		return
+	}
+
+	if agreement.Status != AgreementStatusPending {
+		s.mu.Unlock()
+		http.Error(w, "协议状态为 "+string(agreement.Status)+"，不能签约", http.StatusConflict)
+		return
+	}
+	switch action {
+	case "sign", "sign_fail":
+		s.seq++
+		agreement.Status = AgreementStatusActive
+		agreement.AgreementNo = "SANDBOX-A" + strconv.Itoa(s.seq)
+		agreement.FailCharges = action == "sign_fail"
+	case "reject":
+		agreement.Status = AgreementStatusTerminated
+	default:
+		s.mu.Unlock()
+		http.Error(w, "未知操作", http.StatusBadRequest)
+		return
+	}
+	values := url.Values{
+		"external_agreement_no": {agreement.ExternalAgreementNo},
+		"agreement_no":          {agreement.AgreementNo},
+		"agreement_status":      {string(agreement.Status)},
+	}
+	s.mu.Unlock()
+
+	s.sign(values)
+	go s.notify(s.config.AgreementNotifyURL, values)
+
+	if action == "reject" {
+		fmt.Fprint(w, "已拒绝签约")
+		return
+	}
+	fmt.Fprint(w, "签约成功")
+}
@@ -0,0 +1,446 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WeChatConfig 微信支付 APIv3 配置
+type WeChatConfig struct {
+	AppID             string
+	MchID             string
+	SerialNo          string // 商户API证书序列号
+	PrivateKey        string // 商户API私钥（PEM）
+	APIv3Key          string // APIv3 密钥，用于解密通知
+	PlatformPublicKey string // 微信支付公钥或平台证书（PEM），用于验签
+	PlatformSerial    string // 微信支付公钥ID或平台证书序列号，为空时不校验
+	NotifyURL         string
+	BaseURL           string // 默认 https://api.mch.weixin.qq.com
+}
+
+// WeChatService 微信支付 Native 支付，返回二维码链接
+type WeChatService struct {
+	config     *WeChatConfig
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	client     *http.Client
+}
+
+// wechatNotifyMaxSkew 通知时间戳允许的最大偏差
+const wechatNotifyMaxSkew = 5 * time.Minute
+
+func NewWeChatService(config *WeChatConfig) (*WeChatService, error) {
+	if len(config.APIv3Key) != 32 {
+		return nil, errors.New("微信支付 APIv3 密钥长度必须为32字节")
+	}
+
+	privateKey, err := parseRSAPrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载微信支付商户私钥失败: %v", err)
+	}
+	publicKey, err := parseRSAPublicKey(config.PlatformPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载微信支付公钥失败: %v", err)
+	}
+
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.mch.weixin.qq.com"
+	}
+
+	return &WeChatService{
+		config:     config,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Name 渠道名称
+func (s *WeChatService) Name() string {
+	return "wechat"
+}
+
+// CreatePayment 创建 Native 支付，返回的 code_url 由前端生成二维码
+func (s *WeChatService) CreatePayment(ctx context.Context, req *CreateRequest) (*CreateResult, error) {
+	body := map[string]interface{}{
+		"appid":        s.config.AppID,
+		"mchid":        s.config.MchID,
+		"description":  req.Subject,
+		"out_trade_no": req.OutTradeNo,
+		"notify_url":   s.config.NotifyURL,
+		"amount": map[string]interface{}{
+			"total":    req.Amount,
+			"currency": "CNY",
+		},
+	}
+
+	var rsp struct {
+		CodeURL string `json:"code_url"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v3/pay/transactions/native", body, &rsp); err != nil {
+		return nil, err
+	}
+	return &CreateResult{PayURL: rsp.CodeURL}, nil
+}
+
+// VerifyNotify 校验通知签名并解密支付结果
+func (s *WeChatService) VerifyNotify(header http.Header, body []byte) (*Notification, error) {
+	if err := s.verify(header, body); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		ID        string `json:"id"`
+		EventType string `json:"event_type"`
+		Resource  struct {
+			Algorithm      string `json:"algorithm"`
+			Ciphertext     string `json:"ciphertext"`
+			AssociatedData string `json:"associated_data"`
+			Nonce          string `json:"nonce"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("解析通知失败: %v", err)
+	}
+	if event.Resource.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, fmt.Errorf("不支持的通知加密算法: %s", event.Resource.Algorithm)
+	}
+
+	plaintext, err := s.decrypt(event.Resource.Ciphertext, event.Resource.Nonce, event.Resource.AssociatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction wechatTransaction
+	if err := json.Unmarshal(plaintext, &transaction); err != nil {
+		return nil, fmt.Errorf("解析支付结果失败: %v", err)
+	}
+	if transaction.AppID != s.config.AppID || transaction.MchID != s.config.MchID {
+		return nil, errors.New("通知的 appid 或 mchid 不匹配")
+	}
+
+	return &Notification{
+		NotifyID:   event.ID,
+		OutTradeNo: transaction.OutTradeNo,
+		TradeNo:    transaction.TransactionID,
+		Status:     transaction.status(),
+		Amount:     transaction.Amount.Total,
+	}, nil
+}
+
+// AckNotify 按 APIv3 要求应答，失败时返回 FAIL 以便微信支付重试
+func (s *WeChatService) AckNotify(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"code": "FAIL", "message": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"code": "SUCCESS", "message": "成功"})
+}
+
+// Query 按商户订单号查询交易
+func (s *WeChatService) Query(ctx context.Context, outTradeNo string) (*Trade, error) {
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "?mchid=" + url.QueryEscape(s.config.MchID)
+
+	var transaction wechatTransaction
+	if err := s.do(ctx, http.MethodGet, path, nil, &transaction); err != nil {
+		return nil, err
+	}
+
+	return &Trade{
+		OutTradeNo: transaction.OutTradeNo,
+		TradeNo:    transaction.TransactionID,
+		Status:     transaction.status(),
+		Amount:     transaction.Amount.Total,
+	}, nil
+}
+
+// Refund 发起退款，退款结果可能需要稍后查询
+func (s *WeChatService) Refund(ctx context.Context, req *RefundRequest) (*RefundResult, error) {
+	body := map[string]interface{}{
+		"out_trade_no":  req.OutTradeNo,
+		"out_refund_no": req.OutRefundNo,
+		"reason":        req.Reason,
+		"amount": map[string]interface{}{
+			"refund":   req.Amount,
+			"total":    req.TotalAmount,
+			"currency": "CNY",
+		},
+	}
+
+	var rsp struct {
+		RefundID string `json:"refund_id"`
+		Status   string `json:"status"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v3/refund/domestic/refunds", body, &rsp); err != nil {
+		return nil, err
+	}
+
+	return &RefundResult{RefundNo: rsp.RefundID, Status: wechatRefundStatus(rsp.Status)}, nil
+}
+
+// QueryRefund 查询退款结果
+func (s *WeChatService) QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error) {
+	var rsp struct {
+		RefundID string `json:"refund_id"`
+		Status   string `json:"status"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(outRefundNo), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &RefundResult{RefundNo: rsp.RefundID, Status: wechatRefundStatus(rsp.Status)}, nil
+}
+
+// wechatRefundStatus 转换微信支付的退款状态
+func wechatRefundStatus(status string) RefundStatus {
+	switch status {
+	case "SUCCESS":
+		return RefundStatusSuccess
+	case "CLOSED", "ABNORMAL":
+		return RefundStatusFailed
+	default:
+		return RefundStatusProcessing
+	}
+}
+
+// Close 关闭未支付的交易
+func (s *WeChatService) Close(ctx context.Context, outTradeNo string) error {
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "/close"
+	err := s.do(ctx, http.MethodPost, path, map[string]string{"mchid": s.config.MchID}, nil)
+	if err == ErrTradeNotFound {
+		return nil
+	}
+	return err
+}
+
+// wechatTransaction 微信支付交易信息
+type wechatTransaction struct {
+	AppID         string `json:"appid"`
+	MchID         string `json:"mchid"`
+	OutTradeNo    string `json:"out_trade_no"`
+	TransactionID string `json:"transaction_id"`
+	TradeState    string `json:"trade_state"`
+	Amount        struct {
+		Total int `json:"total"`
+	} `json:"amount"`
+}
+
+// status 转换微信支付交易状态
+func (t *wechatTransaction) status() TradeStatus {
+	switch t.TradeState {
+	case "SUCCESS":
+		return TradeStatusSuccess
+	case "CLOSED", "REVOKED", "REFUND":
+		return TradeStatusClosed
+	case "PAYERROR":
+		return TradeStatusFailed
+	}
+	return TradeStatusPending
+}
+
+// do 发送签名请求并校验应答签名
+func (s *WeChatService) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.config.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	authorization, err := s.authorization(method, path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("调用微信支付接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var rspErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &rspErr)
+		if rspErr.Code == "ORDER_NOT_EXIST" || rspErr.Code == "RESOURCE_NOT_EXISTS" {
+			return ErrTradeNotFound
+		}
+		return fmt.Errorf("调用微信支付接口失败: %d %s %s", resp.StatusCode, rspErr.Code, rspErr.Message)
+	}
+
+	if err := s.verify(resp.Header, data); err != nil {
+		return err
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// authorization 生成 WECHATPAY2-SHA256-RSA2048 认证头
+func (s *WeChatService) authorization(method, path string, body []byte) (string, error) {
+	nonce, err := wechatNonce()
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	message := method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",timestamp="%s",serial_no="%s",signature="%s"`,
+		s.config.MchID, nonce, timestamp, s.config.SerialNo, base64.StdEncoding.EncodeToString(signature)), nil
+}
+
+// verify 校验应答或通知的签名
+func (s *WeChatService) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("Wechatpay-Timestamp")
+	nonce := header.Get("Wechatpay-Nonce")
+	signature := header.Get("Wechatpay-Signature")
+	serial := header.Get("Wechatpay-Serial")
+	if timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("缺少微信支付签名")
+	}
+	if s.config.PlatformSerial != "" && serial != s.config.PlatformSerial {
+		return fmt.Errorf("微信支付公钥序列号不匹配: %s", serial)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("无效的签名时间戳")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > wechatNotifyMaxSkew || skew < -wechatNotifyMaxSkew {
+		return errors.New("签名时间戳已过期")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("无效的签名")
+	}
+
+	message := timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return errors.New("微信支付签名校验失败")
+	}
+	return nil
+}
+
+// decrypt 使用 APIv3 密钥解密通知内容
+func (s *WeChatService) decrypt(ciphertext, nonce, associatedData string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errors.New("无效的通知密文")
+	}
+
+	block, err := aes.NewCipher([]byte(s.config.APIv3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+	if err != nil {
+		return nil, errors.New("解密通知失败")
+	}
+	return plaintext, nil
+}
+
+// wechatNonce 生成随机字符串
+func wechatNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// parseRSAPrivateKey 解析 PEM 格式的 PKCS8 或 PKCS1 私钥
+func parseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("无效的 PEM 私钥")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("私钥不是 RSA 私钥")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// parseRSAPublicKey 解析 PEM 格式的公钥或证书
+func parseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("无效的 PEM 公钥")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	default:
+		var err error
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("公钥不是 RSA 公钥")
+	}
+	return rsaKey, nil
+}
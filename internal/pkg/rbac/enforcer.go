@@ -0,0 +1,141 @@
+package rbac
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy 权限策略（请求方法 + 路径模式）
+type Policy struct {
+	Method      string
+	PathPattern string
+}
+
+// DefaultCacheTTL 权限判定缓存的默认有效期
+const DefaultCacheTTL = time.Minute
+
+// decision 缓存的判定结果
+type decision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+var (
+	mu        sync.RWMutex
+	decisions = make(map[string]decision)
+	// generation 每次 Invalidate 加一，加载策略期间缓存被清空时丢弃加载结果
+	generation uint64
+	cacheTTL   = DefaultCacheTTL
+)
+
+// SetCacheTTL 设置权限判定缓存的有效期，d <= 0 时使用默认值
+// Invalidate 只清空当前进程的缓存，多实例部署时其他实例的权限变更最迟在有效期后生效
+func SetCacheTTL(d time.Duration) {
+	if d <= 0 {
+		d = DefaultCacheTTL
+	}
+	mu.Lock()
+	cacheTTL = d
+	mu.Unlock()
+}
+
+// KeyMatch 判断路由模板是否匹配路径模式
+// 模式中的 :param 匹配任意单个路径段，* 位于末尾时匹配剩余所有路径段，否则匹配单个路径段
+func KeyMatch(path, pattern string) bool {
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+
+	for i, part := range patternParts {
+		if part == "*" && i == len(patternParts)-1 {
+			return len(pathParts) >= len(patternParts)
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if part == "*" || strings.HasPrefix(part, ":") {
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+
+	return len(pathParts) == len(patternParts)
+}
+
+// MethodMatch 判断请求方法是否匹配，* 匹配所有方法
+func MethodMatch(method, pattern string) bool {
+	return pattern == "*" || strings.EqualFold(method, pattern)
+}
+
+// Match 判断请求是否被任一策略允许
+func Match(policies []Policy, method, path string) bool {
+	for _, p := range policies {
+		if MethodMatch(method, p.Method) && KeyMatch(path, p.PathPattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforce 按角色集合判定请求是否允许，结果按角色集合缓存，缓存在 SetCacheTTL 设置的有效期后过期
+// load 仅在缓存未命中时调用，用于加载角色集合的全部权限策略
+func Enforce(roleIDs []uint, method, path string, load func() ([]Policy, error)) (bool, error) {
+	key := cacheKey(roleIDs, method, path)
+
+	mu.RLock()
+	cached, ok := decisions[key]
+	gen := generation
+	mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.allowed, nil
+	}
+
+	policies, err := load()
+	if err != nil {
+		return false, err
+	}
+	allowed := Match(policies, method, path)
+
+	// 加载期间发生过 Invalidate 时结果可能基于旧的策略，只返回不缓存
+	mu.Lock()
+	if gen == generation {
+		decisions[key] = decision{allowed: allowed, expiresAt: time.Now().Add(cacheTTL)}
+	}
+	mu.Unlock()
+
+	return allowed, nil
+}
+
+// Invalidate 清空当前进程的权限判定缓存，角色或权限分配变更后调用
+func Invalidate() {
+	mu.Lock()
+	decisions = make(map[string]decision)
+	generation++
+	mu.Unlock()
+}
+
+// cacheKey 生成角色集合 + 请求的缓存键
+func cacheKey(roleIDs []uint, method, path string) string {
+	ids := make([]int, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	var b strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(id))
+	}
+	b.WriteByte('|')
+	b.WriteString(strings.ToUpper(method))
+	b.WriteByte(' ')
+	b.WriteString(path)
+	return b.String()
+}
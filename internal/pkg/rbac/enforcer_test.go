@@ -0,0 +1,162 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyMatch(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"/api/v1/users", "/api/v1/users", true},
+		{"/api/v1/users/", "/api/v1/users", true},
+		{"/api/v1/users", "/api/v1/roles", false},
+		{"/api/v1/users/:id", "/api/v1/users/:id", true},
+		{"/api/v1/users/:id", "/api/v1/users/:userId", true},
+		{"/api/v1/users", "/api/v1/users/:id", false},
+		{"/api/v1/users/:id/roles", "/api/v1/users/:id", false},
+		{"/api/v1/users/:id", "/api/v1/*", true},
+		{"/api/v1/users", "/api/v1/*", true},
+		{"/api/v1/users/:id/roles", "/api/v1/users/*", true},
+		{"/api/v1/users", "/api/v1/users/*", false},
+		{"/api/v1/users/:id/roles", "/api/*/users/:id/roles", true},
+		{"/api/v1/users/:id/roles", "/api/*/users/:id", false},
+		{"/api/v1/roles/:id", "/api/*/users/:id", false},
+	}
+
+	for _, tt := range tests {
+		if got := KeyMatch(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("KeyMatch(%q, %q) = %v, 期望 %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	policies := []Policy{
+		{Method: "GET", PathPattern: "/api/v1/users/:id"},
+		{Method: "*", PathPattern: "/api/v1/roles/*"},
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{"GET", "/api/v1/users/:id", true},
+		{"get", "/api/v1/users/:id", true},
+		{"DELETE", "/api/v1/users/:id", false},
+		{"DELETE", "/api/v1/roles/:id", true},
+		{"POST", "/api/v1/roles/:id/permissions", true},
+		{"POST", "/api/v1/roles", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(policies, tt.method, tt.path); got != tt.want {
+			t.Errorf("Match(%s %s) = %v, 期望 %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+// resetCache 清空判定缓存，测试结束后恢复默认有效期
+func resetCache(t *testing.T) {
+	t.Helper()
+	Invalidate()
+	t.Cleanup(func() {
+		SetCacheTTL(DefaultCacheTTL)
+		Invalidate()
+	})
+}
+
+func TestEnforceCachesUntilInvalidate(t *testing.T) {
+	resetCache(t)
+
+	policies := []Policy{{Method: "GET", PathPattern: "/api/v1/users"}}
+	loads := 0
+	load := func() ([]Policy, error) {
+		loads++
+		return policies, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		allowed, err := Enforce([]uint{2, 1}, "GET", "/api/v1/users", load)
+		if err != nil || !allowed {
+			t.Fatalf("Enforce = %v, %v, 期望允许", allowed, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("加载策略 %d 次, 期望 1 次", loads)
+	}
+
+	// 角色顺序不同的同一角色集合共用缓存
+	if _, err := Enforce([]uint{1, 2}, "get", "/api/v1/users", load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 1 {
+		t.Fatalf("相同角色集合重复加载策略 %d 次", loads)
+	}
+
+	// 权限被移除后，Invalidate 前仍使用缓存，之后重新加载
+	policies = nil
+	if allowed, _ := Enforce([]uint{1, 2}, "GET", "/api/v1/users", load); !allowed {
+		t.Fatal("Invalidate 前缓存未生效")
+	}
+	Invalidate()
+	if allowed, _ := Enforce([]uint{1, 2}, "GET", "/api/v1/users", load); allowed {
+		t.Fatal("Invalidate 后仍使用旧的判定结果")
+	}
+	if loads != 2 {
+		t.Fatalf("加载策略 %d 次, 期望 2 次", loads)
+	}
+}
+
+func TestEnforceDiscardsResultLoadedBeforeInvalidate(t *testing.T) {
+	resetCache(t)
+
+	// 加载旧策略期间权限发生变更
+	stale := func() ([]Policy, error) {
+		Invalidate()
+		return []Policy{{Method: "GET", PathPattern: "/api/v1/users"}}, nil
+	}
+	if allowed, _ := Enforce([]uint{1}, "GET", "/api/v1/users", stale); !allowed {
+		t.Fatal("本次请求应按加载到的策略判定")
+	}
+
+	loads := 0
+	fresh := func() ([]Policy, error) {
+		loads++
+		return nil, nil
+	}
+	if allowed, _ := Enforce([]uint{1}, "GET", "/api/v1/users", fresh); allowed {
+		t.Fatal("缓存了 Invalidate 之前加载的判定结果")
+	}
+	if loads != 1 {
+		t.Fatalf("加载策略 %d 次, 期望 1 次", loads)
+	}
+}
+
+func TestEnforceCacheExpires(t *testing.T) {
+	resetCache(t)
+	SetCacheTTL(50 * time.Millisecond)
+
+	loads := 0
+	load := func() ([]Policy, error) {
+		loads++
+		return nil, nil
+	}
+
+	Enforce([]uint{1}, "GET", "/api/v1/users", load)
+	Enforce([]uint{1}, "GET", "/api/v1/users", load)
+	if loads != 1 {
+		t.Fatalf("有效期内加载策略 %d 次, 期望 1 次", loads)
+	}
+
+	// 其他实例的权限变更不会触发本进程的 Invalidate，依靠过期重新加载
+	time.Sleep(60 * time.Millisecond)
+	Enforce([]uint{1}, "GET", "/api/v1/users", load)
+	if loads != 2 {
+		t.Fatalf("过期后加载策略 %d 次, 期望 2 次", loads)
+	}
+}
@@ -1,13 +1,21 @@
 package setting
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
+	"os"
+	"strconv"
 	"time"
 )
 
 type App struct {
-	JwtSecret string
-	Port      int
+	JwtSecret            string
+	Port                 int
+	AccessTokenTTL       int    // 访问令牌有效期（分钟）
+	RefreshTokenTTL      int    // 刷新令牌有效期（小时）
+	TokenStore           string // 令牌黑名单存储：memory / db
+	SessionTouchInterval int    // 更新会话最近活跃时间的最小间隔（秒）
+	PermissionCacheTTL   int    // 权限判定缓存有效期（秒）
 }
 
 type Server struct {
@@ -16,6 +24,168 @@ type Server struct {
 	WriteTimeout time.Duration
 }
 
+type JWT struct {
+	Algorithm      string // 签名算法：HS256 / RS256 / EdDSA
+	KeyDir         string // 非对称密钥目录
+	ActiveKid      string // 签名使用的密钥ID，留空时使用最新的私钥
+	RotateInterval int    // 自动轮换间隔（小时），0 表示不自动生成新密钥
+	ReloadInterval int    // 重新加载密钥目录的间隔（分钟）
+	Issuer         string // 令牌签发者 iss
+	Audience       string // 令牌受众 aud
+	Leeway         int    // 校验 exp/nbf/iat 时允许的时钟偏差（秒）
+}
+
+type LoginSecurity struct {
+	FreeAttempts  int    // 不触发退避的失败次数
+	MaxFailures   int    // 账户锁定阈值
+	IPMaxFailures int    // 客户端IP锁定阈值
+	BaseDelay     int    // 首次退避时长（秒），之后每次失败翻倍
+	MaxDelay      int    // 退避时长上限（秒）
+	LockDuration  int    // 锁定时长（分钟）
+	Window        int    // 距上次失败超过该时长后重新计数（分钟）
+	Store         string // 失败记录存储：memory / db
+}
+
+type TwoFactor struct {
+	EnforceForSuper bool   // 持有超级管理员角色的用户必须启用两步验证
+	Issuer          string // 认证器应用中显示的签发者名称
+	ChallengeTTL    int    // 登录挑战令牌有效期（分钟）
+	Skew            int    // 允许前后偏差的时间步数
+}
+
+type EmailSecurity struct {
+	RequireVerifiedForOrder bool   // 未验证邮箱的用户不能创建订单
+	VerifyTokenTTL          int    // 邮箱验证令牌有效期（小时）
+	ResetTokenTTL           int    // 密码重置令牌有效期（分钟）
+	ResendInterval          int    // 同一用户两次发送邮件的最小间隔（秒）
+	VerifyURL               string // 邮箱验证页面地址，令牌以 token 参数附加
+	ResetURL                string // 密码重置页面地址，令牌以 token 参数附加
+}
+
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	MinEntropy       float64 // 估算熵的下限（位）
+	MinClasses       int     // 至少包含的字符类别数
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	CheckCommon      bool   // 拒绝常见或已泄露的密码
+	CommonListFile   string // 额外的弱密码列表文件
+	DisallowUserInfo bool   // 不能包含用户名或邮箱前缀
+
+	Algorithm         string // 哈希算法：bcrypt / argon2id
+	BcryptCost        int
+	Argon2Memory      uint32 // 内存（KiB）
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+type Security struct {
+	Login     LoginSecurity
+	TwoFactor TwoFactor
+	Email     EmailSecurity
+	Password  PasswordPolicy
+}
+
+type Mail struct {
+	Driver   string // 发送方式：log（写日志或文件）/ smtp
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Dir      string // log 模式下保存邮件的目录，为空时只写日志
+}
+
+type OAuthProvider struct {
+	Type         string // oidc / github / wechat
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // 回调地址，指向 /api/v1/oauth/<name>/callback
+	Issuer       string // oidc 的 issuer，配置后通过发现文档获取端点
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+}
+
+type OAuth struct {
+	StateTTL     int                      // 授权请求有效期（分钟）
+	AutoRegister bool                     // 第三方账号未绑定时自动注册新用户
+	Providers    map[string]OAuthProvider // 键为提供方名称
+}
+
+type Alipay struct {
+	Enabled              bool
+	AppID                string
+	PrivateKey           string // 应用私钥，与 PrivateKeyFile 二选一
+	PrivateKeyFile       string
+	AlipayPublicKey      string // 公钥模式：支付宝公钥，与 AlipayPublicKeyFile 二选一
+	AlipayPublicKeyFile  string
+	AppPublicCertFile    string // 证书模式：应用公钥证书，配置后启用证书模式
+	AlipayRootCertFile   string // 证书模式：支付宝根证书
+	AlipayPublicCertFile string // 证书模式：支付宝公钥证书
+	NotifyURL            string // 指向 /api/v1/payment/notify/alipay
+	ReturnURL            string // 指向 /api/v1/payment/return/alipay
+	IsProd               bool   // 是否生产环境
+}
+
+type WeChatPay struct {
+	Enabled               bool
+	AppID                 string
+	MchID                 string // 商户号
+	SerialNo              string // 商户API证书序列号
+	PrivateKeyFile        string // 商户API私钥文件（PEM）
+	APIv3Key              string // APIv3 密钥
+	PlatformPublicKeyFile string // 微信支付公钥或平台证书文件（PEM）
+	PlatformSerial        string // 微信支付公钥ID或平台证书序列号
+	NotifyURL             string // 指向 /api/v1/payment/notify/wechat
+}
+
+type SandboxPay struct {
+	Enabled            bool
+	Secret             string // 通知签名密钥
+	CheckoutURL        string // 沙箱收银台地址，指向 /api/v1/payment/sandbox/checkout
+	NotifyURL          string // 指向 /api/v1/payment/notify/sandbox
+	ReturnURL          string // 指向 /api/v1/payment/return/sandbox
+	NotifyDelay        int    // 支付后延迟发送异步通知（秒）
+	AgreementNotifyURL string // 签约通知，指向 /api/v1/payment/agreement/notify/sandbox
+}
+
+type OrderJob struct {
+	Enabled    bool
+	Interval   int // 执行间隔（秒）
+	OrderTTL   int // 未支付订单的有效期（分钟），超过后关闭交易并取消订单
+	QueryDelay int // 下单后多久开始主动查询交易状态（秒）
+	BatchSize  int // 每次处理的订单数
+}
+
+type Reconcile struct {
+	Enabled bool
+	Hour    int    // 每天开始核对前一天账单的时刻（0-23）
+	BillDir string // 账单目录，文件路径为 <billDir>/<provider>/<2006-01-02>.csv
+}
+
+type Subscription struct {
+	Enabled        bool
+	Interval       int   // 续费任务执行间隔（秒）
+	RenewBefore    int   // 会员到期前多久扣款（小时）
+	GraceDays      int   // 续费失败后保留会员权益的天数
+	RetryIntervals []int // 续费失败后依次等待的小时数，全部失败后订阅过期
+	BatchSize      int   // 每次处理的订阅数
+}
+
+type Payment struct {
+	Default      string // 未指定支付方式时使用的渠道
+	WeChat       WeChatPay
+	Sandbox      SandboxPay
+	OrderJob     OrderJob     // 未支付订单的查询和过期关闭
+	Reconcile    Reconcile    // 每日账单核对
+	Subscription Subscription // 自动续费
+}
+
 type Database struct {
 	Type        string
 	User        string
@@ -29,6 +199,12 @@ type Database struct {
 var (
 	AppSetting      = &App{}
 	ServerSetting   = &Server{}
+	JWTSetting      = &JWT{}
+	SecuritySetting = &Security{}
+	MailSetting     = &Mail{}
+	OAuthSetting    = &OAuth{}
+	PaymentSetting  = &Payment{}
+	AlipaySetting   = &Alipay{}
 	DatabaseSetting = &Database{}
 )
 
@@ -51,6 +227,39 @@ func Init() error {
 		return err
 	}
 
+	// 加载JWT配置
+	if err := viper.UnmarshalKey("jwt", JWTSetting); err != nil {
+		return err
+	}
+
+	// 加载Security配置
+	if err := viper.UnmarshalKey("security", SecuritySetting); err != nil {
+		return err
+	}
+
+	// 加载Mail配置
+	if err := viper.UnmarshalKey("mail", MailSetting); err != nil {
+		return err
+	}
+
+	// 加载OAuth配置
+	if err := viper.UnmarshalKey("oauth", OAuthSetting); err != nil {
+		return err
+	}
+
+	// 加载Payment配置
+	if err := viper.UnmarshalKey("payment", PaymentSetting); err != nil {
+		return err
+	}
+
+	// 加载Alipay配置，环境变量优先于配置文件
+	if err := viper.UnmarshalKey("alipay", AlipaySetting); err != nil {
+		return err
+	}
+	if err := loadAlipayEnv(AlipaySetting); err != nil {
+		return err
+	}
+
 	// 加载Database配置
 	if err := viper.UnmarshalKey("database", DatabaseSetting); err != nil {
 		return err
@@ -58,3 +267,39 @@ func Init() error {
 
 	return nil
 }
+
+// loadAlipayEnv 使用 ALIPAY_* 环境变量覆盖支付宝配置，避免把密钥写入配置文件
+func loadAlipayEnv(cfg *Alipay) error {
+	strs := map[string]*string{
+		"ALIPAY_APP_ID":               &cfg.AppID,
+		"ALIPAY_PRIVATE_KEY":          &cfg.PrivateKey,
+		"ALIPAY_PRIVATE_KEY_FILE":     &cfg.PrivateKeyFile,
+		"ALIPAY_PUBLIC_KEY":           &cfg.AlipayPublicKey,
+		"ALIPAY_PUBLIC_KEY_FILE":      &cfg.AlipayPublicKeyFile,
+		"ALIPAY_APP_PUBLIC_CERT_FILE": &cfg.AppPublicCertFile,
+		"ALIPAY_ROOT_CERT_FILE":       &cfg.AlipayRootCertFile,
+		"ALIPAY_PUBLIC_CERT_FILE":     &cfg.AlipayPublicCertFile,
+		"ALIPAY_NOTIFY_URL":           &cfg.NotifyURL,
+		"ALIPAY_RETURN_URL":           &cfg.ReturnURL,
+	}
+	for key, field := range strs {
+		if value, ok := os.LookupEnv(key); ok {
+			*field = value
+		}
+	}
+
+	bools := map[string]*bool{
+		"ALIPAY_ENABLED": &cfg.Enabled,
+		"ALIPAY_IS_PROD": &cfg.IsProd,
+	}
+	for key, field := range bools {
+		if value, ok := os.LookupEnv(key); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 无效: %v", key, err)
+			}
+			*field = b
+		}
+	}
+	return nil
+}
@@ -1,54 +1,199 @@
 package util
 
 import (
+	"errors"
+	"fmt"
 	"interviewGenius/internal/pkg/setting"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims JWT 声明
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	jwt.StandardClaims
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`             // 角色名
+	PermVersion int64    `json:"perm_version"`      // 签发时用户的权限版本
+	Purpose     string   `json:"purpose,omitempty"` // 用途令牌的用途，访问令牌为空
+	SessionID   string   `json:"sid,omitempty"`     // 登录会话ID
+	jwt.RegisteredClaims
 }
 
+// 用途令牌的用途
+const (
+	PurposeTwoFactor     = "2fa"            // 两步验证挑战令牌
+	PurposeVerifyEmail   = "verify_email"   // 邮箱验证令牌
+	PurposeResetPassword = "reset_password" // 密码重置令牌
+)
+
+// ErrTokenPurpose 令牌用途不匹配
+var ErrTokenPurpose = errors.New("令牌用途不匹配")
+
 // GenerateToken 生成JWT令牌
-func GenerateToken(userID string, username string) (string, error) {
-	// 设置过期时间 - 24小时
-	expireTime := time.Now().Add(24 * time.Hour)
+func GenerateToken(userID string, username string, roles []string, permVersion int64, sessionID string) (string, error) {
+	now := time.Now()
 
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expireTime.Unix(),
-			IssuedAt:  time.Now().Unix(),
-			Issuer:    "interview-genius",
+		UserID:      userID,
+		Username:    username,
+		Roles:       roles,
+		PermVersion: permVersion,
+		SessionID:   sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID,
+			Issuer:    JWTIssuer(),
+			Audience:  jwt.ClaimStrings{JWTAudience()},
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := tokenClaims.SignedString([]byte(setting.AppSetting.JwtSecret))
-	return token, err
+	return signClaims(claims)
 }
 
 // ParseToken 解析JWT令牌
+// 固定签名算法，并校验 exp、nbf、iat、iss 与 aud，允许配置的时钟偏差
 func ParseToken(token string) (*Claims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(setting.AppSetting.JwtSecret), nil
-	})
+	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, keyFunc, parserOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := tokenClaims.Claims.(*Claims)
+	if !ok || !tokenClaims.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	// 用途令牌不能当作访问令牌使用
+	if claims.Purpose != "" {
+		return nil, ErrTokenPurpose
+	}
+
+	return claims, nil
+}
+
+// GenerateActionToken 生成指定用途的短期令牌，如两步验证挑战，返回令牌及其 jti
+func GenerateActionToken(userID string, username string, purpose string, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+	jti := uuid.NewString()
+
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Purpose:  purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userID,
+			Issuer:    JWTIssuer(),
+			Audience:  jwt.ClaimStrings{JWTAudience()},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := signClaims(claims)
+	return token, jti, err
+}
 
+// ParseActionToken 解析用途令牌，用途必须与 purpose 一致
+func ParseActionToken(token string, purpose string) (*Claims, error) {
+	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, keyFunc, parserOptions()...)
 	if err != nil {
 		return nil, err
 	}
 
-	if tokenClaims != nil {
-		if claims, ok := tokenClaims.Claims.(*Claims); ok && tokenClaims.Valid {
-			return claims, nil
-		}
+	claims, ok := tokenClaims.Claims.(*Claims)
+	if !ok || !tokenClaims.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrTokenPurpose
 	}
 
-	return nil, err
+	return claims, nil
+}
+
+// parserOptions 令牌解析选项
+func parserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods([]string{JWTAlgorithm()}),
+		jwt.WithIssuer(JWTIssuer()),
+		jwt.WithAudience(JWTAudience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(JWTLeeway()),
+	}
+}
+
+// JWTIssuer 令牌签发者
+func JWTIssuer() string {
+	if setting.JWTSetting.Issuer == "" {
+		return "interview-genius"
+	}
+	return setting.JWTSetting.Issuer
+}
+
+// JWTAudience 令牌受众
+func JWTAudience() string {
+	if setting.JWTSetting.Audience == "" {
+		return "interview-genius-api"
+	}
+	return setting.JWTSetting.Audience
+}
+
+// JWTLeeway 校验时间类声明时允许的时钟偏差
+func JWTLeeway() time.Duration {
+	return time.Duration(setting.JWTSetting.Leeway) * time.Second
+}
+
+// signClaims 使用当前配置的算法和密钥签名，非对称算法在头部写入 kid
+func signClaims(claims jwt.Claims) (string, error) {
+	alg := JWTAlgorithm()
+	if alg == "HS256" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(setting.AppSetting.JwtSecret))
+	}
+
+	key, err := currentSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	tokenClaims := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims)
+	tokenClaims.Header["kid"] = key.kid
+	return tokenClaims.SignedString(key.private)
+}
+
+// keyFunc 根据令牌头部选择验签密钥
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	alg := JWTAlgorithm()
+	if token.Method.Alg() != alg {
+		return nil, fmt.Errorf("不允许的签名算法: %s", token.Method.Alg())
+	}
+	if alg == "HS256" {
+		return []byte(setting.AppSetting.JwtSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return verificationKey(kid)
+}
+
+// AccessTokenTTL 访问令牌有效期，未配置时默认15分钟
+func AccessTokenTTL() time.Duration {
+	if setting.AppSetting.AccessTokenTTL <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(setting.AppSetting.AccessTokenTTL) * time.Minute
+}
+
+// RefreshTokenTTL 刷新令牌有效期，未配置时默认7天
+func RefreshTokenTTL() time.Duration {
+	if setting.AppSetting.RefreshTokenTTL <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return time.Duration(setting.AppSetting.RefreshTokenTTL) * time.Hour
 }
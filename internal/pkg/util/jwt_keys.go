@@ -0,0 +1,353 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"interviewGenius/internal/pkg/setting"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	privateKeySuffix = ".key.pem"
+	publicKeySuffix  = ".pub.pem"
+	autoKidPrefix    = "auto-"
+)
+
+// signingKey 签名密钥
+type signingKey struct {
+	kid       string
+	private   crypto.Signer
+	createdAt time.Time
+}
+
+// keySet 签名密钥与全部验签公钥
+type keySet struct {
+	mu      sync.RWMutex
+	signing *signingKey
+	public  map[string]crypto.PublicKey
+}
+
+var keys = &keySet{public: make(map[string]crypto.PublicKey)}
+
+// JWTAlgorithm 当前配置的签名算法，默认 HS256
+func JWTAlgorithm() string {
+	if setting.JWTSetting.Algorithm == "" {
+		return "HS256"
+	}
+	return setting.JWTSetting.Algorithm
+}
+
+// InitKeys 加载非对称签名密钥并启动定时轮换，HS256 模式下无需调用
+func InitKeys() error {
+	alg := JWTAlgorithm()
+	if alg == "HS256" {
+		return nil
+	}
+	if alg != "RS256" && alg != "EdDSA" {
+		return fmt.Errorf("不支持的JWT签名算法: %s", alg)
+	}
+
+	if err := os.MkdirAll(setting.JWTSetting.KeyDir, 0o700); err != nil {
+		return err
+	}
+	if err := rotateKeys(); err != nil {
+		return err
+	}
+
+	interval := time.Duration(setting.JWTSetting.ReloadInterval) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := rotateKeys(); err != nil {
+				zap.L().Error("轮换JWT密钥失败", zap.Error(err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// rotateKeys 按需生成新密钥并重新加载密钥目录
+func rotateKeys() error {
+	if err := loadKeys(); err != nil {
+		return err
+	}
+
+	rotateInterval := time.Duration(setting.JWTSetting.RotateInterval) * time.Hour
+	keys.mu.RLock()
+	current := keys.signing
+	keys.mu.RUnlock()
+
+	// 没有可用私钥，或开启自动轮换且当前密钥已超过轮换间隔时生成新密钥
+	if current == nil || (rotateInterval > 0 && setting.JWTSetting.ActiveKid == "" && time.Since(current.createdAt) > rotateInterval) {
+		kid, err := generateKey()
+		if err != nil {
+			return err
+		}
+		zap.L().Info("生成新的JWT签名密钥", zap.String("kid", kid))
+
+		if rotateInterval > 0 {
+			pruneKeys(2*rotateInterval + AccessTokenTTL())
+		}
+		return loadKeys()
+	}
+
+	return nil
+}
+
+// loadKeys 加载密钥目录中的全部密钥
+func loadKeys() error {
+	dir := setting.JWTSetting.KeyDir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var signing *signingKey
+	public := make(map[string]crypto.PublicKey)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		switch {
+		case strings.HasSuffix(name, privateKeySuffix):
+			kid := strings.TrimSuffix(name, privateKeySuffix)
+			private, err := readPrivateKey(path)
+			if err != nil {
+				return fmt.Errorf("加载私钥 %s 失败: %v", name, err)
+			}
+			if !keyMatchesAlgorithm(private.Public()) {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			public[kid] = private.Public()
+
+			key := &signingKey{kid: kid, private: private, createdAt: info.ModTime()}
+			if setting.JWTSetting.ActiveKid != "" {
+				if kid == setting.JWTSetting.ActiveKid {
+					signing = key
+				}
+			} else if signing == nil || key.createdAt.After(signing.createdAt) {
+				signing = key
+			}
+
+		case strings.HasSuffix(name, publicKeySuffix):
+			kid := strings.TrimSuffix(name, publicKeySuffix)
+			pub, err := readPublicKey(path)
+			if err != nil {
+				return fmt.Errorf("加载公钥 %s 失败: %v", name, err)
+			}
+			if !keyMatchesAlgorithm(pub) {
+				continue
+			}
+			if _, ok := public[kid]; !ok {
+				public[kid] = pub
+			}
+		}
+	}
+
+	if setting.JWTSetting.ActiveKid != "" && signing == nil {
+		return fmt.Errorf("未找到签名密钥: %s", setting.JWTSetting.ActiveKid)
+	}
+
+	keys.mu.Lock()
+	keys.signing = signing
+	keys.public = public
+	keys.mu.Unlock()
+
+	return nil
+}
+
+// generateKey 生成新的私钥文件，返回 kid
+func generateKey() (string, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	if JWTAlgorithm() == "EdDSA" {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	kid := autoKidPrefix + time.Now().Format("20060102150405")
+	path := filepath.Join(setting.JWTSetting.KeyDir, kid+privateKeySuffix)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+
+	return kid, nil
+}
+
+// pruneKeys 删除超过保留期的自动生成密钥
+func pruneKeys(retention time.Duration) {
+	dir := setting.JWTSetting.KeyDir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), autoKidPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) <= retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			zap.L().Warn("删除过期JWT密钥失败", zap.String("file", entry.Name()), zap.Error(err))
+		}
+	}
+}
+
+// readPrivateKey 读取 PEM 私钥，支持 PKCS8 与 PKCS1
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的PEM格式")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支持的私钥类型")
+	}
+	return signer, nil
+}
+
+// readPublicKey 读取 PEM 公钥，支持 PKIX 与 PKCS1
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的PEM格式")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// keyMatchesAlgorithm 判断密钥类型是否与配置的签名算法一致
+func keyMatchesAlgorithm(pub crypto.PublicKey) bool {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return JWTAlgorithm() == "RS256"
+	case ed25519.PublicKey:
+		return JWTAlgorithm() == "EdDSA"
+	}
+	return false
+}
+
+// currentSigningKey 获取当前签名密钥
+func currentSigningKey() (*signingKey, error) {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	if keys.signing == nil {
+		return nil, errors.New("未加载JWT签名密钥")
+	}
+	return keys.signing, nil
+}
+
+// verificationKey 根据 kid 获取验签公钥
+func verificationKey(kid string) (crypto.PublicKey, error) {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	pub, ok := keys.public[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥ID: %s", kid)
+	}
+	return pub, nil
+}
+
+// JWK JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 获取全部验签公钥的 JWK 集合，HS256 模式下为空
+func JWKS() []JWK {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	jwks := make([]JWK, 0, len(keys.public))
+	for kid, pub := range keys.public {
+		switch key := pub.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: "EdDSA",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(key),
+			})
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,52 @@
+package util
+
+import "strings"
+
+// DeviceName 根据 User-Agent 粗略识别设备，如 "Chrome on Windows"
+func DeviceName(userAgent string) string {
+	fields := strings.Fields(userAgent)
+	if len(fields) == 0 {
+		return "未知设备"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(userAgent, "iPhone"):
+		platform = "iPhone"
+	case strings.Contains(userAgent, "iPad"):
+		platform = "iPad"
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+
+	client := ""
+	switch {
+	case strings.Contains(userAgent, "MicroMessenger"):
+		client = "微信"
+	case strings.Contains(userAgent, "Edg/"):
+		client = "Edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		client = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		client = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		client = "Safari"
+	default:
+		// 非浏览器客户端取产品名，如 "curl/8.0"
+		client = strings.SplitN(fields[0], "/", 2)[0]
+	}
+
+	switch {
+	case platform == "":
+		return client
+	case client == "":
+		return platform
+	}
+	return client + " on " + platform
+}
@@ -4,8 +4,10 @@ import (
 	"interviewGenius/docs"
 	v1 "interviewGenius/internal/controller/v1"
 	"interviewGenius/internal/middleware"
+	"interviewGenius/internal/model"
 	"interviewGenius/internal/pkg/setting"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -30,10 +32,19 @@ func InitRouter() *gin.Engine {
 		c.String(http.StatusOK, docs.SwaggerInfo.SwaggerTemplate)
 	})
 
+	// JWT 验签公钥
+	r.GET("/.well-known/jwks.json", v1.GetJWKS)
+
 	// 初始化控制器
 	userController := v1.NewUserController()
 	roleController := v1.NewRoleController()
 	paymentController := v1.NewPaymentController()
+	apiKeyController := v1.NewAPIKeyController()
+	refundController := v1.NewRefundController()
+	reconcileController := v1.NewReconcileController()
+	couponController := v1.NewCouponController()
+	memberCardController := v1.NewMemberCardController()
+	subscriptionController := v1.NewSubscriptionController()
 
 	// API v1
 	apiV1 := r.Group("/api/v1")
@@ -44,14 +55,44 @@ func InitRouter() *gin.Engine {
 			// 无需认证的接口
 			users.POST("/register", userController.Register)
 			users.POST("/login", userController.Login)
+			users.POST("/login/2fa", userController.LoginTwoFactor)
+			users.POST("/login/2fa/setup", userController.SetupTwoFactorWithChallenge)
+			users.POST("/email/verify", userController.VerifyEmail)
+			users.POST("/password/forgot", userController.ForgotPassword)
+			users.POST("/password/reset", userController.ResetPassword)
 
 			// 需要认证的接口
-			users.Use(middleware.JWT())
+			users.Use(middleware.JWT(), middleware.CheckPermission())
 			{
 				users.GET("", userController.GetUserList)
 				users.GET("/:id", userController.GetUserInfo)
 				users.PUT("/:id", userController.UpdateUser)
 				users.DELETE("/:id", userController.DeleteUser)
+				users.POST("/:id/unlock", userController.UnlockUser)
+
+				// 当前用户的登录会话
+				users.GET("/me/sessions", userController.GetSessions)
+				users.DELETE("/me/sessions", userController.RevokeAllSessions)
+				users.DELETE("/me/sessions/:id", userController.RevokeSession)
+
+				// 当前用户的 API Key
+				users.GET("/me/api-keys", apiKeyController.GetUserAPIKeys)
+				users.POST("/me/api-keys", apiKeyController.CreateUserAPIKey)
+				users.DELETE("/me/api-keys/:id", apiKeyController.RevokeUserAPIKey)
+
+				// 当前用户的邮箱验证
+				users.POST("/me/email/verification", userController.SendVerificationEmail)
+
+				// 当前用户绑定的第三方账号
+				users.GET("/me/identities", userController.GetUserIdentities)
+				users.POST("/me/identities/:provider", userController.LinkIdentity)
+				users.DELETE("/me/identities/:provider", userController.UnlinkIdentity)
+
+				// 当前用户的两步验证
+				users.POST("/me/2fa/enroll", userController.EnrollTwoFactor)
+				users.POST("/me/2fa/confirm", userController.ConfirmTwoFactor)
+				users.POST("/me/2fa/recovery-codes", userController.RegenerateRecoveryCodes)
+				users.DELETE("/me/2fa", userController.DisableTwoFactor)
 
 				// 用户角色管理
 				users.POST("/:id/roles", userController.AddUserRoles)
@@ -60,9 +101,17 @@ func InitRouter() *gin.Engine {
 			}
 		}
 
+		// 第三方登录
+		oauthGroup := apiV1.Group("/oauth")
+		{
+			oauthGroup.GET("/providers", userController.GetOAuthProviders)
+			oauthGroup.GET("/:provider/login", userController.OAuthLogin)
+			oauthGroup.GET("/:provider/callback", userController.OAuthCallback)
+		}
+
 		// 角色管理
 		roles := apiV1.Group("/roles")
-		roles.Use(middleware.JWT())
+		roles.Use(middleware.JWT(), middleware.CheckPermission())
 		{
 			roles.POST("", roleController.CreateRole)
 			roles.GET("", roleController.GetRoleList)
@@ -74,14 +123,20 @@ func InitRouter() *gin.Engine {
 			roles.POST("/:id/permissions", roleController.AddRolePermissions)
 			roles.GET("/:id/permissions", roleController.GetRolePermissions)
 			roles.DELETE("/:id/permissions/:permissionId", roleController.RemoveRolePermission)
+
+			// 角色的 API Key
+			roles.GET("/:id/api-keys", apiKeyController.GetRoleAPIKeys)
+			roles.POST("/:id/api-keys", apiKeyController.CreateRoleAPIKey)
+			roles.DELETE("/:id/api-keys/:keyId", apiKeyController.RevokeRoleAPIKey)
 		}
 
 		// 权限管理
 		permissions := apiV1.Group("/permissions")
-		permissions.Use(middleware.JWT())
+		permissions.Use(middleware.JWT(), middleware.CheckPermission())
 		{
 			permissions.POST("", v1.CreatePermission)
 			permissions.GET("", v1.GetPermissionList)
+			permissions.GET("/report", v1.GetPermissionReport)
 			permissions.GET("/:id", v1.GetPermissionDetail)
 			permissions.PUT("/:id", v1.UpdatePermission)
 			permissions.DELETE("/:id", v1.DeletePermission)
@@ -89,10 +144,16 @@ func InitRouter() *gin.Engine {
 
 		// 权限验证
 		auth := apiV1.Group("/auth")
-		auth.Use(middleware.JWT())
 		{
-			auth.POST("/verify", v1.VerifyPermission)
+			// 无需认证的接口
 			auth.POST("/refresh", v1.RefreshToken)
+
+			// 需要认证的接口
+			auth.Use(middleware.JWT(), middleware.CheckPermission())
+			{
+				auth.POST("/verify", v1.VerifyPermission)
+				auth.POST("/logout", v1.Logout)
+			}
 		}
 
 		// 会员相关接口
@@ -102,24 +163,100 @@ func InitRouter() *gin.Engine {
 			member.GET("/cards", v1.GetMemberCards)
 
 			// 需要认证的接口
-			member.Use(middleware.JWT())
+			member.Use(middleware.JWT(), middleware.CheckPermission())
 			{
 				member.GET("/info", v1.GetMemberInfo)
 				member.POST("/order", v1.CreateOrder)
 				member.POST("/order/:id/pay", v1.PayOrder)
 				member.GET("/orders", v1.GetOrders)
+				member.GET("/orders/:id/events", v1.GetOrderEvents)
 				member.GET("/check", v1.CheckServiceAccess)
+				member.POST("/subscriptions", subscriptionController.CreateSubscription)
+				member.GET("/subscriptions", subscriptionController.GetSubscriptions)
+				member.POST("/subscriptions/:id/cancel", subscriptionController.CancelSubscription)
 			}
 		}
 
+		// 会员卡管理
+		memberCards := apiV1.Group("/member-cards")
+		memberCards.Use(middleware.JWT(), middleware.CheckPermission())
+		{
+			memberCards.GET("", memberCardController.GetMemberCardList)
+			memberCards.POST("", memberCardController.CreateMemberCard)
+			memberCards.PUT("/sort", memberCardController.SortMemberCards)
+			memberCards.PUT("/:id", memberCardController.UpdateMemberCard)
+			memberCards.DELETE("/:id", memberCardController.DeleteMemberCard)
+			memberCards.GET("/:id/prices", memberCardController.GetMemberCardPrices)
+			memberCards.POST("/:id/prices", memberCardController.CreateMemberCardPrice)
+			memberCards.DELETE("/:id/prices/:priceId", memberCardController.DeleteMemberCardPrice)
+		}
+
+		// 订单管理
+		orders := apiV1.Group("/orders")
+		orders.Use(middleware.JWT(), middleware.CheckPermission())
+		{
+			orders.POST("/:id/confirm", paymentController.ConfirmPayment)
+			orders.GET("/:id/refunds", refundController.GetOrderRefunds)
+			orders.POST("/:id/refunds", refundController.CreateRefund)
+			orders.POST("/:id/refunds/:refundId/sync", refundController.SyncRefund)
+		}
+
+		// 优惠券管理
+		coupons := apiV1.Group("/coupons")
+		coupons.Use(middleware.JWT(), middleware.CheckPermission())
+		{
+			coupons.POST("", couponController.CreateCoupon)
+			coupons.GET("", couponController.GetCouponList)
+			coupons.GET("/:id", couponController.GetCoupon)
+			coupons.PUT("/:id", couponController.UpdateCoupon)
+			coupons.DELETE("/:id", couponController.DeleteCoupon)
+		}
+
+		// 账单核对
+		reconciliations := apiV1.Group("/reconciliations")
+		reconciliations.Use(middleware.JWT(), middleware.CheckPermission())
+		{
+			reconciliations.GET("", reconcileController.GetReconciliations)
+			reconciliations.POST("", reconcileController.CreateReconciliation)
+			reconciliations.GET("/:id", reconcileController.GetReconciliation)
+		}
+
 		// 支付相关路由
 		paymentGroup := apiV1.Group("/payment")
 		{
-			paymentGroup.POST("/create", paymentController.CreatePayment)
+			// 无需认证的接口，通知和跳转由渠道签名校验
+			paymentGroup.GET("/providers", paymentController.GetPaymentProviders)
 			paymentGroup.POST("/notify", paymentController.HandlePaymentNotify)
+			paymentGroup.POST("/notify/:provider", paymentController.HandlePaymentNotify)
 			paymentGroup.GET("/return", paymentController.HandlePaymentReturn)
+			paymentGroup.GET("/return/:provider", paymentController.HandlePaymentReturn)
+			paymentGroup.POST("/agreement/notify/:provider", subscriptionController.HandleAgreementNotify)
+			paymentGroup.GET("/sandbox/checkout", paymentController.SandboxCheckout)
+
+			// 需要认证的接口
+			paymentGroup.Use(middleware.JWT(), middleware.CheckPermission())
+			{
+				paymentGroup.POST("/create", paymentController.CreatePayment)
+			}
 		}
 	}
 
 	return r
 }
+
+// SyncPermissions 根据已注册的 API 路由同步权限表
+func SyncPermissions(r *gin.Engine) error {
+	routes := make([]model.RouteInfo, 0)
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		routes = append(routes, model.RouteInfo{
+			Method: route.Method,
+			Path:   route.Path,
+		})
+	}
+
+	_, err := model.SyncRoutePermissions(routes)
+	return err
+}
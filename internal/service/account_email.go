@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/loginguard"
+	"interviewGenius/internal/pkg/mailer"
+	"interviewGenius/internal/pkg/setting"
+	"interviewGenius/internal/pkg/util"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrMailTooFrequent 邮件发送过于频繁
+var ErrMailTooFrequent = errors.New("发送过于频繁，请稍后再试")
+
+// SendVerificationEmail 向当前用户的邮箱发送验证邮件
+func (s *UserService) SendVerificationEmail(userID uuid.UUID) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("邮箱已验证")
+	}
+	return s.sendVerificationEmail(user)
+}
+
+// VerifyEmail 使用邮件中的令牌完成邮箱验证
+func (s *UserService) VerifyEmail(token string) error {
+	record, err := consumeActionToken(token, util.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	return model.MarkEmailVerified(record.UserID, record.Email)
+}
+
+// RequestPasswordReset 发送密码重置邮件
+// 邮箱不存在或发送过于频繁时同样返回成功，避免泄露邮箱是否已注册
+func (s *UserService) RequestPasswordReset(email string) error {
+	user, err := model.GetUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	ttl := resetTokenTTL()
+	link, err := s.issueActionLink(user, util.PurposeResetPassword, ttl, setting.SecuritySetting.Email.ResetURL)
+	if err != nil {
+		if errors.Is(err, ErrMailTooFrequent) {
+			return nil
+		}
+		return err
+	}
+
+	return mailer.Default().Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 %d 分钟内打开以下链接重置密码：\n%s\n\n如果不是您本人操作，请忽略此邮件。\n",
+			user.Username, int(ttl.Minutes()), link),
+	})
+}
+
+// ResetPassword 使用邮件中的令牌重置密码，并使已签发的令牌全部失效
+func (s *UserService) ResetPassword(token, newPassword string) error {
+	claims, err := util.ParseActionToken(token, util.PurposeResetPassword)
+	if err != nil {
+		return model.ErrActionTokenInvalid
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.ErrActionTokenInvalid
+	}
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return model.ErrActionTokenInvalid
+	}
+
+	// 先校验密码策略，不符合时令牌仍可继续使用
+	if err := validatePassword("new_password", newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	record, err := consumeActionToken(token, util.PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	if user.Email != record.Email {
+		return model.ErrActionTokenInvalid
+	}
+
+	if err := model.UpdateUserPassword(user.ID, newPassword); err != nil {
+		return err
+	}
+
+	// 能收到重置邮件即证明拥有该邮箱
+	if user.EmailVerifiedAt == nil {
+		if err := model.MarkEmailVerified(user.ID, user.Email); err != nil {
+			zap.L().Warn("标记邮箱已验证失败", zap.String("user_id", user.ID.String()), zap.Error(err))
+		}
+	}
+
+	if err := loginguard.Reset(loginUserKey(user.Username)); err != nil {
+		zap.L().Warn("清除登录失败记录失败", zap.String("user_id", user.ID.String()), zap.Error(err))
+	}
+	return s.RevokeUserTokens(user.ID)
+}
+
+// sendVerificationEmail 签发邮箱验证令牌并发送邮件
+func (s *UserService) sendVerificationEmail(user *model.User) error {
+	ttl := verifyTokenTTL()
+	link, err := s.issueActionLink(user, util.PurposeVerifyEmail, ttl, setting.SecuritySetting.Email.VerifyURL)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Default().Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "验证您的邮箱",
+		Body: fmt.Sprintf("%s，您好：\n\n请在 %d 小时内打开以下链接完成邮箱验证：\n%s\n\n如果不是您本人操作，请忽略此邮件。\n",
+			user.Username, int(ttl.Hours()), link),
+	})
+}
+
+// issueActionLink 签发一次性令牌并生成邮件中的链接
+func (s *UserService) issueActionLink(user *model.User, purpose string, ttl time.Duration, baseURL string) (string, error) {
+	// 限制发送频率
+	if interval := resendInterval(); interval > 0 {
+		last, err := model.LastUserActionTokenAt(user.ID, purpose)
+		if err != nil {
+			return "", err
+		}
+		if last != nil && time.Since(*last) < interval {
+			return "", ErrMailTooFrequent
+		}
+	}
+
+	token, jti, err := util.GenerateActionToken(user.ID.String(), user.Username, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	if err := model.CreateUserActionToken(&model.UserActionToken{
+		JTI:       jti,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return actionLink(baseURL, token), nil
+}
+
+// consumeActionToken 校验令牌签名和用途，并标记为已使用
+func consumeActionToken(token, purpose string) (*model.UserActionToken, error) {
+	claims, err := util.ParseActionToken(token, purpose)
+	if err != nil {
+		return nil, model.ErrActionTokenInvalid
+	}
+
+	record, err := model.ConsumeUserActionToken(claims.ID, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserID.String() != claims.UserID {
+		return nil, model.ErrActionTokenInvalid
+	}
+	return record, nil
+}
+
+// actionLink 将令牌附加到页面地址，未配置地址时直接返回令牌
+func actionLink(baseURL, token string) string {
+	if baseURL == "" {
+		return token
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return token
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// verifyTokenTTL 邮箱验证令牌有效期，未配置时默认24小时
+func verifyTokenTTL() time.Duration {
+	if setting.SecuritySetting.Email.VerifyTokenTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(setting.SecuritySetting.Email.VerifyTokenTTL) * time.Hour
+}
+
+// resetTokenTTL 密码重置令牌有效期，未配置时默认30分钟
+func resetTokenTTL() time.Duration {
+	if setting.SecuritySetting.Email.ResetTokenTTL <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(setting.SecuritySetting.Email.ResetTokenTTL) * time.Minute
+}
+
+// resendInterval 同一用户两次发送邮件的最小间隔
+func resendInterval() time.Duration {
+	return time.Duration(setting.SecuritySetting.Email.ResendInterval) * time.Second
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/util"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyService struct{}
+
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{}
+}
+
+// CreateUserAPIKey 为用户创建 API Key，权限范围不能超出用户自身的权限
+func (s *APIKeyService) CreateUserAPIKey(userID uuid.UUID, req *dto.CreateAPIKeyRequest) (*dto.CreateAPIKeyResponse, error) {
+	scopes, err := s.loadScopes(req, func(p *model.Permission) (bool, error) {
+		return model.CheckUserPermission(userID, p.Method, p.PathPattern)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.create(&model.APIKey{
+		Name:      req.Name,
+		UserID:    &userID,
+		CreatedBy: userID,
+		ExpiresAt: req.ExpiresAt,
+		Scopes:    scopes,
+	})
+}
+
+// CreateRoleAPIKey 为角色创建 API Key，权限范围不能超出角色和创建者的权限
+func (s *APIKeyService) CreateRoleAPIKey(roleID uint, creatorID uuid.UUID, req *dto.CreateAPIKeyRequest) (*dto.CreateAPIKeyResponse, error) {
+	if _, err := model.GetRoleByID(roleID); err != nil {
+		return nil, errors.New("角色不存在")
+	}
+
+	scopes, err := s.loadScopes(req, func(p *model.Permission) (bool, error) {
+		ok, err := model.CheckRolePermission(roleID, p.Method, p.PathPattern)
+		if err != nil || !ok {
+			return ok, err
+		}
+		return model.CheckUserPermission(creatorID, p.Method, p.PathPattern)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.create(&model.APIKey{
+		Name:      req.Name,
+		RoleID:    &roleID,
+		CreatedBy: creatorID,
+		ExpiresAt: req.ExpiresAt,
+		Scopes:    scopes,
+	})
+}
+
+// GetUserAPIKeys 获取用户的 API Key
+func (s *APIKeyService) GetUserAPIKeys(userID uuid.UUID) ([]dto.APIKeyResponse, error) {
+	keys, err := model.GetUserAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyResponses(keys), nil
+}
+
+// GetRoleAPIKeys 获取角色的 API Key
+func (s *APIKeyService) GetRoleAPIKeys(roleID uint) ([]dto.APIKeyResponse, error) {
+	keys, err := model.GetRoleAPIKeys(roleID)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyResponses(keys), nil
+}
+
+// RevokeUserAPIKey 吊销用户的 API Key
+func (s *APIKeyService) RevokeUserAPIKey(userID, id uuid.UUID) error {
+	return model.RevokeUserAPIKey(userID, id)
+}
+
+// RevokeRoleAPIKey 吊销角色的 API Key
+func (s *APIKeyService) RevokeRoleAPIKey(roleID uint, id uuid.UUID) error {
+	return model.RevokeRoleAPIKey(roleID, id)
+}
+
+// loadScopes 加载请求的权限范围，并逐项检查归属者是否拥有该权限
+func (s *APIKeyService) loadScopes(req *dto.CreateAPIKeyRequest, allowed func(*model.Permission) (bool, error)) ([]*model.Permission, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("过期时间必须晚于当前时间")
+	}
+
+	scopes, err := model.GetPermissionsByIDs(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) != len(uniqueStrings(req.Scopes)) {
+		return nil, errors.New("权限不存在")
+	}
+
+	for _, scope := range scopes {
+		ok, err := allowed(scope)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("不能授予自身没有的权限: " + scope.Method + " " + scope.PathPattern)
+		}
+	}
+	return scopes, nil
+}
+
+// create 生成密钥并保存摘要，完整密钥只在此处返回
+func (s *APIKeyService) create(key *model.APIKey) (*dto.CreateAPIKeyResponse, error) {
+	raw, prefix, err := util.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix = prefix
+	key.KeyHash = util.HashToken(raw)
+
+	if err := model.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	return &dto.CreateAPIKeyResponse{
+		APIKeyResponse: apiKeyResponse(key),
+		Key:            raw,
+	}, nil
+}
+
+// apiKeyResponses 转换 API Key 列表
+func apiKeyResponses(keys []*model.APIKey) []dto.APIKeyResponse {
+	resp := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, apiKeyResponse(key))
+	}
+	return resp
+}
+
+// apiKeyResponse 转换 API Key，不包含密钥摘要
+func apiKeyResponse(key *model.APIKey) dto.APIKeyResponse {
+	scopes := make([]dto.PermissionInfo, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, dto.PermissionInfo{
+			ID:          scope.ID.String(),
+			Method:      scope.Method,
+			PathPattern: scope.PathPattern,
+			Description: scope.Description,
+		})
+	}
+
+	return dto.APIKeyResponse{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		UsageCount: key.UsageCount,
+		Revoked:    key.RevokedAt != nil,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// uniqueStrings 去除重复项
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+)
+
+type CouponService struct{}
+
+func NewCouponService() *CouponService {
+	return &CouponService{}
+}
+
+// CreateCoupon 创建优惠券
+func (s *CouponService) CreateCoupon(req *dto.CouponRequest) (*model.Coupon, error) {
+	coupon := &model.Coupon{IsActive: true}
+	if err := s.apply(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := model.CreateCoupon(coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// GetCouponList 分页获取优惠券
+func (s *CouponService) GetCouponList(page, limit int) ([]*model.Coupon, int64, error) {
+	return model.GetCouponList(page, limit)
+}
+
+// GetCoupon 获取优惠券详情
+func (s *CouponService) GetCoupon(id uint) (*model.Coupon, error) {
+	return model.GetCouponByID(id)
+}
+
+// UpdateCoupon 更新优惠券，已下单的订单不受影响
+func (s *CouponService) UpdateCoupon(id uint, req *dto.CouponRequest) (*model.Coupon, error) {
+	coupon, err := model.GetCouponByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := model.UpdateCoupon(coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// DeleteCoupon 删除优惠券，未支付订单已占用的优惠不受影响
+func (s *CouponService) DeleteCoupon(id uint) error {
+	return model.DeleteCoupon(id)
+}
+
+// apply 校验请求并写入优惠券
+func (s *CouponService) apply(coupon *model.Coupon, req *dto.CouponRequest) error {
+	if req.Type == string(model.CouponTypePercent) && req.Value > 100 {
+		return errors.New("减免百分比不能超过 100")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New("失效时间必须晚于生效时间")
+	}
+
+	exists, err := model.CouponCodeExists(req.Code, coupon.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("优惠码已存在")
+	}
+
+	cards, err := model.GetMemberCardsByIDs(req.CardIDs)
+	if err != nil {
+		return err
+	}
+	if len(cards) != len(uniqueIDs(req.CardIDs)) {
+		return errors.New("会员卡不存在")
+	}
+
+	coupon.Code = req.Code
+	coupon.Name = req.Name
+	coupon.Type = model.CouponType(req.Type)
+	coupon.Value = req.Value
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.TotalLimit = req.TotalLimit
+	coupon.PerUserLimit = req.PerUserLimit
+	coupon.FirstPurchaseOnly = req.FirstPurchaseOnly
+	if req.IsActive != nil {
+		coupon.IsActive = *req.IsActive
+	}
+	coupon.Cards = cards
+	return nil
+}
+
+// uniqueIDs 去除重复的ID
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
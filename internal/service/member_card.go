@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MemberCardService struct{}
+
+func NewMemberCardService() *MemberCardService {
+	return &MemberCardService{}
+}
+
+// GetMemberCardList 获取全部会员卡，包括已下架的会员卡
+func (s *MemberCardService) GetMemberCardList() ([]*model.MemberCard, error) {
+	return model.GetMemberCardList()
+}
+
+// CreateMemberCard 创建会员卡
+func (s *MemberCardService) CreateMemberCard(req *dto.CreateMemberCardRequest, operatorID uuid.UUID) (*model.MemberCard, error) {
+	card := &model.MemberCard{
+		Name:         req.Name,
+		DurationDays: req.DurationDays,
+		Price:        req.Price,
+		Description:  req.Description,
+		SortOrder:    req.SortOrder,
+		IsActive:     req.IsActive == nil || *req.IsActive,
+	}
+	if err := model.CreateMemberCard(card, &model.MemberCardPrice{OperatorID: operatorID}); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// UpdateMemberCard 更新会员卡信息
+func (s *MemberCardService) UpdateMemberCard(id uint, req *dto.UpdateMemberCardRequest) (*model.MemberCard, error) {
+	card, err := model.GetMemberCardByID(id)
+	if err != nil {
+		return nil, err
+	}
+	card.Name = req.Name
+	card.DurationDays = req.DurationDays
+	card.Description = req.Description
+	card.SortOrder = req.SortOrder
+	card.IsActive = req.IsActive
+	if err := model.UpdateMemberCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// SortMemberCards 调整会员卡的展示顺序
+func (s *MemberCardService) SortMemberCards(ids []uint) error {
+	cards, err := model.GetMemberCardsByIDs(ids)
+	if err != nil {
+		return err
+	}
+	if len(uniqueIDs(ids)) != len(ids) || len(cards) != len(ids) {
+		return errors.New("会员卡不存在或重复")
+	}
+	return model.SortMemberCards(ids)
+}
+
+// DeleteMemberCard 归档会员卡
+func (s *MemberCardService) DeleteMemberCard(id uint) error {
+	return model.DeleteMemberCard(id)
+}
+
+// GetPrices 获取会员卡的调价记录
+func (s *MemberCardService) GetPrices(cardID uint) ([]*model.MemberCardPrice, error) {
+	if _, err := model.GetMemberCardByID(cardID); err != nil {
+		return nil, err
+	}
+	return model.GetMemberCardPrices(cardID)
+}
+
+// SchedulePrice 立即或定时调整会员卡价格，已创建的订单金额不受影响
+func (s *MemberCardService) SchedulePrice(cardID uint, req *dto.MemberCardPriceRequest, operatorID uuid.UUID) (*model.MemberCardPrice, error) {
+	price := &model.MemberCardPrice{
+		CardID:     cardID,
+		Price:      req.Price,
+		OperatorID: operatorID,
+	}
+	if req.EffectiveAt != nil {
+		price.EffectiveAt = *req.EffectiveAt
+	} else {
+		price.EffectiveAt = time.Now()
+	}
+	if err := model.ScheduleMemberCardPrice(price); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// CancelPrice 取消尚未生效的调价
+func (s *MemberCardService) CancelPrice(cardID, priceID uint) error {
+	return model.CancelMemberCardPrice(cardID, priceID)
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/oauth"
+	"interviewGenius/internal/pkg/setting"
+	"interviewGenius/internal/pkg/util"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// usernameInvalidChars 自动注册时用户名中需要去掉的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// OAuthLoginURL 生成第三方登录授权地址，linkUserID 不为空时为绑定流程
+func (s *UserService) OAuthLoginURL(provider string, linkUserID *uuid.UUID) (string, error) {
+	p, ok := oauth.Get(provider)
+	if !ok {
+		return "", errors.New("不支持的第三方登录方式")
+	}
+
+	state, err := oauth.GenerateState()
+	if err != nil {
+		return "", err
+	}
+
+	var verifier, challenge string
+	if p.SupportsPKCE() {
+		if verifier, err = oauth.GenerateVerifier(); err != nil {
+			return "", err
+		}
+		challenge = oauth.ChallengeS256(verifier)
+	}
+
+	if err := model.CreateOAuthState(&model.OAuthState{
+		State:        state,
+		Provider:     provider,
+		CodeVerifier: verifier,
+		UserID:       linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL()),
+	}); err != nil {
+		return "", err
+	}
+
+	return p.AuthCodeURL(state, challenge), nil
+}
+
+// OAuthCallback 处理第三方登录回调
+// 已绑定的账号直接登录；绑定流程中绑定到发起用户；
+// 否则按已验证的邮箱关联已有用户，或按配置自动注册。返回值 linked 表示本次为绑定流程
+func (s *UserService) OAuthCallback(ctx context.Context, provider, state, code string, client ClientInfo) (resp *dto.TokenResponse, linked bool, err error) {
+	p, ok := oauth.Get(provider)
+	if !ok {
+		return nil, false, errors.New("不支持的第三方登录方式")
+	}
+
+	record, err := model.ConsumeOAuthState(state, provider)
+	if err != nil {
+		return nil, false, err
+	}
+
+	info, err := p.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		zap.L().Warn("第三方登录授权失败", zap.String("provider", provider), zap.Error(err))
+		return nil, false, errors.New("第三方登录授权失败")
+	}
+
+	identity, err := model.GetUserIdentity(provider, info.Subject)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 绑定流程
+	if record.UserID != nil {
+		if identity != nil && identity.UserID != *record.UserID {
+			return nil, true, errors.New("该第三方账号已绑定其他用户")
+		}
+		user, err := model.GetUserByID(*record.UserID)
+		if err != nil {
+			return nil, true, errors.New("用户不存在")
+		}
+		if identity == nil {
+			if err := model.CreateUserIdentity(newUserIdentity(user.ID, provider, info)); err != nil {
+				return nil, true, err
+			}
+		}
+		return &dto.TokenResponse{ID: user.ID.String(), Username: user.Username}, true, nil
+	}
+
+	var user *model.User
+	if identity != nil {
+		if user, err = model.GetUserByID(identity.UserID); err != nil {
+			return nil, false, errors.New("用户不存在")
+		}
+	} else {
+		if user, err = s.resolveOAuthUser(provider, info); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// 已启用或策略要求两步验证时同样需要第二步
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, false, err
+	}
+	if challenge != nil {
+		return challenge, false, nil
+	}
+
+	resp, err = s.issueTokens(user, client)
+	return resp, false, err
+}
+
+// GetUserIdentities 获取用户绑定的第三方账号
+func (s *UserService) GetUserIdentities(userID uuid.UUID) ([]*model.UserIdentity, error) {
+	return model.GetUserIdentities(userID)
+}
+
+// UnlinkIdentity 解除第三方账号绑定
+// 未验证邮箱的用户无法通过邮件找回密码，至少保留一个第三方账号
+func (s *UserService) UnlinkIdentity(userID uuid.UUID, provider string) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		identities, err := model.GetUserIdentities(userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return errors.New("请先验证邮箱再解除最后一个第三方账号")
+		}
+	}
+
+	return model.DeleteUserIdentity(userID, provider)
+}
+
+// resolveOAuthUser 为未绑定的第三方账号关联已有用户或自动注册
+func (s *UserService) resolveOAuthUser(provider string, info *oauth.UserInfo) (*model.User, error) {
+	if info.Email != "" && info.EmailVerified {
+		existing, err := model.GetUserByEmail(info.Email)
+		if err == nil {
+			// 本地邮箱未经验证时不能确认归属，避免抢注邮箱劫持账号
+			if existing.EmailVerifiedAt == nil {
+				return nil, errors.New("该邮箱已注册，请使用密码登录后绑定")
+			}
+			if err := model.CreateUserIdentity(newUserIdentity(existing.ID, provider, info)); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+
+	if !setting.OAuthSetting.AutoRegister {
+		return nil, errors.New("该第三方账号未绑定用户")
+	}
+	return s.registerOAuthUser(provider, info)
+}
+
+// registerOAuthUser 使用第三方账号信息自动注册用户
+func (s *UserService) registerOAuthUser(provider string, info *oauth.UserInfo) (*model.User, error) {
+	username, err := oauthUsername(provider, info)
+	if err != nil {
+		return nil, err
+	}
+
+	// 随机密码，用户可通过找回密码设置
+	password, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username: username,
+		Password: password,
+		Email:    oauthEmail(provider, info),
+	}
+	if info.Email != "" && info.EmailVerified && user.Email == info.Email {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := model.CreateUser(user); err != nil {
+		return nil, err
+	}
+	if err := model.CreateUserIdentity(newUserIdentity(user.ID, provider, info)); err != nil {
+		return nil, err
+	}
+
+	// 分配默认的普通用户角色
+	if role, err := model.GetRoleByName(model.DefaultRoleName); err == nil {
+		if err := model.AddRolesToUser(user.ID, []uint{role.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return model.GetUserByID(user.ID)
+}
+
+// newUserIdentity 组装绑定记录
+func newUserIdentity(userID uuid.UUID, provider string, info *oauth.UserInfo) *model.UserIdentity {
+	return &model.UserIdentity{
+		UserID:    userID,
+		Provider:  provider,
+		Subject:   info.Subject,
+		Email:     info.Email,
+		Name:      info.Name,
+		AvatarURL: info.AvatarURL,
+	}
+}
+
+// oauthUsername 生成不重复的用户名，优先使用昵称或邮箱前缀
+func oauthUsername(provider string, info *oauth.UserInfo) (string, error) {
+	base := info.Name
+	if base == "" && info.Email != "" {
+		base = strings.SplitN(info.Email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = provider + "_" + base
+	}
+	if len(base) > 20 {
+		base = base[:20]
+	}
+
+	if _, err := model.GetUserByUsername(base); err != nil {
+		return base, nil
+	}
+	for i := 0; i < 5; i++ {
+		suffix, err := util.GenerateOpaqueToken()
+		if err != nil {
+			return "", err
+		}
+		candidate := base + "_" + strings.ToLower(usernameInvalidChars.ReplaceAllString(suffix, ""))[:6]
+		if _, err := model.GetUserByUsername(candidate); err != nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("生成用户名失败")
+}
+
+// oauthEmail 使用第三方邮箱，没有或已被占用时使用不可投递的占位邮箱
+func oauthEmail(provider string, info *oauth.UserInfo) string {
+	if info.Email != "" && info.EmailVerified {
+		if _, err := model.GetUserByEmail(info.Email); err != nil {
+			return info.Email
+		}
+	}
+	sum := sha256.Sum256([]byte(provider + ":" + info.Subject))
+	return provider + "_" + hex.EncodeToString(sum[:8]) + "@oauth.invalid"
+}
+
+// oauthStateTTL 授权请求有效期，未配置时默认10分钟
+func oauthStateTTL() time.Duration {
+	if setting.OAuthSetting.StateTTL <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(setting.OAuthSetting.StateTTL) * time.Minute
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/oauth"
+	"interviewGenius/internal/pkg/setting"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// setupOAuthTest 使用内存数据库和模拟 OIDC 提供方
+func setupOAuthTest(t *testing.T, user oauth.UserInfo) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.Role{}, &model.UserTOTP{}, &model.UserIdentity{},
+		&model.OAuthState{}, &model.Session{}, &model.RefreshToken{}); err != nil {
+		t.Fatalf("迁移数据库表失败: %v", err)
+	}
+	if err := db.SetupJoinTable(&model.User{}, "Roles", &model.UserRole{}); err != nil {
+		t.Fatalf("设置User-Role关联表失败: %v", err)
+	}
+	if err := db.Create(&model.Role{RoleName: model.DefaultRoleName, Name: "普通用户"}).Error; err != nil {
+		t.Fatalf("创建默认角色失败: %v", err)
+	}
+
+	previousDB := model.DB
+	model.DB = db
+	setting.AppSetting.JwtSecret = "oauth_test_secret"
+	setting.OAuthSetting.AutoRegister = true
+
+	srv := httptest.NewServer(nil)
+	srv.Config.Handler = oauth.NewMockServer(srv.URL, user)
+
+	t.Cleanup(func() {
+		srv.Close()
+		model.DB = previousDB
+		setting.OAuthSetting.AutoRegister = false
+	})
+
+	provider, err := oauth.NewProvider(context.Background(), oauth.Config{
+		Name:         "mock",
+		Type:         "oidc",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/v1/auth/oauth/mock/callback",
+		Issuer:       srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("创建提供方失败: %v", err)
+	}
+	oauth.Register(provider)
+}
+
+// authorize 发起授权并返回回调中的 state 和授权码
+func authorize(t *testing.T, s *UserService) (state, code string, loginURL *url.URL) {
+	t.Helper()
+
+	rawURL, err := s.OAuthLoginURL("mock", nil)
+	if err != nil {
+		t.Fatalf("生成授权地址失败: %v", err)
+	}
+	loginURL, err = url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("解析授权地址失败: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		t.Fatalf("请求授权端点失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("授权端点状态码 = %d, 期望 302", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("解析回调地址失败: %v", err)
+	}
+	return callback.Query().Get("state"), callback.Query().Get("code"), loginURL
+}
+
+func TestOAuthStateAndPKCE(t *testing.T) {
+	setupOAuthTest(t, oauth.UserInfo{Subject: "u1", Email: "u1@example.com", EmailVerified: true})
+	s := NewUserService()
+
+	state, code, loginURL := authorize(t, s)
+	if state != loginURL.Query().Get("state") {
+		t.Fatalf("回调 state = %q, 期望 %q", state, loginURL.Query().Get("state"))
+	}
+	if loginURL.Query().Get("code_challenge") == "" || loginURL.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("授权地址缺少 PKCE 参数: %s", loginURL)
+	}
+
+	// 错误的 code_verifier 无法换取令牌
+	provider, _ := oauth.Get("mock")
+	if _, err := provider.Exchange(context.Background(), code, "wrong_verifier"); err == nil {
+		t.Fatal("错误的 code_verifier 换取令牌成功")
+	}
+
+	// 未知的 state 被拒绝
+	if _, _, err := s.OAuthCallback(context.Background(), "mock", "unknown", code, ClientInfo{}); !errors.Is(err, model.ErrOAuthStateInvalid) {
+		t.Fatalf("未知 state 的错误 = %v, 期望 %v", err, model.ErrOAuthStateInvalid)
+	}
+
+	// state 只能使用一次，授权码也只能兑换一次
+	state, code, _ = authorize(t, s)
+	if _, _, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{}); err != nil {
+		t.Fatalf("回调失败: %v", err)
+	}
+	if _, _, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{}); !errors.Is(err, model.ErrOAuthStateInvalid) {
+		t.Fatalf("重复使用 state 的错误 = %v, 期望 %v", err, model.ErrOAuthStateInvalid)
+	}
+}
+
+func TestOAuthCallbackAutoRegister(t *testing.T) {
+	setupOAuthTest(t, oauth.UserInfo{Subject: "new-user", Email: "new@example.com", EmailVerified: true, Name: "new_user"})
+	s := NewUserService()
+
+	state, code, _ := authorize(t, s)
+	resp, linked, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{UserAgent: "go-test"})
+	if err != nil {
+		t.Fatalf("回调失败: %v", err)
+	}
+	if linked {
+		t.Fatal("登录流程被识别为绑定流程")
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatal("自动注册后未签发令牌")
+	}
+
+	user, err := model.GetUserByEmail("new@example.com")
+	if err != nil {
+		t.Fatalf("未创建用户: %v", err)
+	}
+	if user.ID.String() != resp.ID {
+		t.Fatalf("令牌用户 = %s, 期望 %s", resp.ID, user.ID)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Fatal("第三方已验证的邮箱未标记为已验证")
+	}
+	if len(user.Roles) != 1 || user.Roles[0].RoleName != model.DefaultRoleName {
+		t.Fatalf("自动注册用户的角色 = %v, 期望 %s", user.Roles, model.DefaultRoleName)
+	}
+
+	identity, err := model.GetUserIdentity("mock", "new-user")
+	if err != nil || identity == nil || identity.UserID != user.ID {
+		t.Fatalf("未绑定第三方账号: %+v, %v", identity, err)
+	}
+
+	// 再次登录使用已绑定的账号，不重复注册
+	state, code, _ = authorize(t, s)
+	again, _, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{})
+	if err != nil {
+		t.Fatalf("再次登录失败: %v", err)
+	}
+	if again.ID != resp.ID {
+		t.Fatalf("再次登录的用户 = %s, 期望 %s", again.ID, resp.ID)
+	}
+}
+
+func TestOAuthCallbackLinksVerifiedEmail(t *testing.T) {
+	setupOAuthTest(t, oauth.UserInfo{Subject: "existing", Email: "existing@example.com", EmailVerified: true})
+	s := NewUserService()
+
+	now := time.Now()
+	existing := &model.User{Username: "existing", Password: "Existing_pass1", Email: "existing@example.com", EmailVerifiedAt: &now}
+	if err := model.CreateUser(existing); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	state, code, _ := authorize(t, s)
+	resp, _, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{})
+	if err != nil {
+		t.Fatalf("回调失败: %v", err)
+	}
+	if resp.ID != existing.ID.String() {
+		t.Fatalf("登录用户 = %s, 期望关联到已有用户 %s", resp.ID, existing.ID)
+	}
+
+	identity, err := model.GetUserIdentity("mock", "existing")
+	if err != nil || identity == nil || identity.UserID != existing.ID {
+		t.Fatalf("未绑定到已有用户: %+v, %v", identity, err)
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedLocalEmail(t *testing.T) {
+	setupOAuthTest(t, oauth.UserInfo{Subject: "victim", Email: "victim@example.com", EmailVerified: true})
+	s := NewUserService()
+
+	// 本地邮箱未验证，可能是抢注的账号，不能自动关联
+	if err := model.CreateUser(&model.User{Username: "squatter", Password: "Squatter_pass1", Email: "victim@example.com"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	state, code, _ := authorize(t, s)
+	if _, _, err := s.OAuthCallback(context.Background(), "mock", state, code, ClientInfo{}); err == nil {
+		t.Fatal("关联到了未验证邮箱的已有用户")
+	}
+	if identity, _ := model.GetUserIdentity("mock", "victim"); identity != nil {
+		t.Fatal("未验证邮箱的用户被绑定了第三方账号")
+	}
+}
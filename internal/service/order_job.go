@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/payment"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// orderJobLock 未支付订单任务的锁名称
+const orderJobLock = "order_reconcile"
+
+// orderQueryTimeout 单个订单查询和关单的超时时间
+const orderQueryTimeout = 15 * time.Second
+
+// OrderJobConfig 未支付订单任务配置
+type OrderJobConfig struct {
+	Interval   time.Duration // 执行间隔
+	OrderTTL   time.Duration // 未支付订单的有效期
+	QueryDelay time.Duration // 下单后多久开始主动查询
+	BatchSize  int
+}
+
+// OrderJob 定期向支付渠道查询未支付订单
+// 丢失通知的已支付订单补记为已支付，超过有效期的订单关闭渠道交易后取消；
+// 多实例部署时通过 job_lock 表保证同一时刻只有一个实例执行
+type OrderJob struct {
+	config OrderJobConfig
+	owner  string
+}
+
+func NewOrderJob(config OrderJobConfig) *OrderJob {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.OrderTTL <= 0 {
+		config.OrderTTL = 30 * time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	hostname, _ := os.Hostname()
+	return &OrderJob{
+		config: config,
+		owner:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+// Start 在后台按间隔执行，ctx 取消后停止
+func (j *OrderJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// RunOnce 获取任务锁后处理一批未支付订单
+func (j *OrderJob) RunOnce(ctx context.Context) {
+	acquired, err := model.AcquireJobLock(orderJobLock, j.owner, j.lockTTL())
+	if err != nil {
+		zap.L().Error("获取订单任务锁失败", zap.Error(err))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	orders, err := model.GetUnpaidOrders(time.Now().Add(-j.config.QueryDelay), j.config.BatchSize)
+	if err != nil {
+		zap.L().Error("获取未支付订单失败", zap.Error(err))
+		return
+	}
+
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return
+		}
+		// 每个订单处理前续期，续期失败说明锁已被其他实例接手，停止本批处理
+		renewed, err := model.AcquireJobLock(orderJobLock, j.owner, j.lockTTL())
+		if err != nil || !renewed {
+			zap.L().Warn("订单任务锁续期失败，停止处理", zap.Error(err))
+			return
+		}
+		if err := j.reconcile(ctx, order); err != nil {
+			zap.L().Warn("处理未支付订单失败", zap.String("order_id", order.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// lockTTL 锁的有效期覆盖两个执行周期，且不短于两个订单的处理时间，
+// 每处理一个订单续期一次，实例异常退出后其他实例可以接手
+func (j *OrderJob) lockTTL() time.Duration {
+	ttl := 2 * j.config.Interval
+	if ttl < 2*orderQueryTimeout {
+		ttl = 2 * orderQueryTimeout
+	}
+	return ttl
+}
+
+// reconcile 查询单个订单的交易状态并更新订单
+func (j *OrderJob) reconcile(ctx context.Context, order *model.Order) error {
+	expired := time.Since(order.CreatedAt) > j.config.OrderTTL
+
+	// 没有支付渠道的订单只做过期取消
+	if order.Provider == "" {
+		if expired {
+			return j.closeOrder(order, model.OrderStatusCancelled, model.ActorSystem, "超时未支付")
+		}
+		return nil
+	}
+
+	provider, ok := payment.Get(order.Provider)
+	if !ok {
+		return ErrPaymentProviderNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, orderQueryTimeout)
+	defer cancel()
+
+	trade, err := provider.Query(ctx, order.ID.String())
+	if errors.Is(err, payment.ErrTradeNotFound) {
+		// 用户未打开支付页面，渠道中还没有交易
+		if expired {
+			return j.closeOrder(order, model.OrderStatusCancelled, model.ActorSystem, "超时未支付，渠道无交易")
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch trade.Status {
+	case payment.TradeStatusSuccess:
+		if trade.Amount != order.Amount {
+			return fmt.Errorf("交易金额 %d 与订单金额 %d 不一致", trade.Amount, order.Amount)
+		}
+		switch err := model.PayOrder(order.ID, trade.TradeNo, model.OrderEventInfo{
+			Actor:  model.ProviderActor(provider.Name()),
+			Reason: "主动查询确认已支付",
+		}); {
+		case err == nil:
+			zap.L().Info("主动查询确认订单已支付", zap.String("order_id", order.ID.String()), zap.String("trade_no", trade.TradeNo))
+		case errors.Is(err, model.ErrOrderAlreadyPaid):
+		default:
+			return err
+		}
+	case payment.TradeStatusFailed:
+		return j.closeOrder(order, model.OrderStatusFailed, model.ProviderActor(provider.Name()), "渠道交易支付失败")
+	case payment.TradeStatusClosed:
+		return j.closeOrder(order, model.OrderStatusClosed, model.ProviderActor(provider.Name()), "渠道交易已关闭")
+	default:
+		if !expired {
+			return nil
+		}
+		// 先关闭渠道交易，关闭失败（如用户恰好完成支付）时保留订单等待下次查询
+		if err := provider.Close(ctx, order.ID.String()); err != nil {
+			return fmt.Errorf("关闭交易失败: %v", err)
+		}
+		return j.closeOrder(order, model.OrderStatusCancelled, model.ActorSystem, "超时未支付，已关闭渠道交易")
+	}
+	return nil
+}
+
+// closeOrder 将未支付的订单改为取消、失败或关闭
+func (j *OrderJob) closeOrder(order *model.Order, status model.OrderStatus, actor, reason string) error {
+	closed, err := model.CloseUnpaidOrder(order.ID, status, model.OrderEventInfo{Actor: actor, Reason: reason})
+	if err != nil {
+		return err
+	}
+	if closed {
+		zap.L().Info("关闭未支付订单", zap.String("order_id", order.ID.String()), zap.String("status", string(status)))
+	}
+	return nil
+}
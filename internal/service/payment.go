@@ -1,136 +1,277 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"interviewGenius/internal/dto"
 	"interviewGenius/internal/model"
 	"interviewGenius/internal/pkg/payment"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
-type PaymentService struct {
-	alipayService *payment.AlipayService
-}
+var ErrPaymentProviderNotFound = errors.New("不支持的支付方式")
 
-func NewPaymentService(config *payment.AlipayConfig) (*PaymentService, error) {
-	alipayService, err := payment.NewAlipayService(config)
-	if err != nil {
-		return nil, err
-	}
+type PaymentService struct{}
 
-	return &PaymentService{
-		alipayService: alipayService,
-	}, nil
+func NewPaymentService() *PaymentService {
+	return &PaymentService{}
 }
 
-// CreatePayment 创建支付订单
-func (s *PaymentService) CreatePayment(userID string, cardID uint) (*dto.PaymentResponse, error) {
+// CreatePayment 创建支付订单，provider 为空时使用默认支付渠道
+func (s *PaymentService) CreatePayment(ctx context.Context, userID uuid.UUID, req *dto.PaymentRequest) (*dto.PaymentResponse, error) {
+	provider, ok := payment.Get(req.Provider)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
 	// 获取会员卡信息
-	card, err := model.GetMemberCardByID(cardID)
+	card, err := model.GetAvailableMemberCard(req.CardID)
 	if err != nil {
 		return nil, errors.New("会员卡不存在")
 	}
 
-	// 将userID字符串转换为UUID
-	userUUID, err := uuid.Parse(userID)
+	// 创建订单并记录支付渠道，优惠码在同一事务中占用
+	order, err := model.CreateOrder(userID, card, provider.Name(), req.CouponCode)
 	if err != nil {
-		return nil, errors.New("用户ID格式无效")
+		return nil, err
 	}
 
-	// 创建订单
-	order, err := model.CreateOrder(userUUID, cardID, card.Price)
+	response, err := s.checkout(ctx, provider, order, card.Name)
 	if err != nil {
+		// 渠道下单失败时取消订单，避免留下无法支付的订单
+		if cancelErr := model.CancelOrder(order.ID, model.OrderEventInfo{
+			Actor:  model.ActorSystem,
+			Reason: "渠道下单失败: " + err.Error(),
+		}); cancelErr != nil {
+			zap.L().Error("取消订单失败", zap.String("order_id", order.ID.String()), zap.Error(cancelErr))
+		}
 		return nil, err
 	}
+	return response, nil
+}
 
-	// 将价格从分转换为元，并格式化为字符串
-	amountStr := fmt.Sprintf("%.2f", float64(card.Price)/100)
+// PayOrder 为用户已创建的订单向支付渠道下单
+// 订单已选择渠道时只能使用该渠道，避免同一订单在多个渠道重复支付；渠道下单失败时保留订单，可以重试
+func (s *PaymentService) PayOrder(ctx context.Context, userID, orderID uuid.UUID, providerName string) (*dto.PaymentResponse, error) {
+	order, err := model.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, model.ErrOrderNotFound
+	}
+	if order.Status != model.OrderStatusCreated {
+		return nil, model.ErrOrderStatusInvalid
+	}
 
-	// 创建支付宝支付
-	payURL, err := s.alipayService.CreatePayment(
-		order.ID.String(),
-		amountStr,
-		card.Name,
-	)
-	if err != nil {
-		return nil, err
+	if order.Provider != "" {
+		if providerName != "" && providerName != order.Provider {
+			return nil, errors.New("订单已选择其他支付方式")
+		}
+		providerName = order.Provider
+	}
+	provider, ok := payment.Get(providerName)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
 	}
 
-	return &dto.PaymentResponse{
-		OrderID: order.ID.String(),
-		PayURL:  payURL,
-	}, nil
+	if order.Provider == "" {
+		updated, err := model.SetOrderProvider(order.ID, provider.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			// 并发请求已为订单选择了渠道或订单状态已变化
+			return nil, model.ErrOrderStatusInvalid
+		}
+	}
+
+	subject := "会员卡"
+	if order.Card != nil {
+		subject = order.Card.Name
+	}
+	return s.checkout(ctx, provider, order, subject)
 }
 
-// HandlePaymentNotify 处理支付通知
-func (s *PaymentService) HandlePaymentNotify(params map[string]string) error {
-	// 创建一个http.Request用于验证通知
-	req := &http.Request{}
+// ConfirmOfflinePayment 管理员确认订单已线下收款
+// 订单已选择支付渠道时先关闭渠道交易，关闭失败（如用户已在线支付）时拒绝确认，避免重复收款
+func (s *PaymentService) ConfirmOfflinePayment(ctx context.Context, orderID, operatorID uuid.UUID, req *dto.ConfirmPaymentRequest) error {
+	order, err := model.GetOrderByID(orderID)
+	if err != nil {
+		return model.ErrOrderNotFound
+	}
+	if order.PaymentTime != nil {
+		return model.ErrOrderAlreadyPaid
+	}
+	if order.Status != model.OrderStatusCreated {
+		return model.ErrOrderStatusInvalid
+	}
 
-	// 验证支付宝通知
-	if err := s.alipayService.VerifyNotification(req); err != nil {
-		return err
+	if order.Provider != "" {
+		provider, ok := payment.Get(order.Provider)
+		if !ok {
+			return ErrPaymentProviderNotFound
+		}
+		closeCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		err := provider.Close(closeCtx, order.ID.String())
+		cancel()
+		if err != nil && !errors.Is(err, payment.ErrTradeNotFound) {
+			return errors.New("关闭渠道交易失败，请先确认用户是否已在线支付: " + err.Error())
+		}
 	}
 
-	// 获取订单ID
-	orderID, err := s.GetOrderID(params)
-	if err != nil {
+	reason := "确认线下收款，凭证号 " + req.ReferenceNo
+	if req.Remark != "" {
+		reason += "；" + req.Remark
+	}
+	if err := model.ConfirmOfflinePayment(order.ID, req.ReferenceNo, model.OrderEventInfo{
+		Actor:  model.AdminActor(operatorID),
+		Reason: reason,
+	}); err != nil {
 		return err
 	}
 
-	// 将orderID字符串转换为UUID
-	orderUUID, err := uuid.Parse(orderID)
+	zap.L().Info("管理员确认线下收款",
+		zap.String("order_id", order.ID.String()),
+		zap.String("operator_id", operatorID.String()),
+		zap.String("reference_no", req.ReferenceNo))
+	return nil
+}
+
+// checkout 调用支付渠道为订单下单
+func (s *PaymentService) checkout(ctx context.Context, provider payment.Provider, order *model.Order, subject string) (*dto.PaymentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result, err := provider.CreatePayment(ctx, &payment.CreateRequest{
+		OutTradeNo: order.ID.String(),
+		Amount:     order.Amount,
+		Subject:    subject,
+	})
 	if err != nil {
-		return errors.New("订单ID格式无效")
+		return nil, err
 	}
 
-	// 更新订单状态
-	return model.PayOrder(orderUUID)
+	return &dto.PaymentResponse{
+		OrderID:  order.ID.String(),
+		Provider: provider.Name(),
+		Amount:   order.Amount,
+		Discount: order.Discount,
+		PayURL:   result.PayURL,
+	}, nil
 }
 
-// HandlePaymentReturn 处理支付返回
-func (s *PaymentService) HandlePaymentReturn(params map[string]string) error {
-	// 验证签名（这里依赖于支付宝SDK的验证方法）
-	req := &http.Request{}
-	if err := s.alipayService.VerifyNotification(req); err != nil {
-		return errors.New("签名验证失败")
+// HandlePaymentNotify 处理支付渠道的异步通知
+// 校验签名和金额后将订单标记为已支付，通知原文和处理结果保存在 payment_notification 表
+// 返回 nil 表示可以应答成功，重复通知同样返回 nil
+func (s *PaymentService) HandlePaymentNotify(provider payment.Provider, header http.Header, body []byte, clientIP string) error {
+	record := &model.PaymentNotification{
+		Provider: provider.Name(),
+		RawBody:  string(body),
+		ClientIP: clientIP,
+	}
+	if err := model.CreatePaymentNotification(record); err != nil {
+		return err
 	}
 
-	// 获取订单ID
-	orderID, err := s.GetOrderID(params)
+	err := s.processNotification(provider, header, body, record)
 	if err != nil {
-		return errors.New("获取订单ID失败")
+		record.Error = err.Error()
+	}
+	if updateErr := model.UpdatePaymentNotification(record); updateErr != nil {
+		zap.L().Error("更新支付通知记录失败", zap.Uint("id", record.ID), zap.Error(updateErr))
+	}
+	return err
+}
+
+// processNotification 校验通知并更新订单，处理结果写入 record.Result
+func (s *PaymentService) processNotification(provider payment.Provider, header http.Header, body []byte, record *model.PaymentNotification) error {
+	record.Result = model.NotifyResultRejected
+
+	notification, err := provider.VerifyNotify(header, body)
+	if err != nil {
+		return err
+	}
+	record.Verified = true
+	record.NotifyID = notification.NotifyID
+	record.OutTradeNo = notification.OutTradeNo
+	record.TradeNo = notification.TradeNo
+	record.TradeStatus = string(notification.Status)
+	record.TotalAmount = payment.FormatAmount(notification.Amount)
+
+	// 只有支付成功的交易需要处理
+	if notification.Status != payment.TradeStatusSuccess {
+		record.Result = model.NotifyResultIgnored
+		return nil
 	}
 
-	// 将orderID字符串转换为UUID
-	orderUUID, err := uuid.Parse(orderID)
+	orderID, err := uuid.Parse(notification.OutTradeNo)
 	if err != nil {
 		return errors.New("订单ID格式无效")
 	}
+	order, err := model.GetOrderByID(orderID)
+	if err != nil {
+		return errors.New("订单不存在")
+	}
+
+	// 订单只接受下单时所用渠道的通知
+	if order.Provider != "" && order.Provider != provider.Name() {
+		return errors.New("支付渠道与订单不一致")
+	}
 
-	// 更新订单状态
-	if err := model.PayOrder(orderUUID); err != nil {
-		return errors.New("更新订单状态失败")
+	// 通知金额必须与订单金额一致
+	if notification.Amount != order.Amount {
+		zap.L().Error("支付通知金额与订单不一致",
+			zap.String("order_id", order.ID.String()),
+			zap.Int("order_amount", order.Amount),
+			zap.Int("notify_amount", notification.Amount))
+		return errors.New("支付金额与订单金额不一致")
 	}
 
+	switch err := model.PayOrder(order.ID, notification.TradeNo, model.OrderEventInfo{
+		Actor:   model.ProviderActor(provider.Name()),
+		Reason:  "支付成功通知",
+		Payload: record.RawBody,
+	}); {
+	case err == nil:
+		record.Result = model.NotifyResultProcessed
+	case errors.Is(err, model.ErrOrderAlreadyPaid):
+		record.Result = model.NotifyResultDuplicate
+	case errors.Is(err, model.ErrOrderStatusInvalid):
+		// 已取消的订单收到支付成功通知，应答成功避免重复通知，由人工退款处理
+		record.Result = model.NotifyResultConflict
+		record.Error = "订单状态为 " + string(order.Status) + "，需人工处理"
+		zap.L().Error("订单状态不允许支付，需人工处理",
+			zap.String("order_id", order.ID.String()),
+			zap.String("status", string(order.Status)),
+			zap.String("trade_no", notification.TradeNo))
+	default:
+		record.Result = model.NotifyResultPending
+		return err
+	}
 	return nil
 }
 
-// GetOrderID 从支付参数中获取订单ID
-func (s *PaymentService) GetOrderID(params map[string]string) (string, error) {
-	orderID, ok := params["out_trade_no"]
+// HandlePaymentReturn 处理支付完成后的同步跳转，返回订单ID
+// 同步跳转只校验签名，订单状态以异步通知为准
+func (s *PaymentService) HandlePaymentReturn(providerName string, values url.Values) (string, error) {
+	provider, ok := payment.Get(providerName)
 	if !ok {
-		return "", errors.New("订单ID不存在")
+		return "", ErrPaymentProviderNotFound
+	}
+	verifier, ok := provider.(payment.ReturnVerifier)
+	if !ok {
+		return "", errors.New("该支付方式不支持同步跳转")
 	}
-	return orderID, nil
-}
 
-// VerifySign 验证签名
-func (s *PaymentService) VerifySign(params map[string]string) error {
-	// 创建一个http.Request用于验证签名
-	req := &http.Request{}
-	return s.alipayService.VerifyNotification(req)
+	orderID, err := verifier.VerifyReturn(values)
+	if err != nil {
+		return "", errors.New("签名验证失败")
+	}
+	if _, err := uuid.Parse(orderID); err != nil {
+		return "", errors.New("订单ID格式无效")
+	}
+	return orderID, nil
 }
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/payment"
+	"interviewGenius/internal/pkg/setting"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// billDateLayout 账单日期格式
+const billDateLayout = "2006-01-02"
+
+// reconcileJobLock 对账任务的锁名称
+const reconcileJobLock = "bill_reconcile"
+
+// ErrBillNotFound 没有可用的对账单
+var ErrBillNotFound = errors.New("没有可用的对账单")
+
+type ReconcileService struct{}
+
+func NewReconcileService() *ReconcileService {
+	return &ReconcileService{}
+}
+
+// Reconcile 核对渠道某一天的账单并保存对账报告
+// bill 为空时依次读取 <billDir>/<provider>/<date>.csv 和渠道提供的账单
+func (s *ReconcileService) Reconcile(ctx context.Context, providerName string, date time.Time, bill io.Reader, source string) (*model.ReconciliationReport, error) {
+	provider, ok := payment.Get(providerName)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	if bill == nil {
+		rc, billSource, err := s.openBill(ctx, provider, date)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		bill, source = rc, billSource
+	}
+
+	report := &model.ReconciliationReport{
+		Provider: provider.Name(),
+		BillDate: date.Format(billDateLayout),
+		Source:   source,
+	}
+
+	records, err := payment.ParseBill(bill)
+	if err == nil {
+		err = s.compare(provider.Name(), date, records, report)
+	}
+	if err != nil {
+		report.Status = model.ReconcileStatusFailed
+		report.Error = err.Error()
+	}
+
+	if saveErr := model.CreateReconciliationReport(report); saveErr != nil {
+		return nil, saveErr
+	}
+	return report, err
+}
+
+// GetReports 分页获取对账报告
+func (s *ReconcileService) GetReports(provider string, page, limit int) ([]*model.ReconciliationReport, int64, error) {
+	return model.GetReconciliationReports(provider, page, limit)
+}
+
+// GetReport 获取对账报告及差异明细
+func (s *ReconcileService) GetReport(id uint) (*model.ReconciliationReport, error) {
+	return model.GetReconciliationReport(id)
+}
+
+// openBill 打开账单文件或向渠道获取账单
+func (s *ReconcileService) openBill(ctx context.Context, provider payment.Provider, date time.Time) (io.ReadCloser, string, error) {
+	if dir := setting.PaymentSetting.Reconcile.BillDir; dir != "" {
+		path := filepath.Join(dir, provider.Name(), date.Format(billDateLayout)+".csv")
+		f, err := os.Open(path)
+		if err == nil {
+			return f, path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+
+	if downloader, ok := provider.(payment.BillDownloader); ok {
+		rc, err := downloader.DownloadBill(ctx, date)
+		if err != nil {
+			return nil, "", fmt.Errorf("获取对账单失败: %v", err)
+		}
+		return rc, provider.Name(), nil
+	}
+	return nil, "", ErrBillNotFound
+}
+
+// remoteTrade 账单中同一订单的汇总
+type remoteTrade struct {
+	tradeNo  string
+	paid     int
+	refunded int
+	payment  bool // 是否有支付记录
+}
+
+// compare 按商户订单号核对账单和本地订单，差异写入 report
+func (s *ReconcileService) compare(provider string, date time.Time, records []payment.BillRecord, report *model.ReconciliationReport) error {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+
+	remote := make(map[string]*remoteTrade)
+	for _, record := range records {
+		r, ok := remote[record.OutTradeNo]
+		if !ok {
+			r = &remoteTrade{}
+			remote[record.OutTradeNo] = r
+		}
+		if record.Type == payment.BillTypeRefund {
+			r.refunded += record.Amount
+			continue
+		}
+		r.paid += record.Amount
+		r.payment = true
+		r.tradeNo = record.TradeNo
+	}
+
+	localPaid, err := model.GetOrdersPaidBetween(provider, start, end)
+	if err != nil {
+		return err
+	}
+	refunds, err := model.GetRefundsCompletedBetween(start, end)
+	if err != nil {
+		return err
+	}
+
+	// 加载账单、当天支付和当天退款涉及的全部订单
+	idSet := make(map[uuid.UUID]bool)
+	for outTradeNo := range remote {
+		if id, err := uuid.Parse(outTradeNo); err == nil {
+			idSet[id] = true
+		}
+	}
+	for _, order := range localPaid {
+		idSet[order.ID] = true
+	}
+	for _, refund := range refunds {
+		idSet[refund.OrderID] = true
+	}
+	ids := make([]uuid.UUID, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	orderList, err := model.GetOrdersByIDs(ids)
+	if err != nil {
+		return err
+	}
+	orders := make(map[string]*model.Order, len(orderList))
+	for _, order := range orderList {
+		if order.Provider == provider {
+			orders[order.ID.String()] = order
+		}
+	}
+
+	localRefunded := make(map[string]int)
+	for _, refund := range refunds {
+		if _, ok := orders[refund.OrderID.String()]; ok {
+			localRefunded[refund.OrderID.String()] += refund.Amount
+		}
+	}
+
+	addItem := func(item *model.ReconciliationItem) {
+		report.Items = append(report.Items, item)
+	}
+
+	// 账单中的交易逐笔核对本地订单
+	outTradeNos := make([]string, 0, len(remote))
+	for outTradeNo := range remote {
+		outTradeNos = append(outTradeNos, outTradeNo)
+	}
+	sort.Strings(outTradeNos)
+
+	for _, outTradeNo := range outTradeNos {
+		r := remote[outTradeNo]
+		order, ok := orders[outTradeNo]
+		if !ok {
+			addItem(&model.ReconciliationItem{
+				OutTradeNo:   outTradeNo,
+				TradeNo:      r.tradeNo,
+				Type:         model.DiscrepancyMissingLocal,
+				RemoteAmount: r.paid,
+				Detail:       fmt.Sprintf("账单支付 %s 元，退款 %s 元", payment.FormatAmount(r.paid), payment.FormatAmount(r.refunded)),
+			})
+			continue
+		}
+
+		matched := true
+		if r.payment {
+			if r.paid != order.Amount {
+				matched = false
+				addItem(&model.ReconciliationItem{
+					OutTradeNo:   outTradeNo,
+					TradeNo:      r.tradeNo,
+					Type:         model.DiscrepancyAmountMismatch,
+					LocalAmount:  order.Amount,
+					RemoteAmount: r.paid,
+					LocalStatus:  string(order.Status),
+					Detail:       "支付金额不一致",
+				})
+			}
+			if order.PaymentTime == nil {
+				matched = false
+				addItem(&model.ReconciliationItem{
+					OutTradeNo:   outTradeNo,
+					TradeNo:      r.tradeNo,
+					Type:         model.DiscrepancyStatusMismatch,
+					LocalAmount:  order.Amount,
+					RemoteAmount: r.paid,
+					LocalStatus:  string(order.Status),
+					Detail:       "渠道已收款，本地订单未支付",
+				})
+			}
+		}
+		if r.refunded != localRefunded[outTradeNo] {
+			matched = false
+			addItem(&model.ReconciliationItem{
+				OutTradeNo:   outTradeNo,
+				TradeNo:      r.tradeNo,
+				Type:         model.DiscrepancyAmountMismatch,
+				LocalAmount:  localRefunded[outTradeNo],
+				RemoteAmount: r.refunded,
+				LocalStatus:  string(order.Status),
+				Detail:       "退款金额不一致",
+			})
+		}
+		if matched {
+			report.MatchedCount++
+		}
+	}
+
+	// 本地当天已支付但账单中没有支付记录
+	for _, order := range localPaid {
+		if r, ok := remote[order.ID.String()]; ok && r.payment {
+			continue
+		}
+		addItem(&model.ReconciliationItem{
+			OutTradeNo:  order.ID.String(),
+			TradeNo:     order.TradeNo,
+			Type:        model.DiscrepancyMissingRemote,
+			LocalAmount: order.Amount,
+			LocalStatus: string(order.Status),
+			Detail:      "账单中没有支付记录",
+		})
+	}
+
+	// 本地当天退款但账单中没有该订单
+	for outTradeNo, amount := range localRefunded {
+		if _, ok := remote[outTradeNo]; ok {
+			continue
+		}
+		order := orders[outTradeNo]
+		addItem(&model.ReconciliationItem{
+			OutTradeNo:  outTradeNo,
+			TradeNo:     order.TradeNo,
+			Type:        model.DiscrepancyMissingRemote,
+			LocalAmount: amount,
+			LocalStatus: string(order.Status),
+			Detail:      "账单中没有退款记录",
+		})
+	}
+
+	report.RemoteCount = len(records)
+	report.LocalCount = len(localPaid)
+	report.Discrepancies = len(report.Items)
+	report.Status = model.ReconcileStatusBalanced
+	if report.Discrepancies > 0 {
+		report.Status = model.ReconcileStatusUnbalanced
+	}
+	return nil
+}
+
+// ReconcileJob 每天核对前一天的渠道账单
+type ReconcileJob struct {
+	hour    int
+	owner   string
+	service *ReconcileService
+}
+
+// NewReconcileJob hour 为每天开始对账的时刻，渠道账单通常在次日上午生成
+func NewReconcileJob(hour int) *ReconcileJob {
+	hostname, _ := os.Hostname()
+	return &ReconcileJob{
+		hour:    hour,
+		owner:   fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		service: NewReconcileService(),
+	}
+}
+
+// Start 每 10 分钟检查一次，到达对账时刻后核对尚未对账的渠道
+func (j *ReconcileJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// RunOnce 核对前一天各渠道的账单，已有报告（包括失败的报告）的渠道跳过，需要重新对账时手动触发
+func (j *ReconcileJob) RunOnce(ctx context.Context) {
+	now := time.Now()
+	if now.Hour() < j.hour {
+		return
+	}
+
+	acquired, err := model.AcquireJobLock(reconcileJobLock, j.owner, 30*time.Minute)
+	if err != nil {
+		zap.L().Error("获取对账任务锁失败", zap.Error(err))
+		return
+	}
+	if !acquired {
+		return
+	}
+	defer func() {
+		if err := model.ReleaseJobLock(reconcileJobLock, j.owner); err != nil {
+			zap.L().Error("释放对账任务锁失败", zap.Error(err))
+		}
+	}()
+
+	date := now.AddDate(0, 0, -1)
+	for _, name := range payment.Names() {
+		done, err := model.HasReconciliationReport(name, date.Format(billDateLayout))
+		if err != nil {
+			zap.L().Error("查询对账报告失败", zap.Error(err))
+			return
+		}
+		if done {
+			continue
+		}
+
+		report, err := j.service.Reconcile(ctx, name, date, nil, "")
+		switch {
+		case errors.Is(err, ErrBillNotFound):
+			// 账单尚未放入目录，下次检查时重试
+		case err != nil:
+			zap.L().Error("对账失败", zap.String("provider", name), zap.Error(err))
+		case report.Discrepancies > 0:
+			zap.L().Warn("对账存在差异", zap.String("provider", name), zap.String("date", report.BillDate), zap.Int("discrepancies", report.Discrepancies))
+		}
+	}
+}
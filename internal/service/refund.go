@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/payment"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RefundService struct{}
+
+func NewRefundService() *RefundService {
+	return &RefundService{}
+}
+
+// CreateRefund 发起订单退款
+// 没有支付渠道的订单直接完成退款；渠道调用出错时退款保持处理中，可通过 SyncRefund 查询结果
+func (s *RefundService) CreateRefund(ctx context.Context, orderID uuid.UUID, req *dto.CreateRefundRequest, operatorID uuid.UUID) (*model.Refund, error) {
+	refund, order, err := model.CreateRefund(orderID, req.Amount, req.Reason, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Provider == "" {
+		return model.CompleteRefund(refund.ID, "")
+	}
+
+	provider, ok := payment.Get(order.Provider)
+	if !ok {
+		_ = model.UpdateRefundStatus(refund.ID, model.RefundStatusFailed, "", "支付渠道未启用")
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result, err := provider.Refund(ctx, &payment.RefundRequest{
+		OutTradeNo:  order.ID.String(),
+		OutRefundNo: refund.ID.String(),
+		Amount:      refund.Amount,
+		TotalAmount: order.Amount,
+		Reason:      refund.Reason,
+	})
+	if err != nil {
+		// 无法确定渠道是否已受理，保持处理中等待查询
+		zap.L().Error("调用退款接口失败", zap.String("refund_id", refund.ID.String()), zap.Error(err))
+		if updateErr := model.UpdateRefundStatus(refund.ID, model.RefundStatusProcessing, "", err.Error()); updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, errors.New("退款请求失败，请稍后查询退款结果: " + err.Error())
+	}
+
+	return s.applyResult(refund.ID, result)
+}
+
+// SyncRefund 向支付渠道查询处理中的退款并更新结果，退款不属于该订单时返回 ErrRefundNotFound
+func (s *RefundService) SyncRefund(ctx context.Context, orderID, refundID uuid.UUID) (*model.Refund, error) {
+	refund, err := model.GetRefund(refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.OrderID != orderID {
+		return nil, model.ErrRefundNotFound
+	}
+	if refund.Status != model.RefundStatusPending && refund.Status != model.RefundStatusProcessing {
+		return refund, nil
+	}
+
+	order, err := model.GetOrderByID(refund.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Provider == "" {
+		return model.CompleteRefund(refund.ID, "")
+	}
+	provider, ok := payment.Get(order.Provider)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result, err := provider.QueryRefund(ctx, order.ID.String(), refund.ID.String())
+	if errors.Is(err, payment.ErrTradeNotFound) {
+		// 渠道未收到退款请求
+		result = &payment.RefundResult{Status: payment.RefundStatusFailed}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.applyResult(refund.ID, result)
+}
+
+// GetOrderRefunds 获取订单的退款记录
+func (s *RefundService) GetOrderRefunds(orderID uuid.UUID) ([]*model.Refund, error) {
+	return model.GetOrderRefunds(orderID)
+}
+
+// applyResult 根据渠道返回的退款状态更新退款记录
+func (s *RefundService) applyResult(refundID uuid.UUID, result *payment.RefundResult) (*model.Refund, error) {
+	switch result.Status {
+	case payment.RefundStatusSuccess:
+		return model.CompleteRefund(refundID, result.RefundNo)
+	case payment.RefundStatusFailed:
+		if err := model.UpdateRefundStatus(refundID, model.RefundStatusFailed, result.RefundNo, "支付渠道退款失败"); err != nil {
+			return nil, err
+		}
+	default:
+		if err := model.UpdateRefundStatus(refundID, model.RefundStatusProcessing, result.RefundNo, ""); err != nil {
+			return nil, err
+		}
+	}
+	return model.GetRefund(refundID)
+}
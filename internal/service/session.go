@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/util"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClientInfo 发起登录的客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// GetSessions 获取用户当前有效的登录会话，currentSessionID 对应的会话标记为当前会话
+func (s *UserService) GetSessions(userID uuid.UUID, currentSessionID string) ([]dto.SessionResponse, error) {
+	sessions, err := model.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, dto.SessionResponse{
+			ID:         session.ID.String(),
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID.String() == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeSession 吊销用户的指定会话，该会话的刷新令牌和访问令牌随即失效
+func (s *UserService) RevokeSession(userID, sessionID uuid.UUID) error {
+	return model.RevokeSession(userID, sessionID)
+}
+
+// RevokeAllSessions 退出全部设备
+func (s *UserService) RevokeAllSessions(userID uuid.UUID) error {
+	return s.RevokeUserTokens(userID)
+}
+
+// startSession 为新的登录创建会话，会话ID同时作为刷新令牌家族ID
+func (s *UserService) startSession(userID uuid.UUID, client ClientInfo, expiresAt time.Time) (*model.Session, error) {
+	session := &model.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Device:    util.DeviceName(client.UserAgent),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: expiresAt,
+	}
+	if err := model.CreateSession(session); err != nil {
+		return nil, errors.New("创建会话失败")
+	}
+	return session, nil
+}
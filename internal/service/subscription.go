@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/payment"
+	"interviewGenius/internal/pkg/setting"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSubscriptionDisabled  = errors.New("自动续费未开启")
+	ErrAgreementNotSupported = errors.New("该支付方式不支持自动续费")
+	ErrSubscriptionEnded     = errors.New("自动续费已结束")
+)
+
+// subscriptionPolicy 续费时间和失败重试策略
+type subscriptionPolicy struct {
+	renewBefore    time.Duration   // 会员到期前多久扣款
+	gracePeriod    time.Duration   // 续费失败后保留会员权益的时长
+	retryIntervals []time.Duration // 续费失败后依次等待的时长
+}
+
+// currentSubscriptionPolicy 读取配置，未配置时使用默认值
+func currentSubscriptionPolicy() subscriptionPolicy {
+	cfg := setting.PaymentSetting.Subscription
+	policy := subscriptionPolicy{
+		renewBefore: time.Duration(cfg.RenewBefore) * time.Hour,
+		gracePeriod: time.Duration(cfg.GraceDays) * 24 * time.Hour,
+	}
+	if policy.renewBefore <= 0 {
+		policy.renewBefore = 24 * time.Hour
+	}
+	if policy.gracePeriod <= 0 {
+		policy.gracePeriod = 3 * 24 * time.Hour
+	}
+	for _, hours := range cfg.RetryIntervals {
+		if hours > 0 {
+			policy.retryIntervals = append(policy.retryIntervals, time.Duration(hours)*time.Hour)
+		}
+	}
+	if len(policy.retryIntervals) == 0 {
+		policy.retryIntervals = []time.Duration{6 * time.Hour, 24 * time.Hour, 48 * time.Hour}
+	}
+	return policy
+}
+
+type SubscriptionService struct{}
+
+func NewSubscriptionService() *SubscriptionService {
+	return &SubscriptionService{}
+}
+
+// Create 创建自动续费订阅并向支付渠道发起签约，provider 为空时使用默认支付渠道
+func (s *SubscriptionService) Create(ctx context.Context, userID uuid.UUID, req *dto.SubscriptionRequest) (*dto.SubscriptionResponse, error) {
+	if !setting.PaymentSetting.Subscription.Enabled {
+		return nil, ErrSubscriptionDisabled
+	}
+	provider, ok := payment.Get(req.Provider)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+	agreements, ok := provider.(payment.AgreementProvider)
+	if !ok {
+		return nil, ErrAgreementNotSupported
+	}
+
+	card, err := model.GetAvailableMemberCard(req.CardID)
+	if err != nil {
+		return nil, errors.New("会员卡不存在")
+	}
+
+	sub := &model.Subscription{
+		UserID:   userID,
+		CardID:   card.ID,
+		Provider: provider.Name(),
+	}
+	if err := model.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	result, err := agreements.SignAgreement(ctx, &payment.SignRequest{
+		ExternalAgreementNo: sub.ID.String(),
+		Subject:             card.Name,
+		Amount:              card.Price,
+		PeriodDays:          card.DurationDays,
+	})
+	if err != nil {
+		// 发起签约失败时结束订阅，用户可以重新开通
+		if _, endErr := model.EndSubscription(sub.ID, model.SubscriptionStatusCancelled, "发起签约失败: "+err.Error()); endErr != nil {
+			zap.L().Error("结束订阅失败", zap.String("subscription_id", sub.ID.String()), zap.Error(endErr))
+		}
+		return nil, err
+	}
+
+	return &dto.SubscriptionResponse{
+		SubscriptionID: sub.ID.String(),
+		Provider:       provider.Name(),
+		Amount:         card.Price,
+		SignURL:        result.SignURL,
+	}, nil
+}
+
+// GetUserSubscriptions 获取用户的订阅列表
+func (s *SubscriptionService) GetUserSubscriptions(userID uuid.UUID) ([]*model.Subscription, error) {
+	return model.GetUserSubscriptions(userID)
+}
+
+// Cancel 取消自动续费
+// 已签约的订阅在当前周期结束后不再扣款，已支付的会员时长不受影响；
+// 尚未签约或续费失败的订阅立即结束，宽限期同时结束
+func (s *SubscriptionService) Cancel(ctx context.Context, userID, id uuid.UUID) (*model.Subscription, error) {
+	sub, err := model.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, model.ErrSubscriptionNotFound
+	}
+
+	switch sub.Status {
+	case model.SubscriptionStatusActive:
+		updated, err := model.UpdateSubscription(id, map[string]interface{}{"cancel_at_period_end": true})
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrSubscriptionEnded
+		}
+	case model.SubscriptionStatusPending, model.SubscriptionStatusPastDue:
+		if err := s.end(ctx, sub, model.SubscriptionStatusCancelled, "用户取消自动续费"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrSubscriptionEnded
+	}
+
+	zap.L().Info("用户取消自动续费", zap.String("subscription_id", id.String()), zap.String("status", string(sub.Status)))
+	return model.GetSubscriptionByID(id)
+}
+
+// HandleAgreementNotify 处理支付渠道的签约、解约通知
+// 签约成功后激活订阅并立即扣首期，扣款失败时由续费任务按计划重试；返回 nil 表示可以应答成功
+func (s *SubscriptionService) HandleAgreementNotify(ctx context.Context, provider payment.Provider, header http.Header, body []byte) error {
+	agreements, ok := provider.(payment.AgreementProvider)
+	if !ok {
+		return ErrAgreementNotSupported
+	}
+	agreement, err := agreements.VerifyAgreementNotify(header, body)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(agreement.ExternalAgreementNo)
+	if err != nil {
+		return errors.New("签约号格式无效")
+	}
+	sub, err := model.GetSubscriptionByID(id)
+	if err != nil {
+		return err
+	}
+	if sub.Provider != provider.Name() {
+		return errors.New("支付渠道与订阅不一致")
+	}
+
+	switch agreement.Status {
+	case payment.AgreementStatusActive:
+		activated, err := model.ActivateSubscription(id, agreement.AgreementNo)
+		if err != nil {
+			return err
+		}
+		if !activated {
+			// 重复通知，或订阅已被取消（如用户重新发起签约），后者解约避免留下无人管理的协议
+			if sub.Status == model.SubscriptionStatusCancelled || sub.Status == model.SubscriptionStatusExpired {
+				s.terminate(ctx, sub.Provider, agreement.AgreementNo)
+			}
+			return nil
+		}
+		zap.L().Info("自动续费签约成功", zap.String("subscription_id", id.String()), zap.String("agreement_no", agreement.AgreementNo))
+
+		sub.Status = model.SubscriptionStatusActive
+		sub.AgreementNo = agreement.AgreementNo
+		if err := s.renew(ctx, sub); err != nil {
+			zap.L().Warn("首期扣款失败，等待续费任务重试", zap.String("subscription_id", id.String()), zap.Error(err))
+		}
+	case payment.AgreementStatusTerminated:
+		ended, err := model.EndSubscription(id, model.SubscriptionStatusCancelled, "用户在支付渠道解约或拒绝签约")
+		if err != nil {
+			return err
+		}
+		if ended {
+			zap.L().Info("支付渠道通知解约", zap.String("subscription_id", id.String()))
+		}
+	}
+	return nil
+}
+
+// renew 处理到期的订阅：检查等待中的续费订单、结束已取消的订阅或发起新一期扣款
+func (s *SubscriptionService) renew(ctx context.Context, sub *model.Subscription) error {
+	if sub.PendingOrderID != nil {
+		order, err := model.GetOrderByID(*sub.PendingOrderID)
+		if err != nil {
+			return err
+		}
+		return s.settle(ctx, sub, order)
+	}
+
+	if sub.CancelAtPeriodEnd {
+		return s.end(ctx, sub, model.SubscriptionStatusCancelled, "当前周期结束，不再续费")
+	}
+
+	card, err := model.GetAvailableMemberCard(sub.CardID)
+	if errors.Is(err, model.ErrMemberCardNotFound) {
+		return s.end(ctx, sub, model.SubscriptionStatusExpired, "会员卡已下架")
+	}
+	if err != nil {
+		return err
+	}
+
+	agreements, err := agreementProvider(sub.Provider)
+	if err != nil {
+		return err
+	}
+
+	order, err := model.CreateSubscriptionOrder(sub, card)
+	if errors.Is(err, model.ErrSubscriptionChanged) {
+		return nil
+	}
+	if errors.Is(err, model.ErrEmailNotVerified) {
+		// 开通后才开启邮箱验证要求时无法继续扣款，结束订阅避免每次任务都重复尝试
+		return s.end(ctx, sub, model.SubscriptionStatusExpired, "邮箱未验证，无法续费")
+	}
+	if err != nil {
+		return err
+	}
+
+	trade, err := agreements.ChargeAgreement(ctx, &payment.ChargeRequest{
+		OutTradeNo:  order.ID.String(),
+		AgreementNo: sub.AgreementNo,
+		Amount:      order.Amount,
+		Subject:     card.Name,
+	})
+	if err != nil {
+		// 扣款结果未知，保留订单由订单任务查询渠道交易，超时未支付时关闭订单后按失败重试
+		return fmt.Errorf("代扣请求失败: %v", err)
+	}
+
+	info := model.OrderEventInfo{Actor: model.ProviderActor(sub.Provider)}
+	switch trade.Status {
+	case payment.TradeStatusSuccess:
+		if trade.Amount != order.Amount {
+			return fmt.Errorf("交易金额 %d 与订单金额 %d 不一致", trade.Amount, order.Amount)
+		}
+		info.Reason = "自动续费扣款成功"
+		if err := model.PayOrder(order.ID, trade.TradeNo, info); err != nil && !errors.Is(err, model.ErrOrderAlreadyPaid) {
+			return err
+		}
+	case payment.TradeStatusFailed, payment.TradeStatusClosed:
+		info.Reason = "自动续费扣款失败"
+		if _, err := model.CloseUnpaidOrder(order.ID, model.OrderStatusFailed, info); err != nil {
+			return err
+		}
+	default:
+		// 渠道处理中，等待支付通知或订单任务查询
+		return nil
+	}
+
+	if order, err = model.GetOrderByID(order.ID); err != nil {
+		return err
+	}
+	return s.settle(ctx, sub, order)
+}
+
+// settle 根据续费订单的结果更新订阅，订单仍未支付时继续等待
+// 扣款失败时进入宽限期并按计划重试，重试次数用完后订阅过期并解约
+func (s *SubscriptionService) settle(ctx context.Context, sub *model.Subscription, order *model.Order) error {
+	policy := currentSubscriptionPolicy()
+	now := time.Now()
+
+	if order.PaymentTime != nil {
+		periodEnd := *order.PeriodEnd
+		// 会员卡时长短于提前扣款时间时在周期中点扣款，避免连续扣款
+		lead := policy.renewBefore
+		if period := periodEnd.Sub(*order.PeriodStart); lead > period/2 {
+			lead = period / 2
+		}
+		_, err := model.FinishSubscriptionCharge(sub.ID, order.ID, map[string]interface{}{
+			"status":             model.SubscriptionStatusActive,
+			"current_period_end": periodEnd,
+			"next_charge_at":     periodEnd.Add(-lead),
+			"grace_until":        nil,
+			"retry_count":        0,
+			"last_error":         "",
+		})
+		return err
+	}
+	if order.Status == model.OrderStatusCreated {
+		return nil
+	}
+
+	reason := "续费订单状态为 " + string(order.Status)
+	retry := sub.RetryCount + 1
+	if retry > len(policy.retryIntervals) {
+		zap.L().Warn("自动续费重试次数用完，订阅过期", zap.String("subscription_id", sub.ID.String()), zap.String("order_id", order.ID.String()))
+		return s.end(ctx, sub, model.SubscriptionStatusExpired, reason+"，重试次数已用完")
+	}
+
+	updates := map[string]interface{}{
+		"status":         model.SubscriptionStatusPastDue,
+		"retry_count":    retry,
+		"next_charge_at": now.Add(policy.retryIntervals[retry-1]),
+		"last_error":     reason,
+	}
+	// 只有支付过的订阅有宽限期，宽限期从已支付周期结束时开始计算
+	if sub.CurrentPeriodEnd != nil {
+		updates["grace_until"] = sub.CurrentPeriodEnd.Add(policy.gracePeriod)
+	}
+	if _, err := model.FinishSubscriptionCharge(sub.ID, order.ID, updates); err != nil {
+		return err
+	}
+	zap.L().Warn("自动续费扣款失败", zap.String("subscription_id", sub.ID.String()), zap.String("order_id", order.ID.String()), zap.Int("retry", retry))
+	return nil
+}
+
+// end 结束订阅并向渠道解约，解约失败只记录日志，结束的订阅不会再使用该协议扣款
+func (s *SubscriptionService) end(ctx context.Context, sub *model.Subscription, status model.SubscriptionStatus, reason string) error {
+	ended, err := model.EndSubscription(sub.ID, status, reason)
+	if err != nil || !ended {
+		return err
+	}
+	s.terminate(ctx, sub.Provider, sub.AgreementNo)
+	return nil
+}
+
+// terminate 向渠道解约
+func (s *SubscriptionService) terminate(ctx context.Context, providerName, agreementNo string) {
+	if agreementNo == "" {
+		return
+	}
+	agreements, err := agreementProvider(providerName)
+	if err == nil {
+		err = agreements.TerminateAgreement(ctx, agreementNo)
+	}
+	if err != nil && !errors.Is(err, payment.ErrAgreementNotFound) {
+		zap.L().Error("解约失败", zap.String("provider", providerName), zap.String("agreement_no", agreementNo), zap.Error(err))
+	}
+}
+
+// agreementProvider 获取支持签约代扣的渠道
+func agreementProvider(name string) (payment.AgreementProvider, error) {
+	provider, ok := payment.Get(name)
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+	agreements, ok := provider.(payment.AgreementProvider)
+	if !ok {
+		return nil, ErrAgreementNotSupported
+	}
+	return agreements, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"interviewGenius/internal/model"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// subscriptionJobLock 续费任务的锁名称
+const subscriptionJobLock = "subscription_renew"
+
+// subscriptionChargeTimeout 单个订阅扣款的超时时间
+const subscriptionChargeTimeout = 15 * time.Second
+
+// SubscriptionJob 定期处理到达扣款时间的订阅
+// 按协议扣款生成续费订单，失败后按计划重试；多实例部署时通过 job_lock 表保证同一时刻只有一个实例执行
+type SubscriptionJob struct {
+	interval  time.Duration
+	batchSize int
+	owner     string
+	service   *SubscriptionService
+}
+
+func NewSubscriptionJob(interval time.Duration, batchSize int) *SubscriptionJob {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	hostname, _ := os.Hostname()
+	return &SubscriptionJob{
+		interval:  interval,
+		batchSize: batchSize,
+		owner:     fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		service:   NewSubscriptionService(),
+	}
+}
+
+// Start 在后台按间隔执行，ctx 取消后停止
+func (j *SubscriptionJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// RunOnce 获取任务锁后处理一批到期的订阅
+func (j *SubscriptionJob) RunOnce(ctx context.Context) {
+	acquired, err := model.AcquireJobLock(subscriptionJobLock, j.owner, j.lockTTL())
+	if err != nil {
+		zap.L().Error("获取续费任务锁失败", zap.Error(err))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	subs, err := model.GetDueSubscriptions(time.Now(), j.batchSize)
+	if err != nil {
+		zap.L().Error("获取到期订阅失败", zap.Error(err))
+		return
+	}
+
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		// 每个订阅处理前续期，续期失败说明锁已被其他实例接手，停止本批处理
+		renewed, err := model.AcquireJobLock(subscriptionJobLock, j.owner, j.lockTTL())
+		if err != nil || !renewed {
+			zap.L().Warn("续费任务锁续期失败，停止处理", zap.Error(err))
+			return
+		}
+		subCtx, cancel := context.WithTimeout(ctx, subscriptionChargeTimeout)
+		err = j.service.renew(subCtx, sub)
+		cancel()
+		if err != nil {
+			zap.L().Warn("处理自动续费失败", zap.String("subscription_id", sub.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// lockTTL 锁的有效期覆盖两个执行周期，且不短于两个订阅的处理时间，
+// 每处理一个订阅续期一次，实例异常退出后其他实例可以接手
+func (j *SubscriptionJob) lockTTL() time.Duration {
+	ttl := 2 * j.interval
+	if ttl < 2*subscriptionChargeTimeout {
+		ttl = 2 * subscriptionChargeTimeout
+	}
+	return ttl
+}
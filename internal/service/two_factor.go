@@ -0,0 +1,357 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"interviewGenius/internal/dto"
+	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/loginguard"
+	"interviewGenius/internal/pkg/setting"
+	"interviewGenius/internal/pkg/totp"
+	"interviewGenius/internal/pkg/util"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// LoginTwoFactor 使用挑战令牌和验证码（或恢复码）完成登录
+// 策略要求启用但尚未启用时，验证码用于确认设置，并返回恢复码
+func (s *UserService) LoginTwoFactor(req *dto.TwoFactorLoginRequest, client ClientInfo) (*dto.TokenResponse, error) {
+	user, err := s.challengeUser(req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	key := twoFactorKey(user.ID)
+	if err := loginguard.Check(key, accountLoginPolicy()); err != nil {
+		return nil, err
+	}
+
+	config, err := model.GetUserTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	switch {
+	case config != nil && config.Enabled:
+		ok, err := s.verifySecondFactor(config, req.Code, req.RecoveryCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			s.recordTwoFactorFailure(key)
+			return nil, errors.New("验证码错误")
+		}
+	case config != nil && twoFactorEnforced(user):
+		// 首次设置：确认验证码后启用
+		recoveryCodes, err = s.enableTwoFactor(config, req.Code)
+		if err != nil {
+			s.recordTwoFactorFailure(key)
+			return nil, err
+		}
+	default:
+		return nil, errors.New("请先设置两步验证")
+	}
+
+	if err := loginguard.Reset(key); err != nil {
+		zap.L().Warn("清除两步验证失败记录失败", zap.String("key", key), zap.Error(err))
+	}
+
+	resp, err := s.issueTokens(user, client)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+// SetupTwoFactorWithChallenge 策略要求启用两步验证的用户在登录过程中生成密钥
+func (s *UserService) SetupTwoFactorWithChallenge(challengeToken string) (*dto.TwoFactorSetupResponse, error) {
+	user, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if !twoFactorEnforced(user) {
+		return nil, errors.New("当前账户无需在登录时设置两步验证")
+	}
+	return s.beginTwoFactorSetup(user)
+}
+
+// EnrollTwoFactor 开始设置两步验证，生成待确认的密钥
+func (s *UserService) EnrollTwoFactor(userID uuid.UUID) (*dto.TwoFactorSetupResponse, error) {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return s.beginTwoFactorSetup(user)
+}
+
+// ConfirmTwoFactor 使用验证码确认设置并启用两步验证，返回恢复码
+func (s *UserService) ConfirmTwoFactor(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	config, err := model.GetUserTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.New("请先设置两步验证")
+	}
+
+	key := twoFactorKey(userID)
+	if err := loginguard.Check(key, accountLoginPolicy()); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.enableTwoFactor(config, code)
+	if err != nil {
+		s.recordTwoFactorFailure(key)
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor 关闭两步验证，需要提供当前验证码
+func (s *UserService) DisableTwoFactor(userID uuid.UUID, code string) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if twoFactorEnforced(user) {
+		return errors.New("当前账户必须启用两步验证")
+	}
+
+	if err := s.checkEnabledTOTP(userID, code); err != nil {
+		return err
+	}
+	return model.DisableTOTP(userID)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，需要提供当前验证码
+func (s *UserService) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	if err := s.checkEnabledTOTP(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.RegenerateRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// twoFactorChallenge 判断登录是否需要两步验证，需要时返回挑战令牌
+func (s *UserService) twoFactorChallenge(user *model.User) (*dto.TokenResponse, error) {
+	config, err := model.GetUserTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := config != nil && config.Enabled
+	if !enabled && !twoFactorEnforced(user) {
+		return nil, nil
+	}
+
+	challengeToken, _, err := util.GenerateActionToken(user.ID.String(), user.Username, util.PurposeTwoFactor, twoFactorChallengeTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		ID:                user.ID.String(),
+		Username:          user.Username,
+		TwoFactorRequired: true,
+		SetupRequired:     !enabled,
+		ChallengeToken:    challengeToken,
+	}, nil
+}
+
+// challengeUser 解析挑战令牌并获取对应用户
+func (s *UserService) challengeUser(challengeToken string) (*model.User, error) {
+	claims, err := util.ParseActionToken(challengeToken, util.PurposeTwoFactor)
+	if err != nil {
+		return nil, errors.New("挑战令牌无效或已过期")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.New("挑战令牌无效或已过期")
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return user, nil
+}
+
+// beginTwoFactorSetup 生成并保存待确认的密钥
+func (s *UserService) beginTwoFactorSetup(user *model.User) (*dto.TwoFactorSetupResponse, error) {
+	config, err := model.GetUserTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil && config.Enabled {
+		return nil, errors.New("两步验证已启用")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.SaveTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(twoFactorIssuer(), user.Username, secret),
+	}, nil
+}
+
+// enableTwoFactor 校验待确认密钥的验证码并启用两步验证，返回恢复码明文
+func (s *UserService) enableTwoFactor(config *model.UserTOTP, code string) ([]string, error) {
+	if config.Enabled {
+		return nil, errors.New("两步验证已启用")
+	}
+
+	counter, ok := totp.Validate(config.Secret, code, time.Now(), twoFactorSkew())
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.EnableTOTP(config.UserID, counter, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkEnabledTOTP 校验已启用的两步验证码
+func (s *UserService) checkEnabledTOTP(userID uuid.UUID, code string) error {
+	config, err := model.GetUserTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if config == nil || !config.Enabled {
+		return errors.New("两步验证未启用")
+	}
+
+	key := twoFactorKey(userID)
+	if err := loginguard.Check(key, accountLoginPolicy()); err != nil {
+		return err
+	}
+
+	ok, err := s.verifySecondFactor(config, code, "")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordTwoFactorFailure(key)
+		return errors.New("验证码错误")
+	}
+	return nil
+}
+
+// verifySecondFactor 校验验证码或恢复码，同一验证码和恢复码只能使用一次
+func (s *UserService) verifySecondFactor(config *model.UserTOTP, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return model.UseRecoveryCode(config.UserID, util.HashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	counter, ok := totp.Validate(config.Secret, code, time.Now(), twoFactorSkew())
+	if !ok || counter <= config.LastCounter {
+		return false, nil
+	}
+	return model.UseTOTPCounter(config.UserID, counter)
+}
+
+// recordTwoFactorFailure 记录两步验证失败
+func (s *UserService) recordTwoFactorFailure(key string) {
+	if err := loginguard.Fail(key, accountLoginPolicy()); err != nil {
+		zap.L().Error("记录两步验证失败次数失败", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// generateRecoveryCodes 生成恢复码明文及其哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	buf := make([]byte, 10)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		chars := make([]byte, len(buf))
+		for j, b := range buf {
+			chars[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+
+		code := string(chars[:5]) + "-" + string(chars[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, util.HashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// twoFactorEnforced 判断策略是否要求该用户启用两步验证
+func twoFactorEnforced(user *model.User) bool {
+	if !setting.SecuritySetting.TwoFactor.EnforceForSuper {
+		return false
+	}
+	for _, role := range user.Roles {
+		if role.IsSuper {
+			return true
+		}
+	}
+	return false
+}
+
+// twoFactorKey 按用户记录两步验证失败的键
+func twoFactorKey(userID uuid.UUID) string {
+	return "2fa:" + userID.String()
+}
+
+// twoFactorIssuer 认证器应用中显示的签发者名称
+func twoFactorIssuer() string {
+	if setting.SecuritySetting.TwoFactor.Issuer == "" {
+		return "InterviewGenius"
+	}
+	return setting.SecuritySetting.TwoFactor.Issuer
+}
+
+// twoFactorChallengeTTL 登录挑战令牌有效期，未配置时默认5分钟
+func twoFactorChallengeTTL() time.Duration {
+	if setting.SecuritySetting.TwoFactor.ChallengeTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(setting.SecuritySetting.TwoFactor.ChallengeTTL) * time.Minute
+}
+
+// twoFactorSkew 允许前后偏差的时间步数
+func twoFactorSkew() int {
+	if setting.SecuritySetting.TwoFactor.Skew < 0 {
+		return 0
+	}
+	return setting.SecuritySetting.TwoFactor.Skew
+}
@@ -4,9 +4,16 @@ import (
 	"errors"
 	"interviewGenius/internal/dto"
 	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/denylist"
+	"interviewGenius/internal/pkg/loginguard"
+	"interviewGenius/internal/pkg/password"
+	"interviewGenius/internal/pkg/setting"
 	"interviewGenius/internal/pkg/util"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type UserService struct{}
@@ -16,13 +23,18 @@ func NewUserService() *UserService {
 }
 
 // Register 注册用户
-func (s *UserService) Register(req *dto.RegisterRequest) (*dto.TokenResponse, error) {
+func (s *UserService) Register(req *dto.RegisterRequest, client ClientInfo) (*dto.TokenResponse, error) {
 	// 检查用户名是否已存在
 	_, err := model.GetUserByUsername(req.Username)
 	if err == nil {
 		return nil, errors.New("用户名已存在")
 	}
 
+	// 校验密码策略
+	if err := validatePassword("password", req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// 创建用户
 	user := &model.User{
 		Username: req.Username,
@@ -34,42 +46,158 @@ func (s *UserService) Register(req *dto.RegisterRequest) (*dto.TokenResponse, er
 		return nil, err
 	}
 
-	// 生成Token
-	token, err := util.GenerateToken(user.ID.String(), user.Username)
+	// 发送邮箱验证邮件，失败不影响注册
+	if err := s.sendVerificationEmail(user); err != nil {
+		zap.L().Warn("发送邮箱验证邮件失败", zap.String("user_id", user.ID.String()), zap.Error(err))
+	}
+
+	// 分配默认的普通用户角色
+	if role, err := model.GetRoleByName(model.DefaultRoleName); err == nil {
+		if err := model.AddRolesToUser(user.ID, []uint{role.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 重新加载角色和权限版本
+	user, err = model.GetUserByID(user.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.TokenResponse{
-		ID:       user.ID.String(),
-		Username: user.Username,
-		Token:    token,
-	}, nil
+	// 签发令牌
+	return s.issueTokens(user, client)
 }
 
 // Login 用户登录
-func (s *UserService) Login(req *dto.LoginRequest) (*dto.TokenResponse, error) {
+// 按用户名和客户端IP分别记录失败次数，超过阈值后退避或临时锁定
+// 需要两步验证时只返回短期挑战令牌
+func (s *UserService) Login(req *dto.LoginRequest, client ClientInfo) (*dto.TokenResponse, error) {
+	userKey := loginUserKey(req.Username)
+	ipKey := loginIPKey(client.IP)
+
+	// 检查是否处于退避或锁定中
+	if err := loginguard.Check(userKey, accountLoginPolicy()); err != nil {
+		return nil, err
+	}
+	if err := loginguard.Check(ipKey, ipLoginPolicy()); err != nil {
+		return nil, err
+	}
+
 	// 获取用户
 	user, err := model.GetUserByUsername(req.Username)
-	if err != nil {
+	if err != nil || !user.CheckPassword(req.Password) {
+		s.recordLoginFailure(userKey, ipKey)
 		return nil, errors.New("用户名或密码错误")
 	}
 
-	// 验证密码
-	if !user.CheckPassword(req.Password) {
-		return nil, errors.New("用户名或密码错误")
+	if err := loginguard.Reset(userKey); err != nil {
+		zap.L().Warn("清除登录失败记录失败", zap.String("key", userKey), zap.Error(err))
+	}
+
+	// 已启用或策略要求两步验证时返回挑战令牌
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	// 签发令牌
+	return s.issueTokens(user, client)
+}
+
+// UnlockUser 解除用户的登录锁定，clientIP 不为空时一并解除该IP的限制
+func (s *UserService) UnlockUser(id uuid.UUID, clientIP string) error {
+	user, err := model.GetUserByID(id)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+
+	if err := loginguard.Reset(loginUserKey(user.Username)); err != nil {
+		return err
+	}
+	if clientIP != "" {
+		return loginguard.Reset(loginIPKey(clientIP))
+	}
+	return nil
+}
+
+// recordLoginFailure 记录登录失败
+func (s *UserService) recordLoginFailure(userKey, ipKey string) {
+	if err := loginguard.Fail(userKey, accountLoginPolicy()); err != nil {
+		zap.L().Error("记录登录失败次数失败", zap.String("key", userKey), zap.Error(err))
+	}
+	if err := loginguard.Fail(ipKey, ipLoginPolicy()); err != nil {
+		zap.L().Error("记录登录失败次数失败", zap.String("key", ipKey), zap.Error(err))
+	}
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌
+func (s *UserService) RefreshToken(refreshToken string) (*dto.TokenResponse, error) {
+	newRefreshToken, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
 	}
 
-	// 生成Token
-	token, err := util.GenerateToken(user.ID.String(), user.Username)
+	// 轮换刷新令牌
+	rotated, err := model.RotateRefreshToken(
+		util.HashToken(refreshToken),
+		util.HashToken(newRefreshToken),
+		time.Now().Add(util.RefreshTokenTTL()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := model.GetUserByID(rotated.UserID)
+	if err != nil {
+		_ = model.RevokeRefreshTokenFamily(rotated.FamilyID)
+		return nil, errors.New("用户不存在")
+	}
+
+	accessToken, err := util.GenerateToken(user.ID.String(), user.Username, roleNames(user.Roles), user.PermVersion, rotated.FamilyID.String())
 	if err != nil {
 		return nil, err
 	}
 
 	return &dto.TokenResponse{
-		ID:       user.ID.String(),
-		Username: user.Username,
-		Token:    token,
+		ID:           user.ID.String(),
+		Username:     user.Username,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int64(util.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// issueTokens 为用户签发访问令牌，并开启新的登录会话和刷新令牌家族
+func (s *UserService) issueTokens(user *model.User, client ClientInfo) (*dto.TokenResponse, error) {
+	refreshToken, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(util.RefreshTokenTTL())
+	session, err := s.startSession(user.ID, client, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := model.CreateRefreshToken(user.ID, session.ID, util.HashToken(refreshToken), expiresAt); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := util.GenerateToken(user.ID.String(), user.Username, roleNames(user.Roles), user.PermVersion, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		ID:           user.ID.String(),
+		Username:     user.Username,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(util.AccessTokenTTL().Seconds()),
 	}, nil
 }
 
@@ -113,22 +241,78 @@ func (s *UserService) UpdateUser(id uuid.UUID, req *dto.UpdateUserRequest) error
 	if req.Username != "" {
 		user.Username = req.Username
 	}
-	if req.Email != "" {
+	if req.Email != "" && req.Email != user.Email {
+		// 更换邮箱后需要重新验证
 		user.Email = req.Email
+		user.EmailVerifiedAt = nil
 	}
+	passwordChanged := false
 	if req.OldPassword != "" && req.NewPassword != "" {
 		if !user.CheckPassword(req.OldPassword) {
 			return errors.New("原密码错误")
 		}
-		user.Password = req.NewPassword
+		if err := validatePassword("new_password", req.NewPassword, user.Username, user.Email); err != nil {
+			return err
+		}
+		passwordChanged = true
+	}
+
+	if err := model.UpdateUser(user); err != nil {
+		return err
 	}
 
-	return model.UpdateUser(user)
+	// 修改密码后使已签发的令牌全部失效
+	if passwordChanged {
+		if err := model.UpdateUserPassword(id, req.NewPassword); err != nil {
+			return err
+		}
+		return s.RevokeUserTokens(id)
+	}
+	return nil
 }
 
 // DeleteUser 删除用户
 func (s *UserService) DeleteUser(id uuid.UUID) error {
-	return model.DeleteUser(id)
+	if err := model.DeleteUser(id); err != nil {
+		return err
+	}
+	if err := model.RevokeUserAPIKeys(id); err != nil {
+		return err
+	}
+	return s.RevokeUserTokens(id)
+}
+
+// Logout 注销当前访问令牌和所在会话，提供刷新令牌时一并吊销其所在家族
+func (s *UserService) Logout(claims *util.Claims, refreshToken string) error {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return errors.New("无效的用户ID")
+	}
+
+	if err := denylist.Default().Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+		if err := model.RevokeRefreshTokenFamily(sessionID); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		if err := model.RevokeRefreshTokenFamilyByHash(userID, util.HashToken(refreshToken)); err != nil && !errors.Is(err, model.ErrRefreshTokenInvalid) {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeUserTokens 吊销用户已签发的全部访问令牌、刷新令牌和会话
+func (s *UserService) RevokeUserTokens(id uuid.UUID) error {
+	if err := denylist.Default().RevokeUser(id.String(), time.Now()); err != nil {
+		return err
+	}
+	return model.RevokeUserRefreshTokens(id)
 }
 
 // AddUserRoles 添加用户角色
@@ -157,3 +341,71 @@ func (s *UserService) GetUserRoles(userID uuid.UUID) ([]dto.RoleInfo, error) {
 func (s *UserService) RemoveUserRole(userID uuid.UUID, roleID uint) error {
 	return model.RemoveRoleFromUser(userID, roleID)
 }
+
+// roleNames 提取角色名
+func roleNames(roles []*model.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.RoleName)
+	}
+	return names
+}
+
+// validatePassword 按配置的密码策略校验，不符合时返回 *password.PolicyError
+func validatePassword(field, plain, username, email string) error {
+	cfg := setting.SecuritySetting.Password
+	policy := password.Policy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        cfg.MaxLength,
+		MinEntropy:       cfg.MinEntropy,
+		MinClasses:       cfg.MinClasses,
+		RequireUpper:     cfg.RequireUpper,
+		RequireLower:     cfg.RequireLower,
+		RequireDigit:     cfg.RequireDigit,
+		RequireSymbol:    cfg.RequireSymbol,
+		CheckCommon:      cfg.CheckCommon,
+		CommonListFile:   cfg.CommonListFile,
+		DisallowUserInfo: cfg.DisallowUserInfo,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = 6
+	}
+
+	if errs := policy.Validate(field, plain, username, email); len(errs) > 0 {
+		return &password.PolicyError{Errors: errs}
+	}
+	return nil
+}
+
+// loginUserKey 按用户名记录登录失败的键
+func loginUserKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// loginIPKey 按客户端IP记录登录失败的键
+func loginIPKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// accountLoginPolicy 账户维度的登录限制策略
+func accountLoginPolicy() loginguard.Policy {
+	return loginPolicy(setting.SecuritySetting.Login.MaxFailures)
+}
+
+// ipLoginPolicy 客户端IP维度的登录限制策略
+func ipLoginPolicy() loginguard.Policy {
+	return loginPolicy(setting.SecuritySetting.Login.IPMaxFailures)
+}
+
+// loginPolicy 根据配置构造登录限制策略
+func loginPolicy(maxFailures int) loginguard.Policy {
+	cfg := setting.SecuritySetting.Login
+	return loginguard.Policy{
+		FreeAttempts: cfg.FreeAttempts,
+		MaxFailures:  maxFailures,
+		BaseDelay:    time.Duration(cfg.BaseDelay) * time.Second,
+		MaxDelay:     time.Duration(cfg.MaxDelay) * time.Second,
+		LockDuration: time.Duration(cfg.LockDuration) * time.Minute,
+		Window:       time.Duration(cfg.Window) * time.Minute,
+	}
+}
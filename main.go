@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"interviewGenius/config"
 	"interviewGenius/internal/model"
+	"interviewGenius/internal/pkg/denylist"
+	"interviewGenius/internal/pkg/loginguard"
+	"interviewGenius/internal/pkg/mailer"
+	"interviewGenius/internal/pkg/oauth"
+	"interviewGenius/internal/pkg/password"
+	"interviewGenius/internal/pkg/payment"
+	"interviewGenius/internal/pkg/rbac"
 	"interviewGenius/internal/pkg/setting"
+	"interviewGenius/internal/pkg/util"
 	"interviewGenius/internal/router"
+	"interviewGenius/internal/service"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -44,15 +56,157 @@ func main() {
 	}
 	defer zap.L().Sync()
 
+	// 加载JWT签名密钥
+	if err := util.InitKeys(); err != nil {
+		zap.L().Error("加载JWT签名密钥失败", zap.Error(err))
+		return
+	}
+
+	// 密码哈希算法
+	passwordCfg := setting.SecuritySetting.Password
+	password.SetHashConfig(password.HashConfig{
+		Algorithm:         passwordCfg.Algorithm,
+		BcryptCost:        passwordCfg.BcryptCost,
+		Argon2Memory:      passwordCfg.Argon2Memory,
+		Argon2Iterations:  passwordCfg.Argon2Iterations,
+		Argon2Parallelism: passwordCfg.Argon2Parallelism,
+	})
+
 	// 初始化数据库
 	if err := model.Init(); err != nil {
 		zap.L().Error("初始化数据库失败", zap.Error(err))
 		return
 	}
 
+	// 权限判定缓存
+	rbac.SetCacheTTL(time.Duration(setting.AppSetting.PermissionCacheTTL) * time.Second)
+
+	// 令牌黑名单存储
+	if setting.AppSetting.TokenStore == "db" {
+		denylist.SetStore(model.NewDenylistStore())
+	}
+
+	// 登录失败记录存储
+	if setting.SecuritySetting.Login.Store == "db" {
+		loginguard.SetStore(model.NewLoginAttemptStore())
+	}
+
+	// 邮件发送
+	if setting.MailSetting.Driver == "smtp" {
+		mail := setting.MailSetting
+		mailer.SetMailer(mailer.NewSMTPMailer(mail.Host, mail.Port, mail.Username, mail.Password, mail.From))
+	} else {
+		logMailer := mailer.NewLogMailer(setting.MailSetting.Dir)
+		if setting.MailSetting.From != "" {
+			logMailer.From = setting.MailSetting.From
+		}
+		mailer.SetMailer(logMailer)
+	}
+
+	// 第三方登录提供方，单个提供方初始化失败不影响启动
+	for name, cfg := range setting.OAuthSetting.Providers {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oauth.NewProvider(ctx, oauth.Config{
+			Name:         name,
+			Type:         cfg.Type,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Issuer:       cfg.Issuer,
+			AuthURL:      cfg.AuthURL,
+			TokenURL:     cfg.TokenURL,
+			UserInfoURL:  cfg.UserInfoURL,
+			Scopes:       cfg.Scopes,
+		})
+		cancel()
+		if err != nil {
+			zap.L().Error("初始化第三方登录失败", zap.String("provider", name), zap.Error(err))
+			continue
+		}
+		oauth.Register(provider)
+	}
+
+	// 支付渠道，单个渠道初始化失败不影响启动；生产模式下支付宝配置有误时拒绝启动
+	if alipayCfg := setting.AlipaySetting; alipayCfg.Enabled {
+		release := setting.ServerSetting.RunMode == "release"
+		config, err := loadAlipayConfig(alipayCfg)
+		if err != nil {
+			zap.L().Error("读取支付宝配置失败", zap.Error(err))
+			if release {
+				return
+			}
+		} else if err := config.CheckPlaceholder(); err != nil && release {
+			zap.L().Error("生产模式下支付宝密钥无效，拒绝启动", zap.Error(err))
+			return
+		} else if provider, err := payment.NewAlipayService(config); err != nil {
+			zap.L().Error("初始化支付宝失败", zap.Error(err))
+			if release {
+				return
+			}
+		} else {
+			payment.Register(provider)
+		}
+	}
+	if wechatCfg := setting.PaymentSetting.WeChat; wechatCfg.Enabled {
+		provider, err := newWeChatProvider(wechatCfg)
+		if err != nil {
+			zap.L().Error("初始化微信支付失败", zap.Error(err))
+		} else {
+			payment.Register(provider)
+		}
+	}
+	if sandboxCfg := setting.PaymentSetting.Sandbox; sandboxCfg.Enabled {
+		if setting.ServerSetting.RunMode == "release" {
+			zap.L().Error("生产模式下不允许启用沙箱支付渠道，拒绝启动")
+			return
+		}
+		provider, err := payment.NewSandboxService(&payment.SandboxConfig{
+			Secret:             sandboxCfg.Secret,
+			CheckoutURL:        sandboxCfg.CheckoutURL,
+			NotifyURL:          sandboxCfg.NotifyURL,
+			ReturnURL:          sandboxCfg.ReturnURL,
+			NotifyDelay:        time.Duration(sandboxCfg.NotifyDelay) * time.Second,
+			AgreementNotifyURL: sandboxCfg.AgreementNotifyURL,
+		})
+		if err != nil {
+			zap.L().Error("初始化沙箱支付失败", zap.Error(err))
+		} else {
+			payment.Register(provider)
+		}
+	}
+	if setting.PaymentSetting.Default != "" {
+		payment.SetDefault(setting.PaymentSetting.Default)
+	}
+
+	// 未支付订单的主动查询和过期关闭
+	if jobCfg := setting.PaymentSetting.OrderJob; jobCfg.Enabled {
+		service.NewOrderJob(service.OrderJobConfig{
+			Interval:   time.Duration(jobCfg.Interval) * time.Second,
+			OrderTTL:   time.Duration(jobCfg.OrderTTL) * time.Minute,
+			QueryDelay: time.Duration(jobCfg.QueryDelay) * time.Second,
+			BatchSize:  jobCfg.BatchSize,
+		}).Start(context.Background())
+	}
+
+	// 每日账单核对
+	if reconcileCfg := setting.PaymentSetting.Reconcile; reconcileCfg.Enabled {
+		service.NewReconcileJob(reconcileCfg.Hour).Start(context.Background())
+	}
+
+	// 自动续费扣款和失败重试
+	if subscriptionCfg := setting.PaymentSetting.Subscription; subscriptionCfg.Enabled {
+		service.NewSubscriptionJob(time.Duration(subscriptionCfg.Interval)*time.Second, subscriptionCfg.BatchSize).Start(context.Background())
+	}
+
 	// 初始化路由
 	r := router.InitRouter()
 
+	// 同步路由权限
+	if err := router.SyncPermissions(r); err != nil {
+		zap.L().Error("同步路由权限失败", zap.Error(err))
+		return
+	}
+
 	// 配置服务器
 	server := &http.Server{
 		Addr:           fmt.Sprintf(":%d", setting.AppSetting.Port),
@@ -68,3 +222,79 @@ func main() {
 		zap.L().Fatal("服务启动失败", zap.Error(err))
 	}
 }
+
+// newWeChatProvider 读取密钥文件并创建微信支付渠道
+func newWeChatProvider(cfg setting.WeChatPay) (*payment.WeChatService, error) {
+	privateKey, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取商户私钥失败: %v", err)
+	}
+	platformKey, err := os.ReadFile(cfg.PlatformPublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取微信支付公钥失败: %v", err)
+	}
+
+	return payment.NewWeChatService(&payment.WeChatConfig{
+		AppID:             cfg.AppID,
+		MchID:             cfg.MchID,
+		SerialNo:          cfg.SerialNo,
+		PrivateKey:        string(privateKey),
+		APIv3Key:          cfg.APIv3Key,
+		PlatformPublicKey: string(platformKey),
+		PlatformSerial:    cfg.PlatformSerial,
+		NotifyURL:         cfg.NotifyURL,
+	})
+}
+
+// loadAlipayConfig 读取密钥和证书文件，生成支付宝配置
+func loadAlipayConfig(cfg *setting.Alipay) (*payment.AlipayConfig, error) {
+	privateKey, err := readKey(cfg.PrivateKey, cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取应用私钥失败: %v", err)
+	}
+
+	config := &payment.AlipayConfig{
+		AppID:        cfg.AppID,
+		PrivateKey:   privateKey,
+		NotifyURL:    cfg.NotifyURL,
+		ReturnURL:    cfg.ReturnURL,
+		IsProduction: cfg.IsProd,
+	}
+
+	// 配置了应用公钥证书时使用证书模式
+	if cfg.AppPublicCertFile != "" {
+		certs := []struct {
+			file string
+			dest *string
+		}{
+			{cfg.AppPublicCertFile, &config.AppPublicCert},
+			{cfg.AlipayRootCertFile, &config.AlipayRootCert},
+			{cfg.AlipayPublicCertFile, &config.AlipayPublicCert},
+		}
+		for _, cert := range certs {
+			data, err := os.ReadFile(cert.file)
+			if err != nil {
+				return nil, fmt.Errorf("读取支付宝证书失败: %v", err)
+			}
+			*cert.dest = string(data)
+		}
+		return config, nil
+	}
+
+	if config.AliPublicKey, err = readKey(cfg.AlipayPublicKey, cfg.AlipayPublicKeyFile); err != nil {
+		return nil, fmt.Errorf("读取支付宝公钥失败: %v", err)
+	}
+	return config, nil
+}
+
+// readKey 优先使用配置中的密钥内容，未配置时读取密钥文件
+func readKey(value, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}